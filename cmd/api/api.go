@@ -1,11 +1,28 @@
 package api
 
 import (
+	"backend/seed-savers/config"
+	"backend/seed-savers/services/account"
+	"backend/seed-savers/services/address"
+	"backend/seed-savers/services/admin"
 	"backend/seed-savers/services/auth"
+	"backend/seed-savers/services/calendar"
+	"backend/seed-savers/services/companion"
+	"backend/seed-savers/services/conservation"
+	"backend/seed-savers/services/export"
+	"backend/seed-savers/services/growreport"
+	"backend/seed-savers/services/image"
+	"backend/seed-savers/services/importer"
+	"backend/seed-savers/services/lineage"
+	"backend/seed-savers/services/moderation"
 	"backend/seed-savers/services/order"
+	"backend/seed-savers/services/profile"
 	"backend/seed-savers/services/seed"
+	"backend/seed-savers/services/storage"
 
+	"backend/seed-savers/services/tag"
 	"backend/seed-savers/services/user"
+	"backend/seed-savers/services/wishlist"
 	"database/sql"
 	"log"
 	"net/http"
@@ -29,14 +46,69 @@ func (a *ApiServer) Run() error {
 	userStore := user.NewStore(a.db)
 	seedStore := seed.NewStore(a.db)
 	orderStore := order.NewStore(a.db)
+	imageStore := image.NewStore(a.db)
+	lotStore := lineage.NewStore(a.db)
+	calendarStore := calendar.NewStore(a.db)
+	companionStore := companion.NewStore(a.db)
+	importStore := importer.NewStore(a.db)
+	exportStore := export.NewStore(a.db)
+	wishlistStore := wishlist.NewStore(a.db)
+	tagStore := tag.NewStore(a.db)
+	conservationStore := conservation.NewStore(a.db)
+	growReportStore := growreport.NewStore(a.db)
+	profileStore := profile.NewStore(a.db)
+	accountStore := account.NewStore(a.db)
+	addressStore := address.NewStore(a.db)
+	moderationStore := moderation.NewStore(a.db)
+	adminStore := admin.NewStore(a.db)
+	auth.AuditLog = adminStore
 
-	userHandler := user.NewHandler(userStore, authSessionStore)
-	seedHandler := seed.NewHandler(seedStore, userStore, authSessionStore)
+	blobStorage, err := storage.New(config.Envs)
+	if err != nil {
+		return err
+	}
+
+	notifier := wishlist.NewNotifier(wishlistStore, seedStore)
+	go notifier.RunDigest(int(config.Envs.AlertDigestHour))
+	go account.RunPurge(accountStore, account.GracePeriod())
+
+	userHandler := user.NewHandler(userStore, userStore, authSessionStore)
+	seedHandler := seed.NewHandler(seedStore, userStore, authSessionStore, notifier)
 	orderHandler := order.NewHandler(orderStore, userStore, seedStore, authSessionStore)
+	imageHandler := image.NewHandler(imageStore, seedStore, userStore, blobStorage, authSessionStore)
+	lineageHandler := lineage.NewHandler(lotStore, seedStore, userStore, authSessionStore)
+	calendarHandler := calendar.NewHandler(calendarStore, userStore, authSessionStore)
+	companionHandler := companion.NewHandler(companionStore, seedStore, userStore, authSessionStore)
+	importHandler := importer.NewHandler(importStore, notifier, userStore, authSessionStore)
+	exportHandler := export.NewHandler(exportStore)
+	wishlistHandler := wishlist.NewHandler(wishlistStore, seedStore, userStore, authSessionStore)
+	tagHandler := tag.NewHandler(tagStore, seedStore, userStore, authSessionStore)
+	conservationHandler := conservation.NewHandler(conservationStore, seedStore, userStore, authSessionStore)
+	growReportHandler := growreport.NewHandler(growReportStore, seedStore, userStore, authSessionStore)
+	profileHandler := profile.NewHandler(profileStore, userStore, authSessionStore)
+	accountHandler := account.NewHandler(accountStore, userStore, authSessionStore)
+	addressHandler := address.NewHandler(addressStore, userStore, authSessionStore)
+	adminHandler := admin.NewHandler(adminStore, userStore, authSessionStore)
+	moderationHandler := moderation.NewHandler(moderationStore, userStore, authSessionStore)
 
 	userHandler.RegisterRouter(router)
 	seedHandler.RegisterRouter(router)
 	orderHandler.RegisterRouter(router)
+	imageHandler.RegisterRouter(router)
+	lineageHandler.RegisterRouter(router)
+	calendarHandler.RegisterRouter(router)
+	companionHandler.RegisterRouter(router)
+	importHandler.RegisterRouter(router)
+	exportHandler.RegisterRouter(router)
+	wishlistHandler.RegisterRouter(router)
+	tagHandler.RegisterRouter(router)
+	conservationHandler.RegisterRouter(router)
+	growReportHandler.RegisterRouter(router)
+	profileHandler.RegisterRouter(router)
+	accountHandler.RegisterRouter(router)
+	addressHandler.RegisterRouter(router)
+	adminHandler.RegisterRouter(router)
+	moderationHandler.RegisterRouter(router)
 
 	log.Println("listening on: ", a.adress)
 	return http.ListenAndServe(a.adress, router)
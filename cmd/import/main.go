@@ -0,0 +1,97 @@
+package main
+
+import (
+	"backend/seed-savers/config"
+	"backend/seed-savers/db"
+	"backend/seed-savers/services/importer"
+	"backend/seed-savers/services/seed"
+	"backend/seed-savers/services/wishlist"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Importa un catalogo CSV o JSON per conto di un utente. Senza -apply stampa solo il report di prova
+func main() {
+	file := flag.String("file", "", "CSV or JSON file to import")
+	format := flag.String("format", "", "csv or json (default: from the file extension)")
+	userID := flag.Int("user", 0, "ID of the user who receives the inventory")
+	mapping := flag.String("mapping", "", `field to column mapping as JSON, e.g. {"variety_name":"Varietà"}`)
+	apply := flag.Bool("apply", false, "write the import to the database")
+	flag.Parse()
+
+	if *file == "" || *userID == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*file)), ".")
+	}
+
+	var fields map[string]string
+	if *mapping != "" {
+		if err := json.Unmarshal([]byte(*mapping), &fields); err != nil {
+			log.Fatalf("invalid mapping: %v", err)
+		}
+	}
+
+	database, err := db.MySQLStorage(mysql.Config{
+		User:                 config.Envs.DBUser,
+		Passwd:               config.Envs.DBPassword,
+		Addr:                 config.Envs.DBAddress,
+		DBName:               config.Envs.DBName,
+		Net:                  "tcp",
+		AllowNativePasswords: true,
+		ParseTime:            true,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer database.Close()
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	store := importer.NewStore(database)
+	rows, report, err := importer.Prepare(store, f, *format, fields)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *apply {
+		applied := 0
+		err = importer.Apply(store, rows, report, *userID, int(config.Envs.ImportBatchSize), func(processed int) {
+			applied = processed
+			log.Printf("imported %d/%d rows", processed, len(rows))
+		})
+
+		// qui gli avvisi vanno accodati prima di uscire, non in background
+		notifier := wishlist.NewNotifier(wishlist.NewStore(database), seed.NewStore(database))
+		for _, row := range rows[:applied] {
+			if row.Quantity == 0 {
+				continue
+			}
+			if err := notifier.Notify(row.SeedID, *userID); err != nil {
+				log.Printf("failed to queue alerts for seed %d: %v", row.SeedID, err)
+			}
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%d new, %d matched, %d errors", report.New, report.Matched, report.Errors)
+}
@@ -20,6 +20,7 @@ func main() {
 		Net:                  "tcp",
 		AllowNativePasswords: true,
 		ParseTime:            true,
+		MultiStatements:      true,
 	}
 
 	db, err := db.MySQLStorage(cfg)
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS seed_image;
\ No newline at end of file
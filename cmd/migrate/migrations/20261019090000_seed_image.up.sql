@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS seed_image (
+    image_id INT AUTO_INCREMENT PRIMARY KEY,
+    seed_id INT NOT NULL,
+    user_id INT NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    thumb_key VARCHAR(255) NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX (seed_id, position),
+    FOREIGN KEY (seed_id) REFERENCES seed(seed_id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS seed_revision;
+
+ALTER TABLE seed DROP FOREIGN KEY fk_seed_created_by, DROP COLUMN created_by;
+
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,18 @@
+-- curatori e amministratori possono modificare il catalogo. Il primo amministratore va nominato
+-- a mano: UPDATE users SET role = 'admin' WHERE email = '...';
+ALTER TABLE users ADD COLUMN role ENUM('user', 'curator', 'admin') NOT NULL DEFAULT 'user';
+
+ALTER TABLE seed ADD COLUMN created_by INT NULL,
+    ADD CONSTRAINT fk_seed_created_by FOREIGN KEY (created_by) REFERENCES users(user_id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS seed_revision (
+    revision_id INT AUTO_INCREMENT PRIMARY KEY,
+    seed_id INT NOT NULL,
+    user_id INT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    changes JSON NOT NULL,
+    snapshot JSON NOT NULL,
+    reverted_from INT NULL,
+    FOREIGN KEY (seed_id) REFERENCES seed(seed_id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE SET NULL
+);
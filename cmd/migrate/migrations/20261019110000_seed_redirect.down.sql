@@ -0,0 +1 @@
+DROP TABLE IF EXISTS seed_redirect;
\ No newline at end of file
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS seed_redirect (
+    old_seed_id INT NOT NULL PRIMARY KEY,
+    new_seed_id INT NOT NULL,
+    merged_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (new_seed_id) REFERENCES seed(seed_id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS seed_lot;
\ No newline at end of file
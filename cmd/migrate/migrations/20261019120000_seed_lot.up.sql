@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS seed_lot (
+    lot_id INT AUTO_INCREMENT PRIMARY KEY,
+    seed_id INT NOT NULL,
+    user_id INT NULL,
+    parent_lot_id INT NULL,
+    source ENUM('registered', 'exchange', 'grow_out') NOT NULL,
+    order_id INT NULL,
+    quantity INT NOT NULL DEFAULT 0,
+    harvest_year SMALLINT NULL,
+    province VARCHAR(40) NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX (seed_id, user_id),
+    FOREIGN KEY (seed_id) REFERENCES seed(seed_id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE SET NULL,
+    FOREIGN KEY (parent_lot_id) REFERENCES seed_lot(lot_id) ON DELETE SET NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(order_id) ON DELETE SET NULL
+);
+
+-- gli inventari esistenti diventano partite di origine
+INSERT INTO seed_lot (seed_id, user_id, source, quantity, province)
+SELECT us.seed_id, us.user_id, 'registered', us.quantity, a.province
+FROM users_seed us
+LEFT JOIN adress a ON us.user_id = a.id;
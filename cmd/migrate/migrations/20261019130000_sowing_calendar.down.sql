@@ -0,0 +1,13 @@
+ALTER TABLE users
+    DROP COLUMN calendar_token,
+    DROP COLUMN climate_zone;
+
+ALTER TABLE seed
+    DROP COLUMN sow_indoor_start,
+    DROP COLUMN sow_indoor_end,
+    DROP COLUMN sow_outdoor_start,
+    DROP COLUMN sow_outdoor_end,
+    DROP COLUMN transplant_start,
+    DROP COLUMN transplant_end,
+    DROP COLUMN harvest_start,
+    DROP COLUMN harvest_end;
@@ -0,0 +1,13 @@
+ALTER TABLE seed
+    ADD COLUMN sow_indoor_start TINYINT NULL,
+    ADD COLUMN sow_indoor_end TINYINT NULL,
+    ADD COLUMN sow_outdoor_start TINYINT NULL,
+    ADD COLUMN sow_outdoor_end TINYINT NULL,
+    ADD COLUMN transplant_start TINYINT NULL,
+    ADD COLUMN transplant_end TINYINT NULL,
+    ADD COLUMN harvest_start TINYINT NULL,
+    ADD COLUMN harvest_end TINYINT NULL;
+
+ALTER TABLE users
+    ADD COLUMN climate_zone CHAR(1) NULL,
+    ADD COLUMN calendar_token VARCHAR(64) NULL UNIQUE;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS crop_relation;
\ No newline at end of file
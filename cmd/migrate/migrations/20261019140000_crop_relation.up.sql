@@ -0,0 +1,61 @@
+CREATE TABLE IF NOT EXISTS crop_relation (
+    relation_id INT AUTO_INCREMENT PRIMARY KEY,
+    crop_a VARCHAR(100) NOT NULL,
+    crop_b VARCHAR(100) NOT NULL,
+    kind ENUM('companion', 'antagonist', 'rotation_follows') NOT NULL,
+    note VARCHAR(255) NULL,
+    UNIQUE (crop_a, crop_b, kind),
+    INDEX (crop_b)
+);
+
+-- consociazioni e rotazioni di base. Per companion e antagonist la coppia è in ordine
+-- alfabetico, per rotation_follows crop_a va coltivato dopo crop_b
+INSERT INTO crop_relation (crop_a, crop_b, kind, note) VALUES
+    ('basilico', 'pomodoro', 'companion', 'Il basilico tiene lontani afidi e mosca bianca'),
+    ('carota', 'pomodoro', 'companion', NULL),
+    ('cipolla', 'pomodoro', 'companion', NULL),
+    ('aglio', 'pomodoro', 'companion', 'L''aglio aiuta contro i funghi'),
+    ('pomodoro', 'prezzemolo', 'companion', NULL),
+    ('lattuga', 'pomodoro', 'companion', NULL),
+    ('carota', 'cipolla', 'companion', 'La cipolla allontana la mosca della carota'),
+    ('carota', 'porro', 'companion', 'Il porro allontana la mosca della carota'),
+    ('carota', 'lattuga', 'companion', NULL),
+    ('carota', 'ravanello', 'companion', NULL),
+    ('fagiolo', 'mais', 'companion', 'Il fagiolo rampicante usa il mais come tutore'),
+    ('mais', 'zucca', 'companion', 'La zucca copre il terreno e trattiene l''umidità'),
+    ('fagiolo', 'zucca', 'companion', NULL),
+    ('lattuga', 'ravanello', 'companion', NULL),
+    ('fragola', 'lattuga', 'companion', NULL),
+    ('cetriolo', 'fagiolo', 'companion', NULL),
+    ('cetriolo', 'lattuga', 'companion', NULL),
+    ('cavolo', 'sedano', 'companion', 'Il sedano allontana la cavolaia'),
+    ('fagiolo', 'patata', 'companion', NULL),
+    ('fagiolo', 'zucchina', 'companion', NULL),
+    ('fagiolo', 'melanzana', 'companion', NULL),
+    ('basilico', 'peperone', 'companion', NULL),
+    ('cipolla', 'fragola', 'companion', NULL),
+    ('porro', 'sedano', 'companion', NULL),
+    ('bietola', 'cipolla', 'companion', NULL),
+    ('fragola', 'spinacio', 'companion', NULL),
+    ('carota', 'pisello', 'companion', NULL),
+    ('pisello', 'ravanello', 'companion', NULL),
+    ('patata', 'pomodoro', 'antagonist', 'Condividono peronospora e dorifora'),
+    ('finocchio', 'pomodoro', 'antagonist', 'Il finocchio rallenta la crescita di quasi tutti gli ortaggi'),
+    ('cavolo', 'pomodoro', 'antagonist', NULL),
+    ('cipolla', 'fagiolo', 'antagonist', NULL),
+    ('aglio', 'fagiolo', 'antagonist', NULL),
+    ('fagiolo', 'porro', 'antagonist', NULL),
+    ('fagiolo', 'finocchio', 'antagonist', NULL),
+    ('cipolla', 'pisello', 'antagonist', NULL),
+    ('aglio', 'pisello', 'antagonist', NULL),
+    ('patata', 'zucca', 'antagonist', NULL),
+    ('cetriolo', 'patata', 'antagonist', NULL),
+    ('cavolo', 'fragola', 'antagonist', NULL),
+    ('cavolo', 'fagiolo', 'rotation_follows', 'Le leguminose lasciano azoto per gli ortaggi da foglia'),
+    ('pomodoro', 'cavolo', 'rotation_follows', NULL),
+    ('carota', 'pomodoro', 'rotation_follows', NULL),
+    ('fagiolo', 'carota', 'rotation_follows', NULL),
+    ('zucchina', 'pisello', 'rotation_follows', NULL),
+    ('patata', 'zucchina', 'rotation_follows', NULL),
+    ('pisello', 'patata', 'rotation_follows', NULL),
+    ('lattuga', 'pisello', 'rotation_follows', NULL);
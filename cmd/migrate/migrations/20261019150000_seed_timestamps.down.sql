@@ -0,0 +1,3 @@
+ALTER TABLE seed DROP INDEX updated_at,
+    DROP COLUMN updated_at,
+    DROP COLUMN created_at;
@@ -0,0 +1,4 @@
+ALTER TABLE users DROP COLUMN alert_delivery;
+DROP TABLE IF EXISTS alert;
+DROP TABLE IF EXISTS saved_search;
+DROP TABLE IF EXISTS wishlist;
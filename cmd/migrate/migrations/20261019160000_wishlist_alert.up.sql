@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS wishlist (
+    user_id INT NOT NULL,
+    seed_id INT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, seed_id),
+    INDEX (seed_id),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (seed_id) REFERENCES seed(seed_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS saved_search (
+    search_id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    query VARCHAR(100) NOT NULL DEFAULT '',
+    vegetable VARCHAR(100) NOT NULL DEFAULT '',
+    region VARCHAR(40) NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS alert (
+    alert_id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    seed_id INT NOT NULL,
+    owner_id INT NULL,
+    reason ENUM('wishlist', 'saved_search') NOT NULL,
+    search_id INT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    sent_at DATETIME NULL,
+    INDEX (user_id, sent_at),
+    INDEX (sent_at),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (seed_id) REFERENCES seed(seed_id) ON DELETE CASCADE,
+    FOREIGN KEY (owner_id) REFERENCES users(user_id) ON DELETE SET NULL,
+    FOREIGN KEY (search_id) REFERENCES saved_search(search_id) ON DELETE SET NULL
+);
+
+ALTER TABLE users ADD COLUMN alert_delivery ENUM('immediate', 'daily') NOT NULL DEFAULT 'daily';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS seed_tag;
+DROP TABLE IF EXISTS tag;
+ALTER TABLE seed DROP COLUMN pollination;
@@ -0,0 +1,36 @@
+ALTER TABLE seed ADD COLUMN pollination ENUM('self', 'cross', 'hybrid') NULL;
+
+CREATE TABLE IF NOT EXISTS tag (
+    tag_id INT AUTO_INCREMENT PRIMARY KEY,
+    slug VARCHAR(60) NOT NULL UNIQUE,
+    label VARCHAR(60) NOT NULL,
+    status ENUM('curated', 'pending', 'rejected') NOT NULL DEFAULT 'pending',
+    suggested_by INT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX (status),
+    FOREIGN KEY (suggested_by) REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS seed_tag (
+    seed_id INT NOT NULL,
+    tag_id INT NOT NULL,
+    added_by INT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (seed_id, tag_id),
+    INDEX (tag_id),
+    FOREIGN KEY (seed_id) REFERENCES seed(seed_id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tag(tag_id) ON DELETE CASCADE,
+    FOREIGN KEY (added_by) REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+INSERT INTO tag (slug, label, status) VALUES
+    ('resistente-alla-siccita', 'Resistente alla siccità', 'curated'),
+    ('rampicante', 'Rampicante', 'curated'),
+    ('nano', 'Nano', 'curated'),
+    ('varieta-locale', 'Varietà locale', 'curated'),
+    ('presidio-slow-food', 'Presidio Slow Food', 'curated'),
+    ('antica', 'Varietà antica', 'curated'),
+    ('precoce', 'Precoce', 'curated'),
+    ('tardiva', 'Tardiva', 'curated'),
+    ('da-conserva', 'Da conserva', 'curated'),
+    ('resistente-al-freddo', 'Resistente al freddo', 'curated');
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP COLUMN owner_visibility,
+    DROP COLUMN vacation_mode;
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN vacation_mode BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN owner_visibility ENUM('public', 'members', 'hidden') NOT NULL DEFAULT 'public';
@@ -0,0 +1 @@
+ALTER TABLE users_seed DROP COLUMN restocked_at;
@@ -0,0 +1,2 @@
+-- l'ultima volta che un utente ha aumentato la propria scorta di un seme, NULL se non è noto
+ALTER TABLE users_seed ADD COLUMN restocked_at DATETIME NULL;
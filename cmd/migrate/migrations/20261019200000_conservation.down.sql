@@ -0,0 +1,5 @@
+ALTER TABLE seed
+    DROP FOREIGN KEY fk_seed_conservation_set_by,
+    DROP COLUMN conservation_set_by,
+    DROP COLUMN conservation_source,
+    DROP COLUMN conservation_status;
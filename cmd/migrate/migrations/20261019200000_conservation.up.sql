@@ -0,0 +1,5 @@
+ALTER TABLE seed
+    ADD COLUMN conservation_status ENUM('watch', 'endangered', 'critical') NULL,
+    ADD COLUMN conservation_source VARCHAR(150) NULL,
+    ADD COLUMN conservation_set_by INT NULL,
+    ADD CONSTRAINT fk_seed_conservation_set_by FOREIGN KEY (conservation_set_by) REFERENCES users(user_id) ON DELETE SET NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS grow_report_flag;
+DROP TABLE IF EXISTS grow_report_image;
+DROP TABLE IF EXISTS grow_report;
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS grow_report (
+    report_id INT AUTO_INCREMENT PRIMARY KEY,
+    seed_id INT NOT NULL,
+    user_id INT NOT NULL,
+    order_id INT NULL,
+    year SMALLINT NOT NULL,
+    province VARCHAR(40) NULL,
+    germination TINYINT NULL,
+    yield TINYINT NULL,
+    disease_resistance TINYINT NULL,
+    taste TINYINT NULL,
+    notes TEXT NULL,
+    status ENUM('published', 'hidden') NOT NULL DEFAULT 'published',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX (seed_id, status, created_at),
+    FOREIGN KEY (seed_id) REFERENCES seed(seed_id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (order_id) REFERENCES orders(order_id) ON DELETE SET NULL
+);
+
+-- le foto sono quelle già caricate dall'autore tra le immagini del seme
+CREATE TABLE IF NOT EXISTS grow_report_image (
+    report_id INT NOT NULL,
+    image_id INT NOT NULL,
+    PRIMARY KEY (report_id, image_id),
+    FOREIGN KEY (report_id) REFERENCES grow_report(report_id) ON DELETE CASCADE,
+    FOREIGN KEY (image_id) REFERENCES seed_image(image_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS grow_report_flag (
+    report_id INT NOT NULL,
+    user_id INT NOT NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (report_id, user_id),
+    FOREIGN KEY (report_id) REFERENCES grow_report(report_id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
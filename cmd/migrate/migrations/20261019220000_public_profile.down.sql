@@ -0,0 +1,3 @@
+ALTER TABLE users_seed DROP COLUMN private;
+ALTER TABLE users DROP COLUMN status;
+ALTER TABLE users DROP COLUMN created_at;
@@ -0,0 +1,7 @@
+-- gli utenti esistenti non hanno una data di iscrizione nota e la lasciano NULL
+ALTER TABLE users ADD COLUMN created_at DATETIME NULL;
+ALTER TABLE users MODIFY COLUMN created_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP;
+
+ALTER TABLE users ADD COLUMN status ENUM('active', 'blocked', 'deleted') NOT NULL DEFAULT 'active';
+
+ALTER TABLE users_seed ADD COLUMN private BOOLEAN NOT NULL DEFAULT FALSE;
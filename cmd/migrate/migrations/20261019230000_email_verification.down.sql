@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS email_verification;
+ALTER TABLE users DROP COLUMN email_verified_at;
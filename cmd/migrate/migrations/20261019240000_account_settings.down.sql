@@ -0,0 +1,2 @@
+ALTER TABLE email_verification DROP COLUMN purpose;
+ALTER TABLE users DROP COLUMN token_version;
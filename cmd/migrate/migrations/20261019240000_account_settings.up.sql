@@ -0,0 +1,5 @@
+-- cambia a ogni cambio di password e invalida le sessioni firmate con il valore precedente
+ALTER TABLE users ADD COLUMN token_version INT NOT NULL DEFAULT 0;
+
+-- lo stesso link a scadenza conferma anche il nuovo indirizzo quando l'utente cambia email
+ALTER TABLE email_verification ADD COLUMN purpose ENUM('verify', 'change') NOT NULL DEFAULT 'verify';
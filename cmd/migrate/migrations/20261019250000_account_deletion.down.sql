@@ -0,0 +1,11 @@
+ALTER TABLE orders
+    DROP FOREIGN KEY fk_orders_sender,
+    DROP FOREIGN KEY fk_orders_reciver;
+ALTER TABLE orders
+    ADD CONSTRAINT orders_ibfk_1 FOREIGN KEY (sender_user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    ADD CONSTRAINT orders_ibfk_2 FOREIGN KEY (reciver_user_id) REFERENCES users(user_id) ON DELETE CASCADE;
+
+UPDATE orders SET state = 'In attesa' WHERE state = 'Annullato';
+ALTER TABLE orders MODIFY COLUMN state ENUM('In attesa', 'In preparazione', 'In spedizione', 'Arrivato') DEFAULT 'In attesa';
+
+ALTER TABLE users DROP COLUMN deletion_requested_at;
@@ -0,0 +1,13 @@
+-- la cancellazione diventa effettiva dopo il periodo di ripensamento; fino ad allora si può annullare
+ALTER TABLE users ADD COLUMN deletion_requested_at DATETIME NULL;
+
+ALTER TABLE orders MODIFY COLUMN state ENUM('In attesa', 'In preparazione', 'In spedizione', 'Arrivato', 'Annullato') DEFAULT 'In attesa';
+
+-- gli account cancellati vengono anonimizzati: cancellare la riga di un utente non deve più portarsi
+-- via lo storico degli ordini dell'altra parte
+ALTER TABLE orders
+    DROP FOREIGN KEY orders_ibfk_1,
+    DROP FOREIGN KEY orders_ibfk_2;
+ALTER TABLE orders
+    ADD CONSTRAINT fk_orders_sender FOREIGN KEY (sender_user_id) REFERENCES users(user_id) ON DELETE RESTRICT,
+    ADD CONSTRAINT fk_orders_reciver FOREIGN KEY (reciver_user_id) REFERENCES users(user_id) ON DELETE RESTRICT;
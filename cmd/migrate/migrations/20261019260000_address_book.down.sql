@@ -0,0 +1,23 @@
+ALTER TABLE orders
+    DROP COLUMN shipping_label,
+    DROP COLUMN shipping_state,
+    DROP COLUMN shipping_city,
+    DROP COLUMN shipping_street,
+    DROP COLUMN shipping_cap,
+    DROP COLUMN shipping_province,
+    DROP COLUMN shipping_number,
+    DROP COLUMN shipping_apartment_number;
+
+-- resta solo l'indirizzo predefinito di ogni utente
+DELETE FROM adress WHERE is_default IS NULL;
+ALTER TABLE adress DROP FOREIGN KEY fk_adress_user;
+ALTER TABLE adress
+    DROP INDEX uq_adress_default,
+    DROP COLUMN is_default,
+    DROP COLUMN label,
+    MODIFY COLUMN adress_id INT NOT NULL,
+    DROP PRIMARY KEY,
+    DROP COLUMN adress_id,
+    CHANGE COLUMN user_id id INT NOT NULL,
+    ADD PRIMARY KEY (id),
+    ADD FOREIGN KEY (id) REFERENCES users(user_id) ON DELETE CASCADE;
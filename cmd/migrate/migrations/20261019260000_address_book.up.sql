@@ -0,0 +1,29 @@
+-- ogni utente può avere più indirizzi: la chiave primaria non è più l'utente. is_default vale TRUE
+-- per l'indirizzo predefinito e NULL per gli altri, così l'indice unico ne ammette uno solo per utente
+ALTER TABLE adress DROP FOREIGN KEY adress_ibfk_1;
+ALTER TABLE adress
+    DROP PRIMARY KEY,
+    CHANGE COLUMN id user_id INT NOT NULL,
+    ADD COLUMN adress_id INT AUTO_INCREMENT PRIMARY KEY FIRST,
+    ADD COLUMN label VARCHAR(40) NOT NULL DEFAULT 'Casa',
+    ADD COLUMN is_default BOOLEAN NULL,
+    ADD UNIQUE INDEX uq_adress_default (user_id, is_default),
+    ADD CONSTRAINT fk_adress_user FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE;
+UPDATE adress SET is_default = TRUE;
+
+-- l'indirizzo di spedizione viene copiato nell'ordine, così le modifiche successive non riscrivono lo storico
+ALTER TABLE orders
+    ADD COLUMN shipping_label VARCHAR(40) NULL,
+    ADD COLUMN shipping_state VARCHAR(40) NULL,
+    ADD COLUMN shipping_city VARCHAR(40) NULL,
+    ADD COLUMN shipping_street VARCHAR(40) NULL,
+    ADD COLUMN shipping_cap VARCHAR(10) NULL,
+    ADD COLUMN shipping_province VARCHAR(40) NULL,
+    ADD COLUMN shipping_number SMALLINT NULL,
+    ADD COLUMN shipping_apartment_number VARCHAR(40) NULL;
+
+UPDATE orders o
+INNER JOIN adress a ON a.user_id = o.reciver_user_id AND a.is_default
+SET o.shipping_label = a.label, o.shipping_state = a.state, o.shipping_city = a.city, o.shipping_street = a.street,
+    o.shipping_cap = a.cap, o.shipping_province = a.province, o.shipping_number = a.number,
+    o.shipping_apartment_number = a.apartment_number;
@@ -0,0 +1,5 @@
+-- degli esponenti resta solo la parte numerica, i civici SNC diventano 0
+UPDATE adress SET number = COALESCE(REGEXP_SUBSTR(number, '^[0-9]+'), '0') WHERE number NOT REGEXP '^[0-9]+$';
+UPDATE orders SET shipping_number = COALESCE(REGEXP_SUBSTR(shipping_number, '^[0-9]+'), '0') WHERE shipping_number NOT REGEXP '^[0-9]+$';
+ALTER TABLE adress MODIFY COLUMN number SMALLINT NOT NULL;
+ALTER TABLE orders MODIFY COLUMN shipping_number SMALLINT NULL;
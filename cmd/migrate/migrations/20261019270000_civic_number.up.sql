@@ -0,0 +1,3 @@
+-- il numero civico può avere un esponente ("12/B"), quindi diventa testo
+ALTER TABLE adress MODIFY COLUMN number VARCHAR(10) NOT NULL;
+ALTER TABLE orders MODIFY COLUMN shipping_number VARCHAR(10) NULL;
@@ -0,0 +1,13 @@
+-- ogni azione di curatori e amministratori, letture comprese; details contiene il corpo della richiesta
+-- o, per le letture, i parametri della query
+CREATE TABLE IF NOT EXISTS admin_audit (
+    audit_id INT AUTO_INCREMENT PRIMARY KEY,
+    actor_user_id INT NULL,
+    actor_role ENUM('user', 'curator', 'admin') NOT NULL,
+    action VARCHAR(255) NOT NULL,
+    path VARCHAR(255) NOT NULL,
+    details JSON NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_admin_audit_created (created_at),
+    CONSTRAINT fk_admin_audit_actor FOREIGN KEY (actor_user_id) REFERENCES users(user_id) ON DELETE SET NULL
+);
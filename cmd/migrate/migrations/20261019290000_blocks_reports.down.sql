@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_warning;
+DROP TABLE IF EXISTS abuse_report;
+DROP TABLE IF EXISTS user_block;
@@ -0,0 +1,43 @@
+-- chi blocca non vede più l'altro tra i possessori e nessuno dei due può ordinare dall'altro
+CREATE TABLE IF NOT EXISTS user_block (
+    blocker_user_id INT NOT NULL,
+    blocked_user_id INT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (blocker_user_id, blocked_user_id),
+    INDEX idx_user_block_blocked (blocked_user_id),
+    CONSTRAINT fk_user_block_blocker FOREIGN KEY (blocker_user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_block_blocked FOREIGN KEY (blocked_user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- target_user_id è l'utente segnalato o l'autore del contenuto segnalato, salvato alla segnalazione
+CREATE TABLE IF NOT EXISTS abuse_report (
+    report_id INT AUTO_INCREMENT PRIMARY KEY,
+    reporter_user_id INT NULL,
+    target_type ENUM('user', 'seed', 'grow_report') NOT NULL,
+    target_id INT NOT NULL,
+    target_user_id INT NULL,
+    category ENUM('spam', 'scam', 'harassment', 'inappropriate', 'other') NOT NULL,
+    reason VARCHAR(1000) NOT NULL,
+    status ENUM('open', 'resolved') NOT NULL DEFAULT 'open',
+    resolution ENUM('dismiss', 'warn', 'suspend', 'remove') NULL,
+    resolution_note VARCHAR(1000) NULL,
+    resolved_by INT NULL,
+    resolved_at DATETIME NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_abuse_report_status (status, created_at),
+    INDEX idx_abuse_report_target (target_type, target_id, status),
+    CONSTRAINT fk_abuse_report_reporter FOREIGN KEY (reporter_user_id) REFERENCES users(user_id) ON DELETE SET NULL,
+    CONSTRAINT fk_abuse_report_target_user FOREIGN KEY (target_user_id) REFERENCES users(user_id) ON DELETE SET NULL,
+    CONSTRAINT fk_abuse_report_resolver FOREIGN KEY (resolved_by) REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_warning (
+    warning_id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    report_id INT NULL,
+    message VARCHAR(1000) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_user_warning_user (user_id),
+    CONSTRAINT fk_user_warning_user FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_warning_report FOREIGN KEY (report_id) REFERENCES abuse_report(report_id) ON DELETE SET NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN address_disclosure, DROP COLUMN profile_visibility;
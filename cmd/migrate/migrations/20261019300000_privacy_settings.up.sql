@@ -0,0 +1,7 @@
+-- la visibilità dell'inventario resta owner_visibility, che vale già per i possessori e per il profilo.
+-- address_disclosure dice quando chi riceve un ordine vede via e civico di chi spedisce i semi: appena c'è
+-- l'ordine o solo dopo che chi spedisce l'ha accettato. Il predefinito 'accepted' non nasconde nulla che
+-- fosse già visibile, perché finora l'indirizzo di chi spedisce non compariva negli ordini
+ALTER TABLE users
+    ADD COLUMN profile_visibility ENUM('public', 'members', 'hidden') NOT NULL DEFAULT 'public',
+    ADD COLUMN address_disclosure ENUM('order', 'accepted') NOT NULL DEFAULT 'accepted';
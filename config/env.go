@@ -24,6 +24,21 @@ type Config struct {
 	GoogleClientSecretId   string
 	JWTExpirationInSeconds int64
 	TokenExpirationInHour  uint8
+	StorageDriver          string
+	StoragePath            string
+	S3Endpoint             string
+	S3Bucket               string
+	S3Region               string
+	S3AccessKey            string
+	S3SecretKey            string
+	MaxImageSizeMB         int64
+	ImportBatchSize        int64
+	AlertDigestHour        int64
+	VerificationTTLHours   int64
+	VerificationResendSecs int64
+	VerificationDailyLimit int64
+	DeletionGraceDays      int64
+	ReauthWindowMinutes    int64
 }
 
 var Envs = initConfig()
@@ -47,6 +62,21 @@ func initConfig() Config {
 		GoogleClientSecretId:   getEnv("GOOGLE_CLIENT_SECRET_ID", ""),
 		AesKey:                 getEnv("AES_KEY", "your super secret code"),
 		TokenExpirationInHour:  uint8(getEnvAsInt("TOKEN_EXPIRATION_HOUR", 5)),
+		StorageDriver:          getEnv("STORAGE_DRIVER", "local"),
+		StoragePath:            getEnv("STORAGE_PATH", "uploads"),
+		S3Endpoint:             getEnv("S3_ENDPOINT", "http://localhost:9000"),
+		S3Bucket:               getEnv("S3_BUCKET", "seed-images"),
+		S3Region:               getEnv("S3_REGION", "us-east-1"),
+		S3AccessKey:            getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:            getEnv("S3_SECRET_KEY", ""),
+		MaxImageSizeMB:         getEnvAsInt("MAX_IMAGE_SIZE_MB", 10),
+		ImportBatchSize:        getEnvAsInt("IMPORT_BATCH_SIZE", 50),
+		AlertDigestHour:        getEnvAsInt("ALERT_DIGEST_HOUR", 7),
+		VerificationTTLHours:   getEnvAsInt("VERIFICATION_TTL_HOURS", 48),
+		VerificationResendSecs: getEnvAsInt("VERIFICATION_RESEND_SECONDS", 60),
+		VerificationDailyLimit: getEnvAsInt("VERIFICATION_DAILY_LIMIT", 5),
+		DeletionGraceDays:      getEnvAsInt("DELETION_GRACE_DAYS", 14),
+		ReauthWindowMinutes:    getEnvAsInt("REAUTH_WINDOW_MINUTES", 10),
 	}
 }
 
@@ -0,0 +1,54 @@
+package geo
+
+import "strings"
+
+// capPrefixes associa il prefisso del CAP alla provincia. Le prime due cifre bastano quasi
+// sempre; le province nate da una divisione usano prefissi più lunghi, scelti con il
+// prefisso più lungo che corrisponde
+var capPrefixes = map[string]string{
+	"00": "RM", "01": "VT", "02": "RI", "03": "FR", "04": "LT", "05": "TR", "06": "PG",
+	"07": "SS", "08": "NU", "09": "CA", "0901": "SU", "0902": "SU", "0903": "SU",
+	"0907": "OR", "0908": "OR", "0909": "OR", "0917": "OR",
+	"10": "TO", "11": "AO", "12": "CN", "13": "VC", "138": "BI", "139": "BI",
+	"14": "AT", "15": "AL", "16": "GE", "17": "SV", "18": "IM", "19": "SP",
+	"20": "MI", "208": "MB", "209": "MB", "21": "VA", "22": "CO", "23": "SO", "238": "LC", "239": "LC",
+	"24": "BG", "25": "BS", "26": "CR", "268": "LO", "269": "LO", "27": "PV",
+	"28": "NO", "288": "VB", "289": "VB", "29": "PC",
+	"30": "VE", "31": "TV", "32": "BL", "33": "UD", "3307": "PN", "3308": "PN", "3309": "PN", "3317": "PN",
+	"34": "TS", "3407": "GO", "3417": "GO", "35": "PD", "36": "VI", "37": "VR", "38": "TN", "39": "BZ",
+	"40": "BO", "41": "MO", "42": "RE", "43": "PR", "44": "FE", "45": "RO", "46": "MN",
+	"47": "FC", "478": "RN", "479": "RN", "48": "RA",
+	"50": "FI", "51": "PT", "52": "AR", "53": "SI", "54": "MS", "55": "LU", "56": "PI", "57": "LI",
+	"58": "GR", "59": "PO",
+	"60": "AN", "61": "PU", "62": "MC", "63": "AP", "638": "FM", "639": "FM",
+	"64": "TE", "65": "PE", "66": "CH", "67": "AQ",
+	"70": "BA", "71": "FG", "72": "BR", "73": "LE", "74": "TA", "75": "MT", "76": "BT",
+	"80": "NA", "81": "CE", "82": "BN", "83": "AV", "84": "SA", "85": "PZ",
+	"86": "CB", "8607": "IS", "8608": "IS", "8609": "IS", "8617": "IS",
+	"87": "CS", "88": "CZ", "888": "KR", "889": "KR", "89": "RC", "898": "VV", "899": "VV",
+	"90": "PA", "91": "TP", "92": "AG", "93": "CL", "94": "EN", "95": "CT", "96": "SR", "97": "RG", "98": "ME",
+}
+
+// ProvinceForCAP trova la provincia di un CAP italiano di cinque cifre
+func ProvinceForCAP(postalCode string) (*Province, bool) {
+	postalCode = strings.TrimSpace(postalCode)
+	if len(postalCode) != 5 || strings.Trim(postalCode, "0123456789") != "" {
+		return nil, false
+	}
+
+	for n := 4; n >= 2; n-- {
+		if code, ok := capPrefixes[postalCode[:n]]; ok {
+			return LookupProvince(code)
+		}
+	}
+	return nil, false
+}
+
+// Locate restituisce la provincia di un indirizzo: il CAP è più affidabile del campo provincia,
+// che è scritto a mano, quindi viene provato per primo
+func Locate(postalCode, province string) (*Province, bool) {
+	if p, ok := ProvinceForCAP(postalCode); ok {
+		return p, true
+	}
+	return LookupProvince(province)
+}
@@ -0,0 +1,28 @@
+package geo
+
+import "math"
+
+const earthRadiusKm = 6371.0
+
+// DistanceGranularityKm è il passo a cui vengono arrotondate le distanze mostrate agli utenti
+const DistanceGranularityKm = 10
+
+// Distance calcola la distanza in linea d'aria in km tra due punti
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// ProvinceDistance restituisce la distanza tra i capoluoghi di due province arrotondata per
+// eccesso a DistanceGranularityKm: chi sta nella stessa provincia risulta a 10 km, così da
+// non rivelare mai la posizione esatta
+func ProvinceDistance(a, b *Province) int {
+	km := Distance(a.Lat, a.Lon, b.Lat, b.Lon)
+	steps := int(math.Ceil(km / DistanceGranularityKm))
+	return max(steps, 1) * DistanceGranularityKm
+}
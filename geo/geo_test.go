@@ -0,0 +1,39 @@
+package geo
+
+import "testing"
+
+func TestProvinceForCAP(t *testing.T) {
+	cases := map[string]string{
+		"10121": "TO",
+		"20900": "MB",
+		"20121": "MI",
+		"33170": "PN",
+		"33100": "UD",
+		"09170": "OR",
+		"89900": "VV",
+	}
+	for code, want := range cases {
+		p, ok := ProvinceForCAP(code)
+		if !ok || p.Code != want {
+			t.Errorf("CAP %s: expected %s, got %+v", code, want, p)
+		}
+	}
+
+	for _, invalid := range []string{"", "1012", "1012A", "101210"} {
+		if _, ok := ProvinceForCAP(invalid); ok {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestProvinceDistance(t *testing.T) {
+	to, _ := LookupProvince("TO")
+	mi, _ := LookupProvince("Milano")
+
+	if d := ProvinceDistance(to, to); d != DistanceGranularityKm {
+		t.Errorf("the same province must be at the minimum distance, got %d", d)
+	}
+	if d := ProvinceDistance(to, mi); d != 130 {
+		t.Errorf("expected Torino-Milano to be about 130 km, got %d", d)
+	}
+}
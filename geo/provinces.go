@@ -0,0 +1,158 @@
+package geo
+
+import (
+	"backend/seed-savers/utils"
+	"strings"
+)
+
+// Province è una provincia italiana con le coordinate approssimative del capoluogo
+// e la zona climatica (A-F) del capoluogo
+type Province struct {
+	Code        string
+	Name        string
+	Region      string
+	Lat         float64
+	Lon         float64
+	ClimateZone string
+}
+
+// provinces è la tabella offline delle province, indicizzata dalla sigla
+var provinces = []Province{
+	{"AG", "Agrigento", "Sicilia", 37.311, 13.576, "B"},
+	{"AL", "Alessandria", "Piemonte", 44.913, 8.615, "E"},
+	{"AN", "Ancona", "Marche", 43.617, 13.518, "D"},
+	{"AO", "Aosta", "Valle d'Aosta", 45.737, 7.320, "E"},
+	{"AP", "Ascoli Piceno", "Marche", 42.854, 13.575, "D"},
+	{"AQ", "L'Aquila", "Abruzzo", 42.350, 13.400, "E"},
+	{"AR", "Arezzo", "Toscana", 43.463, 11.880, "E"},
+	{"AT", "Asti", "Piemonte", 44.900, 8.207, "E"},
+	{"AV", "Avellino", "Campania", 40.914, 14.790, "D"},
+	{"BA", "Bari", "Puglia", 41.117, 16.872, "C"},
+	{"BG", "Bergamo", "Lombardia", 45.698, 9.677, "E"},
+	{"BI", "Biella", "Piemonte", 45.566, 8.054, "E"},
+	{"BL", "Belluno", "Veneto", 46.142, 12.217, "F"},
+	{"BN", "Benevento", "Campania", 41.130, 14.782, "C"},
+	{"BO", "Bologna", "Emilia-Romagna", 44.494, 11.343, "E"},
+	{"BR", "Brindisi", "Puglia", 40.632, 17.936, "C"},
+	{"BS", "Brescia", "Lombardia", 45.541, 10.211, "E"},
+	{"BT", "Barletta-Andria-Trani", "Puglia", 41.227, 16.295, "C"},
+	{"BZ", "Bolzano", "Trentino-Alto Adige", 46.498, 11.354, "E"},
+	{"CA", "Cagliari", "Sardegna", 39.223, 9.121, "C"},
+	{"CB", "Campobasso", "Molise", 41.561, 14.668, "D"},
+	{"CE", "Caserta", "Campania", 41.074, 14.332, "C"},
+	{"CH", "Chieti", "Abruzzo", 42.351, 14.167, "D"},
+	{"CL", "Caltanissetta", "Sicilia", 37.490, 14.062, "C"},
+	{"CN", "Cuneo", "Piemonte", 44.384, 7.542, "F"},
+	{"CO", "Como", "Lombardia", 45.808, 9.085, "E"},
+	{"CR", "Cremona", "Lombardia", 45.133, 10.022, "E"},
+	{"CS", "Cosenza", "Calabria", 39.298, 16.254, "C"},
+	{"CT", "Catania", "Sicilia", 37.502, 15.087, "B"},
+	{"CZ", "Catanzaro", "Calabria", 38.910, 16.588, "C"},
+	{"EN", "Enna", "Sicilia", 37.567, 14.279, "E"},
+	{"FC", "Forlì-Cesena", "Emilia-Romagna", 44.222, 12.041, "D"},
+	{"FE", "Ferrara", "Emilia-Romagna", 44.838, 11.620, "E"},
+	{"FG", "Foggia", "Puglia", 41.462, 15.545, "C"},
+	{"FI", "Firenze", "Toscana", 43.770, 11.255, "D"},
+	{"FM", "Fermo", "Marche", 43.160, 13.718, "D"},
+	{"FR", "Frosinone", "Lazio", 41.640, 13.340, "D"},
+	{"GE", "Genova", "Liguria", 44.405, 8.946, "D"},
+	{"GO", "Gorizia", "Friuli-Venezia Giulia", 45.941, 13.622, "E"},
+	{"GR", "Grosseto", "Toscana", 42.763, 11.113, "D"},
+	{"IM", "Imperia", "Liguria", 43.889, 8.039, "C"},
+	{"IS", "Isernia", "Molise", 41.594, 14.233, "D"},
+	{"KR", "Crotone", "Calabria", 39.081, 17.127, "B"},
+	{"LC", "Lecco", "Lombardia", 45.856, 9.397, "E"},
+	{"LE", "Lecce", "Puglia", 40.352, 18.172, "C"},
+	{"LI", "Livorno", "Toscana", 43.548, 10.311, "D"},
+	{"LO", "Lodi", "Lombardia", 45.314, 9.503, "E"},
+	{"LT", "Latina", "Lazio", 41.468, 12.904, "C"},
+	{"LU", "Lucca", "Toscana", 43.843, 10.505, "D"},
+	{"MB", "Monza e Brianza", "Lombardia", 45.584, 9.274, "E"},
+	{"MC", "Macerata", "Marche", 43.300, 13.453, "D"},
+	{"ME", "Messina", "Sicilia", 38.194, 15.554, "B"},
+	{"MI", "Milano", "Lombardia", 45.464, 9.190, "E"},
+	{"MN", "Mantova", "Lombardia", 45.156, 10.791, "E"},
+	{"MO", "Modena", "Emilia-Romagna", 44.647, 10.925, "E"},
+	{"MS", "Massa-Carrara", "Toscana", 44.036, 10.141, "D"},
+	{"MT", "Matera", "Basilicata", 40.666, 16.604, "D"},
+	{"NA", "Napoli", "Campania", 40.852, 14.268, "C"},
+	{"NO", "Novara", "Piemonte", 45.446, 8.622, "E"},
+	{"NU", "Nuoro", "Sardegna", 40.321, 9.330, "D"},
+	{"OR", "Oristano", "Sardegna", 39.904, 8.592, "C"},
+	{"PA", "Palermo", "Sicilia", 38.116, 13.361, "B"},
+	{"PC", "Piacenza", "Emilia-Romagna", 45.052, 9.693, "E"},
+	{"PD", "Padova", "Veneto", 45.406, 11.877, "E"},
+	{"PE", "Pescara", "Abruzzo", 42.464, 14.214, "D"},
+	{"PG", "Perugia", "Umbria", 43.112, 12.389, "E"},
+	{"PI", "Pisa", "Toscana", 43.723, 10.402, "D"},
+	{"PN", "Pordenone", "Friuli-Venezia Giulia", 45.956, 12.660, "E"},
+	{"PO", "Prato", "Toscana", 43.880, 11.097, "D"},
+	{"PR", "Parma", "Emilia-Romagna", 44.801, 10.328, "E"},
+	{"PT", "Pistoia", "Toscana", 43.933, 10.917, "D"},
+	{"PU", "Pesaro e Urbino", "Marche", 43.910, 12.913, "D"},
+	{"PV", "Pavia", "Lombardia", 45.185, 9.160, "E"},
+	{"PZ", "Potenza", "Basilicata", 40.640, 15.806, "E"},
+	{"RA", "Ravenna", "Emilia-Romagna", 44.418, 12.204, "E"},
+	{"RC", "Reggio Calabria", "Calabria", 38.111, 15.647, "B"},
+	{"RE", "Reggio Emilia", "Emilia-Romagna", 44.698, 10.631, "E"},
+	{"RG", "Ragusa", "Sicilia", 36.927, 14.725, "C"},
+	{"RI", "Rieti", "Lazio", 42.404, 12.862, "E"},
+	{"RM", "Roma", "Lazio", 41.893, 12.483, "D"},
+	{"RN", "Rimini", "Emilia-Romagna", 44.060, 12.566, "E"},
+	{"RO", "Rovigo", "Veneto", 45.070, 11.790, "E"},
+	{"SA", "Salerno", "Campania", 40.683, 14.768, "C"},
+	{"SI", "Siena", "Toscana", 43.318, 11.331, "D"},
+	{"SO", "Sondrio", "Lombardia", 46.170, 9.872, "E"},
+	{"SP", "La Spezia", "Liguria", 44.102, 9.824, "D"},
+	{"SR", "Siracusa", "Sicilia", 37.075, 15.286, "B"},
+	{"SS", "Sassari", "Sardegna", 40.727, 8.561, "C"},
+	{"SU", "Sud Sardegna", "Sardegna", 39.167, 8.522, "C"},
+	{"SV", "Savona", "Liguria", 44.308, 8.481, "D"},
+	{"TA", "Taranto", "Puglia", 40.464, 17.247, "C"},
+	{"TE", "Teramo", "Abruzzo", 42.659, 13.704, "D"},
+	{"TN", "Trento", "Trentino-Alto Adige", 46.067, 11.121, "E"},
+	{"TO", "Torino", "Piemonte", 45.070, 7.686, "E"},
+	{"TP", "Trapani", "Sicilia", 38.018, 12.514, "B"},
+	{"TR", "Terni", "Umbria", 42.563, 12.643, "D"},
+	{"TS", "Trieste", "Friuli-Venezia Giulia", 45.650, 13.777, "E"},
+	{"TV", "Treviso", "Veneto", 45.667, 12.245, "E"},
+	{"UD", "Udine", "Friuli-Venezia Giulia", 46.063, 13.235, "E"},
+	{"VA", "Varese", "Lombardia", 45.820, 8.825, "E"},
+	{"VB", "Verbano-Cusio-Ossola", "Piemonte", 45.921, 8.551, "E"},
+	{"VC", "Vercelli", "Piemonte", 45.320, 8.419, "E"},
+	{"VE", "Venezia", "Veneto", 45.441, 12.316, "E"},
+	{"VI", "Vicenza", "Veneto", 45.548, 11.547, "E"},
+	{"VR", "Verona", "Veneto", 45.438, 10.992, "E"},
+	{"VT", "Viterbo", "Lazio", 42.420, 12.107, "D"},
+	{"VV", "Vibo Valentia", "Calabria", 38.676, 16.101, "C"},
+}
+
+var byKey = indexProvinces()
+
+func indexProvinces() map[string]*Province {
+	index := make(map[string]*Province, len(provinces)*2)
+	for i := range provinces {
+		p := &provinces[i]
+		index[strings.ToLower(p.Code)] = p
+		index[utils.NormalizeName(p.Name)] = p
+	}
+	return index
+}
+
+// LookupProvince trova una provincia dalla sigla ("TO") o dal nome ("Torino"), senza
+// badare a maiuscole e accenti
+func LookupProvince(province string) (*Province, bool) {
+	key := strings.TrimSpace(province)
+	if len(key) == 2 {
+		p, ok := byKey[strings.ToLower(key)]
+		return p, ok
+	}
+
+	p, ok := byKey[utils.NormalizeName(key)]
+	return p, ok
+}
+
+// Provinces restituisce tutte le province della tabella
+func Provinces() []Province {
+	return append([]Province(nil), provinces...)
+}
@@ -0,0 +1,72 @@
+package postal
+
+import "backend/seed-savers/utils"
+
+// Country è un paese in cui si possono spedire semi. Name è il nome salvato negli indirizzi,
+// Aliases gli altri nomi con cui gli utenti lo scrivono
+type Country struct {
+	Code    string
+	Name    string
+	Aliases []string
+}
+
+// countries è la lista offline dei paesi ammessi: l'Unione Europea e i paesi vicini
+var countries = []Country{
+	{"AT", "Austria", []string{"Österreich"}},
+	{"BE", "Belgio", []string{"Belgium", "Belgique", "België"}},
+	{"BG", "Bulgaria", nil},
+	{"CH", "Svizzera", []string{"Switzerland", "Schweiz", "Suisse"}},
+	{"CY", "Cipro", []string{"Cyprus"}},
+	{"CZ", "Repubblica Ceca", []string{"Czech Republic", "Czechia", "Cechia"}},
+	{"DE", "Germania", []string{"Germany", "Deutschland"}},
+	{"DK", "Danimarca", []string{"Denmark", "Danmark"}},
+	{"EE", "Estonia", nil},
+	{"ES", "Spagna", []string{"Spain", "España"}},
+	{"FI", "Finlandia", []string{"Finland", "Suomi"}},
+	{"FR", "Francia", []string{"France"}},
+	{"GB", "Regno Unito", []string{"United Kingdom", "UK", "Gran Bretagna", "Great Britain"}},
+	{"GR", "Grecia", []string{"Greece", "Hellas"}},
+	{"HR", "Croazia", []string{"Croatia", "Hrvatska"}},
+	{"HU", "Ungheria", []string{"Hungary", "Magyarország"}},
+	{"IE", "Irlanda", []string{"Ireland"}},
+	{"IT", "Italia", []string{"Italy", "Italie", "Italien"}},
+	{"LI", "Liechtenstein", nil},
+	{"LT", "Lituania", []string{"Lithuania"}},
+	{"LU", "Lussemburgo", []string{"Luxembourg"}},
+	{"LV", "Lettonia", []string{"Latvia"}},
+	{"MC", "Monaco", []string{"Principato di Monaco"}},
+	{"MT", "Malta", nil},
+	{"NL", "Paesi Bassi", []string{"Netherlands", "Olanda", "Nederland"}},
+	{"NO", "Norvegia", []string{"Norway", "Norge"}},
+	{"PL", "Polonia", []string{"Poland", "Polska"}},
+	{"PT", "Portogallo", []string{"Portugal"}},
+	{"RO", "Romania", nil},
+	{"SE", "Svezia", []string{"Sweden", "Sverige"}},
+	{"SI", "Slovenia", []string{"Slovenija"}},
+	{"SK", "Slovacchia", []string{"Slovakia", "Slovensko"}},
+	{"SM", "San Marino", nil},
+	{"VA", "Città del Vaticano", []string{"Vaticano", "Vatican City", "Santa Sede"}},
+}
+
+var countryIndex = indexCountries()
+
+// indexCountries associa codice, nome e alias normalizzati al paese
+func indexCountries() map[string]*Country {
+	index := make(map[string]*Country, len(countries)*3)
+	for i := range countries {
+		c := &countries[i]
+		index[utils.NormalizeName(c.Code)] = c
+		index[utils.NormalizeName(c.Name)] = c
+		for _, alias := range c.Aliases {
+			index[utils.NormalizeName(alias)] = c
+		}
+	}
+	return index
+}
+
+// LookupCountry trova un paese dal codice ISO, dal nome italiano o da uno dei nomi alternativi,
+// senza badare a maiuscole e accenti
+func LookupCountry(name string) (*Country, bool) {
+	c, ok := countryIndex[utils.NormalizeName(name)]
+	return c, ok
+}
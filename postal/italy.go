@@ -0,0 +1,124 @@
+package postal
+
+import (
+	"backend/seed-savers/geo"
+	"backend/seed-savers/types"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// italianFormat controlla gli indirizzi italiani: il CAP deve esistere e appartenere alla provincia,
+// che viene salvata come sigla; comune e via prendono le maiuscole dell'italiano scritto
+type italianFormat struct{}
+
+func (italianFormat) Normalize(a *types.Adress) FieldErrors {
+	errs := FieldErrors{}
+
+	province, ok := geo.LookupProvince(a.Province)
+	if ok {
+		a.Province = province.Code
+	} else {
+		errs["province"] = "unknown province, use the two-letter code"
+	}
+
+	a.Cap = strings.ReplaceAll(a.Cap, " ", "")
+	if fromCAP, found := geo.ProvinceForCAP(a.Cap); !found {
+		errs["cap"] = "the CAP must be five digits of an existing area"
+	} else if ok && fromCAP.Code != province.Code {
+		errs["cap"] = fmt.Sprintf("the CAP %s belongs to the province of %s", a.Cap, fromCAP.Name)
+	}
+
+	if number, ok := NormalizeCivicNumber(a.Number); ok {
+		a.Number = number
+	} else {
+		errs["number"] = "the civic number is not valid, e.g. 12, 12B, 12/B or SNC"
+	}
+
+	a.City = capitalize(a.City)
+	a.Street = capitalize(expandStreetType(a.Street))
+
+	return errs
+}
+
+// streetTypes sono le abbreviazioni della specie della via (il "DUG" delle Poste), senza punti
+var streetTypes = map[string]string{
+	"v":     "Via",
+	"vle":   "Viale",
+	"v le":  "Viale",
+	"pza":   "Piazza",
+	"pzza":  "Piazza",
+	"p za":  "Piazza",
+	"p zza": "Piazza",
+	"ple":   "Piazzale",
+	"p le":  "Piazzale",
+	"cso":   "Corso",
+	"c so":  "Corso",
+	"lgo":   "Largo",
+	"l go":  "Largo",
+	"vlo":   "Vicolo",
+	"vic":   "Vicolo",
+	"str":   "Strada",
+	"loc":   "Località",
+	"fraz":  "Frazione",
+	"bgo":   "Borgo",
+	"b go":  "Borgo",
+}
+
+// streetPrefix separa la prima parola della via, punti compresi: "p.zza", "v.le", "c.so"
+var streetPrefix = regexp.MustCompile(`^([\p{L}.]+)\s*(.*)$`)
+
+// expandStreetType scrive per esteso la specie della via se è abbreviata: "p.zza Duomo" diventa
+// "Piazza Duomo"
+func expandStreetType(street string) string {
+	m := streetPrefix.FindStringSubmatch(street)
+	if m == nil || m[2] == "" {
+		return street
+	}
+
+	key := strings.ToLower(strings.Trim(strings.ReplaceAll(m[1], ".", " "), " "))
+	if full, ok := streetTypes[key]; ok {
+		return full + " " + m[2]
+	}
+	return street
+}
+
+// particles sono le parole che nei toponimi restano minuscole, tranne all'inizio
+var particles = map[string]bool{
+	"a": true, "al": true, "alla": true, "alle": true, "ai": true, "allo": true, "agli": true,
+	"da": true, "dal": true, "dalla": true, "dai": true,
+	"de": true, "di": true, "del": true, "della": true, "delle": true, "dei": true, "degli": true, "dello": true,
+	"e": true, "ed": true, "in": true, "nel": true, "nella": true, "nell": true, "sul": true, "sulla": true, "sull": true,
+	"d": true, "l": true, "dell": true, "dall": true, "all": true,
+}
+
+// romanNumeral riconosce i numeri romani delle date nelle vie, come "Via XX Settembre"
+var romanNumeral = regexp.MustCompile(`^(?i)M{0,3}(CM|CD|D?C{0,3})(XC|XL|L?X{0,3})(IX|IV|V?I{0,3})$`)
+
+// capitalize mette la maiuscola alle parole di un toponimo lasciando minuscole le preposizioni
+// e gli articoli: "SAN GIOVANNI IN PERSICETO" diventa "San Giovanni in Persiceto",
+// "reggio nell'emilia" diventa "Reggio nell'Emilia"
+func capitalize(name string) string {
+	words := strings.Fields(strings.ReplaceAll(name, "’", "'"))
+	for i, word := range words {
+		parts := strings.Split(word, "'")
+		for j, part := range parts {
+			lower := strings.ToLower(part)
+			first := i == 0 && j == 0
+			switch {
+			case lower == "":
+			case particles[lower] && !first:
+				parts[j] = lower
+			case len(lower) > 1 && !particles[lower] && romanNumeral.MatchString(lower):
+				parts[j] = strings.ToUpper(lower)
+			default:
+				r := []rune(lower)
+				r[0] = unicode.ToUpper(r[0])
+				parts[j] = string(r)
+			}
+		}
+		words[i] = strings.Join(parts, "'")
+	}
+	return strings.Join(words, " ")
+}
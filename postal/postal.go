@@ -0,0 +1,121 @@
+package postal
+
+import (
+	"backend/seed-savers/types"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Format controlla e normalizza gli indirizzi di un paese. Riceve l'indirizzo con il paese già
+// riconosciuto e lo corregge sul posto, restituendo gli errori dei campi che non può sistemare
+type Format interface {
+	Normalize(a *types.Adress) FieldErrors
+}
+
+// formats contiene i formati registrati, indicizzati dal codice ISO del paese. I paesi senza un
+// formato proprio usano genericFormat
+var formats = map[string]Format{
+	"IT": italianFormat{},
+}
+
+// Register aggiunge o sostituisce il formato degli indirizzi di un paese
+func Register(countryCode string, f Format) {
+	formats[strings.ToUpper(countryCode)] = f
+}
+
+// FieldErrors associa il nome JSON di un campo dell'indirizzo al motivo per cui non è valido
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = fmt.Sprintf("%s: %s", field, e[field])
+	}
+	return "invalid address: " + strings.Join(messages, "; ")
+}
+
+// Normalize riconosce il paese dell'indirizzo e lo passa al suo formato. Restituisce nil se
+// l'indirizzo è valido, altrimenti FieldErrors
+func Normalize(a *types.Adress) error {
+	country, ok := LookupCountry(a.Country)
+	if !ok {
+		return FieldErrors{"state": "unknown country"}
+	}
+	a.Country = country.Name
+
+	a.City = collapseSpaces(a.City)
+	a.Street = collapseSpaces(a.Street)
+	a.Province = collapseSpaces(a.Province)
+	a.Apartment_number = collapseSpaces(a.Apartment_number)
+
+	f, ok := formats[country.Code]
+	if !ok {
+		f = genericFormat{}
+	}
+	if errs := f.Normalize(a); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// civicNumber accetta un numero civico con l'eventuale esponente: 12, 12B, 12/B, 12 bis, 12/3
+var civicNumber = regexp.MustCompile(`(?i)^(\d{1,5})\s*(?:(/|-)\s*([a-z]{1,2}|\d{1,3})|(bis|ter|quater)|([a-z]))?$`)
+
+// NormalizeCivicNumber porta un numero civico nella forma usata per le spedizioni: la lettera
+// maiuscola attaccata al numero, la barra solo se c'era, "SNC" per gli indirizzi senza civico
+func NormalizeCivicNumber(number string) (string, bool) {
+	number = collapseSpaces(number)
+	if n := strings.ToUpper(strings.ReplaceAll(number, ".", "")); n == "SNC" {
+		return n, true
+	}
+
+	m := civicNumber.FindStringSubmatch(number)
+	if m == nil {
+		return "", false
+	}
+
+	switch {
+	case m[3] != "":
+		return m[1] + m[2] + strings.ToUpper(m[3]), true
+	case m[4] != "":
+		return m[1] + " " + strings.ToLower(m[4]), true
+	case m[5] != "":
+		return m[1] + strings.ToUpper(m[5]), true
+	}
+	return m[1], true
+}
+
+// genericFormat è il formato dei paesi senza regole proprie: il numero civico deve essere
+// riconoscibile e il codice postale plausibile, gli altri campi restano come li ha scritti l'utente
+type genericFormat struct{}
+
+var genericPostalCode = regexp.MustCompile(`^[A-Z0-9][A-Z0-9 -]{1,8}[A-Z0-9]$`)
+
+func (genericFormat) Normalize(a *types.Adress) FieldErrors {
+	errs := FieldErrors{}
+
+	a.Cap = strings.ToUpper(collapseSpaces(a.Cap))
+	if !genericPostalCode.MatchString(a.Cap) {
+		errs["cap"] = "the postal code is not valid"
+	}
+
+	if number, ok := NormalizeCivicNumber(a.Number); ok {
+		a.Number = number
+	} else {
+		errs["number"] = "the civic number is not valid"
+	}
+
+	return errs
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,93 @@
+package postal
+
+import (
+	"backend/seed-savers/types"
+	"errors"
+	"testing"
+)
+
+func TestNormalizeCivicNumber(t *testing.T) {
+	cases := map[string]string{
+		"12":      "12",
+		" 12 b ":  "12B",
+		"12/b":    "12/B",
+		"12 - 3":  "12-3",
+		"12bis":   "12 bis",
+		"12 TER":  "12 ter",
+		"s.n.c.":  "SNC",
+		"1234/AB": "1234/AB",
+	}
+	for input, want := range cases {
+		if got, ok := NormalizeCivicNumber(input); !ok || got != want {
+			t.Errorf("%q: expected %q, got %q (%v)", input, want, got, ok)
+		}
+	}
+
+	for _, invalid := range []string{"", "B12", "12//B", "12 abc", "123456"} {
+		if _, ok := NormalizeCivicNumber(invalid); ok {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestNormalizeItalianAddress(t *testing.T) {
+	a := &types.Adress{Country: "IT", City: "reggio NELL’EMILIA", Street: "v.le  xx settembre", Cap: "42 121", Province: "Reggio Emilia", Number: "4/a"}
+	if err := Normalize(a); err != nil {
+		t.Fatal(err)
+	}
+
+	want := types.Adress{Country: "Italia", City: "Reggio nell'Emilia", Street: "Viale XX Settembre", Cap: "42121", Province: "RE", Number: "4/A"}
+	if *a != want {
+		t.Errorf("expected %+v, got %+v", want, *a)
+	}
+}
+
+func TestNormalizeReportsFields(t *testing.T) {
+	cases := []struct {
+		adress types.Adress
+		fields []string
+	}{
+		{types.Adress{Country: "Atlantide", Cap: "10121", Province: "TO", Number: "1"}, []string{"state"}},
+		{types.Adress{Country: "Italia", Cap: "10121", Province: "MI", Number: "1"}, []string{"cap"}},
+		{types.Adress{Country: "Italia", Cap: "1012", Province: "XX", Number: "civico"}, []string{"cap", "number", "province"}},
+		{types.Adress{Country: "Svizzera", Cap: "!", Province: "Ticino", Number: "3"}, []string{"cap"}},
+	}
+
+	for _, c := range cases {
+		var fields FieldErrors
+		if err := Normalize(&c.adress); !errors.As(err, &fields) {
+			t.Errorf("%+v: expected field errors, got %v", c.adress, err)
+			continue
+		}
+		if len(fields) != len(c.fields) {
+			t.Errorf("%+v: expected errors on %v, got %v", c.adress, c.fields, fields)
+		}
+		for _, field := range c.fields {
+			if fields[field] == "" {
+				t.Errorf("%+v: expected an error on %s, got %v", c.adress, field, fields)
+			}
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	defer delete(formats, "CH")
+	Register("ch", swissFormat{})
+
+	a := &types.Adress{Country: "Schweiz", Cap: "6900", Province: "TI", Number: "3"}
+	if err := Normalize(a); err != nil || a.Country != "Svizzera" {
+		t.Errorf("expected the swiss format to accept %+v, got %v", a, err)
+	}
+	if err := Normalize(&types.Adress{Country: "CH", Cap: "69000", Number: "3"}); err == nil {
+		t.Errorf("expected the swiss format to reject a five digit postal code")
+	}
+}
+
+type swissFormat struct{}
+
+func (swissFormat) Normalize(a *types.Adress) FieldErrors {
+	if len(a.Cap) != 4 {
+		return FieldErrors{"cap": "the postal code must be four digits"}
+	}
+	return nil
+}
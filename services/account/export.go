@@ -0,0 +1,55 @@
+package account
+
+import (
+	"archive/zip"
+	"backend/seed-savers/types"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// exportProfile è il profilo nell'archivio: i dati dell'account senza indirizzi e inventario,
+// che hanno un file a parte
+type exportProfile struct {
+	ID              int        `json:"id"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	Role            string     `json:"role"`
+	Credits         int8       `json:"credits"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt"`
+	ExportedAt      time.Time  `json:"exportedAt"`
+}
+
+// WriteArchive scrive l'archivio zip dei dati dell'utente, con un file JSON per ogni tipo di dato.
+// Le valutazioni sono i resoconti di coltivazione scritti dall'utente; i messaggi non ci sono perché
+// l'applicazione non ha ancora una messaggistica
+func WriteArchive(w io.Writer, u *types.User, data *types.DataExport, now time.Time) error {
+	files := []struct {
+		name    string
+		content any
+	}{
+		{"profile.json", exportProfile{u.ID, u.Name, u.Email, u.Role, u.Credits, u.EmailVerifiedAt, now}},
+		{"address.json", data.Addresses},
+		{"inventory.json", u.Seeds},
+		{"orders.json", data.Orders},
+		{"ratings.json", data.Ratings},
+		{"wishlist.json", data.Wishlist},
+		{"saved_searches.json", data.SavedSearches},
+	}
+
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		out, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.content); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
@@ -0,0 +1,36 @@
+package account
+
+import (
+	"backend/seed-savers/types"
+	"log"
+	"time"
+)
+
+// PurgeDue anonimizza gli account il cui periodo di ripensamento è finito e restituisce quanti sono
+func PurgeDue(store types.AccountDataStore, grace time.Duration, now time.Time) (int, error) {
+	ids, err := store.GetDueDeletions(now.Add(-grace))
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		if err := store.AnonymizeUser(id); err != nil {
+			log.Printf("failed to delete user %d: %v", id, err)
+			continue
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// RunPurge controlla ogni ora gli account da cancellare. Va avviato in una goroutine
+func RunPurge(store types.AccountDataStore, grace time.Duration) {
+	for {
+		if _, err := PurgeDue(store, grace, time.Now()); err != nil {
+			log.Printf("failed to purge deleted accounts: %v", err)
+		}
+		time.Sleep(time.Hour)
+	}
+}
@@ -0,0 +1,143 @@
+package account
+
+import (
+	"backend/seed-savers/config"
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/services/email"
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store        types.AccountDataStore
+	usersStore   types.UserStore
+	sessionStore *auth.AuthStore
+	send         func(to string, msg []byte) error
+}
+
+func NewHandler(s types.AccountDataStore, us types.UserStore, sessionStore *auth.AuthStore) *Handler {
+	return &Handler{store: s, usersStore: us, sessionStore: sessionStore, send: email.SendMail}
+}
+
+func (h *Handler) RegisterRouter(router *mux.Router) {
+	router.HandleFunc("/user/delete", auth.WithJWTAuth(h.handleRequestDeletion, h.usersStore, h.sessionStore)).Methods("DELETE")
+	router.HandleFunc("/user/delete/cancel", auth.WithJWTAuth(h.handleCancelDeletion, h.usersStore, h.sessionStore)).Methods("POST")
+	router.HandleFunc("/user/me/export", auth.WithJWTAuth(h.handleExport, h.usersStore, h.sessionStore)).Methods("GET")
+}
+
+// GracePeriod è il tempo che passa tra la richiesta di cancellazione e l'anonimizzazione dell'account
+func GracePeriod() time.Duration {
+	return time.Duration(config.Envs.DeletionGraceDays) * 24 * time.Hour
+}
+
+// handleRequestDeletion pianifica la cancellazione dell'account dopo il periodo di ripensamento.
+// Serve la password oppure, per gli account OAuth, un login recente
+func (h *Handler) handleRequestDeletion(w http.ResponseWriter, r *http.Request) {
+	payload, err := utils.DecodePayload[types.DeletionPayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	id, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	u, err := h.usersStore.GetUserByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if status, err := h.reauthenticate(r, u, payload.Password); err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	requestedAt, err := h.store.RequestDeletion(u.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	deletion := types.AccountDeletion{RequestedAt: requestedAt, DeleteAt: requestedAt.Add(GracePeriod())}
+	body := fmt.Sprintf("<html><body><h1>Ciao %s, il tuo account verrà cancellato il %s</h1>"+
+		"<p>Fino ad allora puoi annullare la cancellazione dalle impostazioni dell'account. Se non sei stato tu, cambia subito la password.</p></body></html>",
+		html.EscapeString(u.Name), deletion.DeleteAt.Format("02/01/2006"))
+	if err := h.send(u.Email, email.Message("Cancellazione dell'account", body)); err != nil {
+		log.Printf("failed to notify user %d of the deletion: %v", u.ID, err)
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, deletion)
+}
+
+func (h *Handler) handleCancelDeletion(w http.ResponseWriter, r *http.Request) {
+	id, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	if err := h.store.CancelDeletion(id); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// handleExport scarica un archivio zip con tutti i dati dell'utente
+func (h *Handler) handleExport(w http.ResponseWriter, r *http.Request) {
+	id, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	u, err := h.usersStore.GetCompleteUserByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	data, err := h.store.GetDataExport(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="seed-savers-data-%d.zip"`, id))
+
+	//l'archivio si scrive direttamente nella risposta: se si interrompe a metà resta solo il log
+	if err := WriteArchive(w, u, data, time.Now()); err != nil {
+		log.Printf("data export of user %d failed: %v", id, err)
+	}
+}
+
+// reauthenticate conferma l'identità prima di un'azione irreversibile e restituisce lo stato HTTP
+// da usare se non va a buon fine
+func (h *Handler) reauthenticate(r *http.Request, u *types.User, password string) (int, error) {
+	if u.Password != "OAUTH" {
+		if !auth.ComparePasswords(u.Password, []byte(password)) {
+			return http.StatusForbidden, fmt.Errorf("the password is wrong")
+		}
+		return 0, nil
+	}
+
+	issuedAt, err := auth.SessionIssuedAt(r, h.sessionStore)
+	if err != nil || time.Since(issuedAt) > time.Duration(config.Envs.ReauthWindowMinutes)*time.Minute {
+		return http.StatusUnauthorized, fmt.Errorf("log in again with your provider to confirm")
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,198 @@
+package account
+
+import (
+	"archive/zip"
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/sessions"
+)
+
+func TestRequestDeletion(t *testing.T) {
+	hash, _ := auth.HashPassword("secret")
+	users := &mockUserStore{users: map[int]*types.User{
+		7: {ID: 7, Name: "Anna", Email: "anna@mail.com", Password: hash},
+		8: {ID: 8, Name: "Bruno", Email: "bruno@mail.com", Password: "OAUTH"},
+	}}
+	store := &mockAccountStore{requestedAt: time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)}
+	handler := NewHandler(store, users, &auth.AuthStore{Store: sessions.NewCookieStore([]byte{5})})
+	var mails []string
+	handler.send = func(to string, msg []byte) error {
+		mails = append(mails, to)
+		return nil
+	}
+
+	request := func(userID int, password string) *httptest.ResponseRecorder {
+		marshalled, _ := json.Marshal(types.DeletionPayload{Password: password})
+		req := httptest.NewRequest(http.MethodDelete, "/user/delete", bytes.NewBuffer(marshalled))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, userID))
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/user/delete", handler.handleRequestDeletion)
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should require the password", func(t *testing.T) {
+		if rr := request(7, "wrong"); rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rr.Code)
+		}
+		if len(store.requested) != 0 {
+			t.Errorf("the deletion should not be scheduled")
+		}
+	})
+
+	t.Run("should ask OAuth users to log in again", func(t *testing.T) {
+		if rr := request(8, ""); rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %d but got %d", http.StatusUnauthorized, rr.Code)
+		}
+	})
+
+	t.Run("should schedule the deletion after the grace period", func(t *testing.T) {
+		rr := request(7, "secret")
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("expected status code %d but got %d", http.StatusAccepted, rr.Code)
+		}
+
+		var deletion types.AccountDeletion
+		if err := json.Unmarshal(rr.Body.Bytes(), &deletion); err != nil {
+			t.Fatal(err)
+		}
+		if !deletion.DeleteAt.Equal(store.requestedAt.Add(GracePeriod())) {
+			t.Errorf("unexpected deletion date %v", deletion.DeleteAt)
+		}
+		if fmt.Sprint(store.requested, mails) != "[7] [anna@mail.com]" {
+			t.Errorf("expected the deletion of user 7 to be scheduled and notified, got %v %v", store.requested, mails)
+		}
+	})
+}
+
+func TestExport(t *testing.T) {
+	users := &mockUserStore{users: map[int]*types.User{
+		7: {ID: 7, Name: "Anna", Email: "anna@mail.com", Password: "hash", Seeds: []types.Seed{{ID: 3, Variety_name: "Cuore di bue", Quantity: 20}}},
+	}}
+	store := &mockAccountStore{}
+	handler := NewHandler(store, users, &auth.AuthStore{Store: sessions.NewCookieStore([]byte{5})})
+
+	req := httptest.NewRequest(http.MethodGet, "/user/me/export", nil)
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, 7))
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/user/me/export", handler.handleExport)
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("expected a zip archive, got %d %s", rr.Code, rr.Header().Get("Content-Type"))
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := make(map[string]string)
+	names := make([]string, 0)
+	for _, f := range archive.File {
+		rc, _ := f.Open()
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[f.Name] = string(b)
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+
+	if fmt.Sprint(names) != "[address.json inventory.json orders.json profile.json ratings.json saved_searches.json wishlist.json]" {
+		t.Errorf("unexpected files %v", names)
+	}
+	if !bytes.Contains([]byte(contents["orders.json"]), []byte(`"counterpart": "Bruno"`)) ||
+		!bytes.Contains([]byte(contents["address.json"]), []byte(`"label": "Orto"`)) ||
+		!bytes.Contains([]byte(contents["inventory.json"]), []byte("Cuore di bue")) ||
+		bytes.Contains([]byte(contents["profile.json"]), []byte("hash")) {
+		t.Errorf("unexpected archive contents %v", contents)
+	}
+}
+
+func TestPurgeDue(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	store := &mockAccountStore{due: []int{4, 5, 6}, failing: 5}
+
+	purged, err := PurgeDue(store, 14*24*time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 2 || fmt.Sprint(store.anonymized) != "[4 6]" {
+		t.Errorf("expected users 4 and 6 to be purged, got %d %v", purged, store.anonymized)
+	}
+	if !store.dueBefore.Equal(now.AddDate(0, 0, -14)) {
+		t.Errorf("expected deletions requested before %v, got %v", now.AddDate(0, 0, -14), store.dueBefore)
+	}
+}
+
+type mockAccountStore struct {
+	requestedAt time.Time
+	requested   []int
+	due         []int
+	dueBefore   time.Time
+	failing     int
+	anonymized  []int
+}
+
+func (m *mockAccountStore) RequestDeletion(userID int) (time.Time, error) {
+	m.requested = append(m.requested, userID)
+	return m.requestedAt, nil
+}
+
+func (m *mockAccountStore) CancelDeletion(userID int) error {
+	return nil
+}
+
+func (m *mockAccountStore) GetDueDeletions(requestedBefore time.Time) ([]int, error) {
+	m.dueBefore = requestedBefore
+	return m.due, nil
+}
+
+func (m *mockAccountStore) AnonymizeUser(userID int) error {
+	if userID == m.failing {
+		return fmt.Errorf("database unavailable")
+	}
+	m.anonymized = append(m.anonymized, userID)
+	return nil
+}
+
+func (m *mockAccountStore) GetDataExport(userID int) (*types.DataExport, error) {
+	return &types.DataExport{
+		Addresses:     []types.Adress{{ID: 2, Label: "Orto", Default: true, City: "Bergamo"}},
+		Orders:        []types.DataExportOrder{{ID: 1, Role: "received", Counterpart: "Bruno", State: "Arrivato", SeedID: 3, Quantity: 5}},
+		Ratings:       []types.GrowReport{},
+		Wishlist:      []types.WishlistItem{},
+		SavedSearches: []types.SavedSearch{},
+	}, nil
+}
+
+type mockUserStore struct {
+	types.UserStore
+	users map[int]*types.User
+}
+
+func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
+	u, ok := m.users[id]
+	if !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+	return u, nil
+}
+
+func (m *mockUserStore) GetCompleteUserByID(id int) (*types.User, error) {
+	return m.GetUserByID(id)
+}
@@ -0,0 +1,277 @@
+package account
+
+import (
+	"backend/seed-savers/types"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// unshippedOrders seleziona gli ordini non ancora spediti né annullati: i semi sono ancora dal mittente
+const unshippedOrders = "state IN ('In attesa', 'In preparazione')"
+
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore crea e restituisce un nuovo oggetto Store con il database passato come parametro
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// RequestDeletion avvia il periodo di ripensamento; una richiesta già in corso mantiene la sua data
+func (s *Store) RequestDeletion(userID int) (time.Time, error) {
+	_, err := s.db.Exec("UPDATE users SET deletion_requested_at = COALESCE(deletion_requested_at, CURRENT_TIMESTAMP) WHERE user_id = ? AND status <> 'deleted'", userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var requestedAt sql.NullTime
+	if err := s.db.QueryRow("SELECT deletion_requested_at FROM users WHERE user_id = ?", userID).Scan(&requestedAt); err != nil {
+		return time.Time{}, err
+	}
+	if !requestedAt.Valid {
+		return time.Time{}, fmt.Errorf("the account can't be deleted")
+	}
+
+	return requestedAt.Time, nil
+}
+
+// CancelDeletion annulla una cancellazione ancora nel periodo di ripensamento
+func (s *Store) CancelDeletion(userID int) error {
+	res, err := s.db.Exec("UPDATE users SET deletion_requested_at = NULL WHERE user_id = ? AND deletion_requested_at IS NOT NULL", userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("no deletion is pending")
+	}
+
+	return nil
+}
+
+// GetDueDeletions restituisce gli utenti che hanno chiesto la cancellazione prima di requestedBefore
+func (s *Store) GetDueDeletions(requestedBefore time.Time) ([]int, error) {
+	rows, err := s.db.Query("SELECT user_id FROM users WHERE deletion_requested_at IS NOT NULL AND deletion_requested_at <= ?", requestedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// AnonymizeUser cancella i dati personali dell'utente senza eliminarne la riga, così gli ordini restano
+// nello storico dell'altra parte con un nome anonimo. Gli ordini non ancora spediti vengono annullati
+// e i semi tornano nell'inventario di chi li doveva spedire; quelli già in viaggio restano com'erano.
+// I resoconti di coltivazione restano, anonimi
+func (s *Store) AnonymizeUser(userID int) error {
+	// Inizia una transazione
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Assicura che il rollback venga eseguito in caso di errore
+
+	var status string
+	if err := tx.QueryRow("SELECT status FROM users WHERE user_id = ? FOR UPDATE", userID).Scan(&status); err != nil {
+		return fmt.Errorf("user not found")
+	}
+	if status == types.UserDeleted {
+		return nil
+	}
+
+	// i semi che l'utente aspettava tornano al mittente; quelli che doveva spedire spariscono con il suo inventario
+	_, err = tx.Exec(`INSERT INTO users_seed (user_id, seed_id, quantity, restocked_at)
+		SELECT o.sender_user_id, od.seed_id, SUM(od.quantity), CURRENT_TIMESTAMP
+		FROM orders o INNER JOIN order_detail od ON o.order_id = od.order_id
+		WHERE o.reciver_user_id = ? AND o.sender_user_id <> ? AND o.`+unshippedOrders+`
+		GROUP BY o.sender_user_id, od.seed_id
+		ON DUPLICATE KEY UPDATE quantity = quantity + VALUES(quantity), restocked_at = CURRENT_TIMESTAMP`, userID, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE orders SET state = 'Annullato' WHERE (sender_user_id = ? OR reciver_user_id = ?) AND "+unshippedOrders, userID, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, query := range []string{
+		"DELETE FROM adress WHERE user_id = ?",
+		`UPDATE orders SET shipping_label = NULL, shipping_state = NULL, shipping_city = NULL, shipping_street = NULL,
+			shipping_cap = NULL, shipping_province = NULL, shipping_number = NULL, shipping_apartment_number = NULL
+			WHERE reciver_user_id = ?`,
+		"DELETE FROM users_seed WHERE user_id = ?",
+		"DELETE FROM wishlist WHERE user_id = ?",
+		"DELETE FROM alert WHERE user_id = ?",
+		"DELETE FROM saved_search WHERE user_id = ?",
+		"DELETE FROM user_block WHERE blocker_user_id = ?",
+		"DELETE FROM email_verification WHERE user_id = ?",
+		"UPDATE seed_lot SET province = NULL WHERE user_id = ?",
+		"UPDATE grow_report SET province = NULL, notes = NULL WHERE user_id = ?",
+	} {
+		if _, err := tx.Exec(query, userID); err != nil {
+			return err
+		}
+	}
+
+	// l'email fittizia resta unica e non corrisponde a nessun indirizzo reale; il nuovo token_version
+	// chiude tutte le sessioni
+	_, err = tx.Exec(`UPDATE users SET name = 'Utente eliminato', email = CONCAT('deleted-', user_id, '@invalid'), password = '',
+		credits = 0, email_verified_at = NULL, token_version = token_version + 1, calendar_token = NULL, climate_zone = NULL,
+		vacation_mode = FALSE, owner_visibility = 'hidden', deletion_requested_at = NULL, status = 'deleted'
+		WHERE user_id = ?`, userID)
+	if err != nil {
+		return err
+	}
+
+	// Conferma la transazione
+	return tx.Commit()
+}
+
+// GetDataExport raccoglie rubrica degli indirizzi, ordini, resoconti, lista dei desideri e ricerche salvate dell'utente
+func (s *Store) GetDataExport(userID int) (*types.DataExport, error) {
+	export := &types.DataExport{}
+	var err error
+
+	if export.Addresses, err = s.exportAddresses(userID); err != nil {
+		return nil, err
+	}
+	if export.Orders, err = s.exportOrders(userID); err != nil {
+		return nil, err
+	}
+	if export.Ratings, err = s.exportRatings(userID); err != nil {
+		return nil, err
+	}
+	if export.Wishlist, err = s.exportWishlist(userID); err != nil {
+		return nil, err
+	}
+	if export.SavedSearches, err = s.exportSavedSearches(userID); err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+func (s *Store) exportAddresses(userID int) ([]types.Adress, error) {
+	rows, err := s.db.Query(`SELECT adress_id, label, is_default IS NOT NULL, state, city, street, cap, province, number, apartment_number
+		FROM adress WHERE user_id = ? ORDER BY adress_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := make([]types.Adress, 0)
+	for rows.Next() {
+		a := types.Adress{UserID: userID}
+		var apartment sql.NullString
+		if err := rows.Scan(&a.ID, &a.Label, &a.Default, &a.Country, &a.City, &a.Street, &a.Cap, &a.Province, &a.Number, &apartment); err != nil {
+			return nil, err
+		}
+		a.Apartment_number = apartment.String
+		addresses = append(addresses, a)
+	}
+
+	return addresses, rows.Err()
+}
+
+func (s *Store) exportOrders(userID int) ([]types.DataExportOrder, error) {
+	rows, err := s.db.Query(`SELECT o.order_id, IF(o.sender_user_id = ?, 'sent', 'received'), other.name, o.state, o.order_date,
+			s.seed_id, s.variety_name, od.quantity
+		FROM orders o
+		INNER JOIN order_detail od ON o.order_id = od.order_id
+		INNER JOIN seed s ON od.seed_id = s.seed_id
+		INNER JOIN users other ON other.user_id = IF(o.sender_user_id = ?, o.reciver_user_id, o.sender_user_id)
+		WHERE o.sender_user_id = ? OR o.reciver_user_id = ?
+		ORDER BY o.order_date, o.order_id`, userID, userID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := make([]types.DataExportOrder, 0)
+	for rows.Next() {
+		var o types.DataExportOrder
+		var counterpart sql.NullString
+		if err := rows.Scan(&o.ID, &o.Role, &counterpart, &o.State, &o.OrderDate, &o.SeedID, &o.Variety_name, &o.Quantity); err != nil {
+			return nil, err
+		}
+		o.Counterpart = counterpart.String
+		orders = append(orders, o)
+	}
+
+	return orders, rows.Err()
+}
+
+func (s *Store) exportRatings(userID int) ([]types.GrowReport, error) {
+	rows, err := s.db.Query(`SELECT report_id, seed_id, order_id, year, province, germination, yield, disease_resistance, taste, notes, status, created_at
+		FROM grow_report WHERE user_id = ? ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := make([]types.GrowReport, 0)
+	for rows.Next() {
+		r := types.GrowReport{UserID: userID, Photos: make([]string, 0)}
+		var orderID sql.NullInt64
+		var province, notes sql.NullString
+		if err := rows.Scan(&r.ID, &r.SeedID, &orderID, &r.Year, &province, &r.Germination, &r.Yield, &r.DiseaseResistance, &r.Taste, &notes, &r.Status, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		r.OrderID, r.Province, r.Notes = int(orderID.Int64), province.String, notes.String
+		reports = append(reports, r)
+	}
+
+	return reports, rows.Err()
+}
+
+func (s *Store) exportWishlist(userID int) ([]types.WishlistItem, error) {
+	rows, err := s.db.Query(`SELECT s.seed_id, s.variety_name, s.vegetable, w.created_at
+		FROM wishlist w INNER JOIN seed s ON w.seed_id = s.seed_id
+		WHERE w.user_id = ? ORDER BY w.created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]types.WishlistItem, 0)
+	for rows.Next() {
+		var item types.WishlistItem
+		if err := rows.Scan(&item.SeedID, &item.Variety_name, &item.Vegetable, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func (s *Store) exportSavedSearches(userID int) ([]types.SavedSearch, error) {
+	rows, err := s.db.Query("SELECT search_id, name, query, vegetable, region, created_at FROM saved_search WHERE user_id = ? ORDER BY created_at", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := make([]types.SavedSearch, 0)
+	for rows.Next() {
+		search := types.SavedSearch{UserID: userID}
+		if err := rows.Scan(&search.ID, &search.Name, &search.Query, &search.Vegetable, &search.Region, &search.CreatedAt); err != nil {
+			return nil, err
+		}
+		searches = append(searches, search)
+	}
+
+	return searches, rows.Err()
+}
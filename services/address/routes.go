@@ -0,0 +1,204 @@
+package address
+
+import (
+	"backend/seed-savers/postal"
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// DefaultLabel è l'etichetta degli indirizzi salvati senza un nome
+const DefaultLabel = "Casa"
+
+type Handler struct {
+	store        types.AddressStore
+	usersStore   types.UserStore
+	sessionStore *auth.AuthStore
+}
+
+func NewHandler(s types.AddressStore, us types.UserStore, sessionStore *auth.AuthStore) *Handler {
+	return &Handler{s, us, sessionStore}
+}
+
+func (h *Handler) RegisterRouter(router *mux.Router) {
+	router.HandleFunc("/user/addresses", auth.WithJWTAuth(h.handleGetAddresses, h.usersStore, h.sessionStore)).Methods("GET")
+	router.HandleFunc("/user/addresses", auth.WithJWTAuth(h.handleCreateAddress, h.usersStore, h.sessionStore)).Methods("POST")
+	router.HandleFunc("/user/addresses/{adressID:[0-9]+}", auth.WithJWTAuth(h.handleUpdateAddress, h.usersStore, h.sessionStore)).Methods("PUT")
+	router.HandleFunc("/user/addresses/{adressID:[0-9]+}", auth.WithJWTAuth(h.handleDeleteAddress, h.usersStore, h.sessionStore)).Methods("DELETE")
+	router.HandleFunc("/user/addresses/{adressID:[0-9]+}/default", auth.WithJWTAuth(h.handleSetDefault, h.usersStore, h.sessionStore)).Methods("POST")
+
+	//i vecchi endpoint restano per i client esistenti: aggiungono alla rubrica e modificano l'indirizzo predefinito
+	router.HandleFunc("/register/adress", auth.WithJWTAuth(h.handleCreateAddress, h.usersStore, h.sessionStore)).Methods("POST")
+	router.HandleFunc("/register/adress", auth.WithJWTAuth(h.handleUpdateDefault, h.usersStore, h.sessionStore)).Methods("PUT")
+}
+
+func (h *Handler) handleGetAddresses(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	addresses, err := h.store.GetAddresses(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, addresses)
+}
+
+func (h *Handler) handleCreateAddress(w http.ResponseWriter, r *http.Request) {
+	adress, err := decodeAdress(w, r)
+	if err != nil {
+		writeInvalid(w, err)
+		return
+	}
+
+	if err := h.store.CreateAddress(adress); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, adress)
+}
+
+func (h *Handler) handleUpdateAddress(w http.ResponseWriter, r *http.Request) {
+	adress, err := decodeAdress(w, r)
+	if err != nil {
+		writeInvalid(w, err)
+		return
+	}
+	adress.ID, _ = strconv.Atoi(mux.Vars(r)["adressID"])
+
+	h.update(w, adress)
+}
+
+// handleUpdateDefault modifica l'indirizzo predefinito, come faceva PUT /register/adress con l'unico indirizzo
+func (h *Handler) handleUpdateDefault(w http.ResponseWriter, r *http.Request) {
+	adress, err := decodeAdress(w, r)
+	if err != nil {
+		writeInvalid(w, err)
+		return
+	}
+
+	addresses, err := h.store.GetAddresses(adress.UserID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if len(addresses) == 0 || !addresses[0].Default {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("address not found"))
+		return
+	}
+	adress.ID = addresses[0].ID
+
+	h.update(w, adress)
+}
+
+func (h *Handler) handleDeleteAddress(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+	adressID, _ := strconv.Atoi(mux.Vars(r)["adressID"])
+
+	if err := h.store.DeleteAddress(userID, adressID); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *Handler) handleSetDefault(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+	adressID, _ := strconv.Atoi(mux.Vars(r)["adressID"])
+
+	if err := h.store.SetDefaultAddress(userID, adressID); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// update salva le modifiche di un indirizzo dell'utente. Un indirizzo predefinito resta tale anche
+// se la richiesta non lo chiede: si cambia predefinito scegliendone un altro
+func (h *Handler) update(w http.ResponseWriter, adress *types.Adress) {
+	if _, err := h.store.GetAddress(adress.UserID, adress.ID); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err := h.store.UpdateAddress(adress); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	updated, err := h.store.GetAddress(adress.UserID, adress.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, updated)
+}
+
+func decodeAdress(w http.ResponseWriter, r *http.Request) (*types.Adress, error) {
+	payload, err := utils.DecodePayload[types.AdressPayload](w, r)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	label := payload.Label
+	if label == "" {
+		label = DefaultLabel
+	}
+
+	adress := &types.Adress{
+		UserID:           userID,
+		Label:            label,
+		Default:          payload.Default,
+		Country:          payload.State,
+		City:             payload.City,
+		Street:           payload.Street,
+		Cap:              payload.Cap,
+		Province:         payload.Province,
+		Apartment_number: payload.Apartment_number,
+		Number:           string(payload.Number),
+	}
+
+	//l'indirizzo viene salvato già normalizzato, così ordini e ricerche per provincia lo trovano uguale
+	if err := postal.Normalize(adress); err != nil {
+		return nil, err
+	}
+
+	return adress, nil
+}
+
+// writeInvalid risponde 400; se l'indirizzo non è valido, indica anche il motivo di ogni campo
+func writeInvalid(w http.ResponseWriter, err error) {
+	var fields postal.FieldErrors
+	if errors.As(err, &fields) {
+		utils.WriteJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error(), "fields": fields})
+		return
+	}
+	utils.WriteError(w, http.StatusBadRequest, err)
+}
@@ -0,0 +1,234 @@
+package address
+
+import (
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/sessions"
+)
+
+func TestAddressBook(t *testing.T) {
+	store := &mockAddressStore{}
+	handler := NewHandler(store, nil, &auth.AuthStore{Store: sessions.NewCookieStore([]byte{5})})
+
+	request := func(method, path, route string, h http.HandlerFunc, payload any) *httptest.ResponseRecorder {
+		marshalled, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(marshalled))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, 7))
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc(route, h)
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	adress := func(label, city string, isDefault bool) types.AdressPayload {
+		return types.AdressPayload{State: "Italia", City: city, Street: "Via Roma", Cap: "24100", Province: "BG", Number: "3", Label: label, Default: isDefault}
+	}
+
+	t.Run("should accept a second address on the legacy endpoint", func(t *testing.T) {
+		for _, city := range []string{"Bergamo", "Clusone"} {
+			if rr := request(http.MethodPost, "/register/adress", "/register/adress", handler.handleCreateAddress, adress("", city, false)); rr.Code != http.StatusCreated {
+				t.Fatalf("expected status code %d but got %d", http.StatusCreated, rr.Code)
+			}
+		}
+		if fmt.Sprint(store.summary()) != "[1:Casa:Bergamo:default 2:Casa:Clusone]" {
+			t.Errorf("expected the first address to be the default, got %v", store.summary())
+		}
+	})
+
+	t.Run("should move the default to a new address when asked", func(t *testing.T) {
+		rr := request(http.MethodPost, "/user/addresses", "/user/addresses", handler.handleCreateAddress, adress("Orto", "Ardesio", true))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d", http.StatusCreated, rr.Code)
+		}
+		if fmt.Sprint(store.summary()) != "[1:Casa:Bergamo 2:Casa:Clusone 3:Orto:Ardesio:default]" {
+			t.Errorf("unexpected address book %v", store.summary())
+		}
+	})
+
+	t.Run("should keep the default when an update does not ask for it", func(t *testing.T) {
+		rr := request(http.MethodPut, "/user/addresses/3", "/user/addresses/{adressID:[0-9]+}", handler.handleUpdateAddress, adress("Orto", "Gromo", false))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, rr.Code)
+		}
+		if fmt.Sprint(store.summary()) != "[1:Casa:Bergamo 2:Casa:Clusone 3:Orto:Gromo:default]" {
+			t.Errorf("unexpected address book %v", store.summary())
+		}
+	})
+
+	t.Run("should update the default address on the legacy endpoint", func(t *testing.T) {
+		rr := request(http.MethodPut, "/register/adress", "/register/adress", handler.handleUpdateDefault, adress("Orto", "Valbondione", false))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, rr.Code)
+		}
+		if fmt.Sprint(store.summary()) != "[1:Casa:Bergamo 2:Casa:Clusone 3:Orto:Valbondione:default]" {
+			t.Errorf("unexpected address book %v", store.summary())
+		}
+	})
+
+	t.Run("should not touch addresses of other users", func(t *testing.T) {
+		store.addresses = append(store.addresses, types.Adress{ID: 9, UserID: 8, Label: "Casa", Default: true, City: "Lecco"})
+
+		if rr := request(http.MethodPut, "/user/addresses/9", "/user/addresses/{adressID:[0-9]+}", handler.handleUpdateAddress, adress("Casa", "Milano", false)); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+		if rr := request(http.MethodDelete, "/user/addresses/9", "/user/addresses/{adressID:[0-9]+}", handler.handleDeleteAddress, nil); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+		store.addresses = store.addresses[:3]
+	})
+
+	t.Run("should reject an invalid address", func(t *testing.T) {
+		payload := adress("Casa", "", false)
+		if rr := request(http.MethodPost, "/user/addresses", "/user/addresses", handler.handleCreateAddress, payload); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should explain which fields are wrong", func(t *testing.T) {
+		payload := adress("Casa", "Bergamo", false)
+		payload.Cap, payload.Number = "20121", "12//B"
+
+		rr := request(http.MethodPost, "/user/addresses", "/user/addresses", handler.handleCreateAddress, payload)
+		var body struct {
+			Fields map[string]string `json:"fields"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil || rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected status code %d with field errors, got %d %s", http.StatusBadRequest, rr.Code, rr.Body)
+		}
+		if body.Fields["cap"] == "" || body.Fields["number"] == "" || len(body.Fields) != 2 {
+			t.Errorf("expected errors on cap and number, got %v", body.Fields)
+		}
+	})
+
+	t.Run("should save the address normalized", func(t *testing.T) {
+		payload := json.RawMessage(`{"state": "italy", "city": "SAN GIOVANNI BIANCO", "street": "p.zza  zerbini", "cap": "24015",
+			"province": "bergamo", "number": 12, "label": "Baita"}`)
+
+		rr := request(http.MethodPost, "/user/addresses", "/user/addresses", handler.handleCreateAddress, payload)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+		saved := store.addresses[len(store.addresses)-1]
+		if got := fmt.Sprint(saved.Country, "|", saved.City, "|", saved.Street, "|", saved.Province, "|", saved.Number); got != "Italia|San Giovanni Bianco|Piazza Zerbini|BG|12" {
+			t.Errorf("unexpected normalized address %s", got)
+		}
+	})
+}
+
+// mockAddressStore tiene la rubrica in memoria con le stesse regole sul predefinito dello Store
+type mockAddressStore struct {
+	types.AddressStore
+	addresses []types.Adress
+}
+
+func (m *mockAddressStore) summary() []string {
+	out := make([]string, 0)
+	for _, a := range m.addresses {
+		if a.UserID != 7 {
+			continue
+		}
+		s := fmt.Sprintf("%d:%s:%s", a.ID, a.Label, a.City)
+		if a.Default {
+			s += ":default"
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func (m *mockAddressStore) GetAddresses(userID int) ([]types.Adress, error) {
+	out := make([]types.Adress, 0)
+	for _, a := range m.addresses {
+		if a.UserID == userID && a.Default {
+			out = append([]types.Adress{a}, out...)
+		} else if a.UserID == userID {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockAddressStore) GetAddress(userID, adressID int) (*types.Adress, error) {
+	for _, a := range m.addresses {
+		if a.UserID == userID && a.ID == adressID {
+			return &a, nil
+		}
+	}
+	return nil, fmt.Errorf("address not found")
+}
+
+func (m *mockAddressStore) CreateAddress(adress *types.Adress) error {
+	existing, _ := m.GetAddresses(adress.UserID)
+	adress.Default = adress.Default || len(existing) == 0
+	if adress.Default {
+		m.clearDefault(adress.UserID)
+	}
+	adress.ID = len(m.addresses) + 1
+	m.addresses = append(m.addresses, *adress)
+	return nil
+}
+
+func (m *mockAddressStore) UpdateAddress(adress *types.Adress) error {
+	for i, a := range m.addresses {
+		if a.ID == adress.ID && a.UserID == adress.UserID {
+			updated := *adress
+			updated.Default = a.Default
+			m.addresses[i] = updated
+		}
+	}
+	if adress.Default {
+		return m.SetDefaultAddress(adress.UserID, adress.ID)
+	}
+	return nil
+}
+
+func (m *mockAddressStore) DeleteAddress(userID, adressID int) error {
+	deleted, err := m.GetAddress(userID, adressID)
+	if err != nil {
+		return err
+	}
+
+	kept := make([]types.Adress, 0)
+	for _, a := range m.addresses {
+		if a.ID != adressID {
+			kept = append(kept, a)
+		}
+	}
+	m.addresses = kept
+
+	if remaining, _ := m.GetAddresses(userID); deleted.Default && len(remaining) > 0 {
+		return m.SetDefaultAddress(userID, remaining[len(remaining)-1].ID)
+	}
+	return nil
+}
+
+func (m *mockAddressStore) SetDefaultAddress(userID, adressID int) error {
+	if _, err := m.GetAddress(userID, adressID); err != nil {
+		return err
+	}
+	m.clearDefault(userID)
+	for i, a := range m.addresses {
+		if a.ID == adressID {
+			m.addresses[i].Default = true
+		}
+	}
+	return nil
+}
+
+func (m *mockAddressStore) clearDefault(userID int) {
+	for i, a := range m.addresses {
+		if a.UserID == userID {
+			m.addresses[i].Default = false
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package address
+
+import (
+	"backend/seed-savers/types"
+	"database/sql"
+	"fmt"
+)
+
+// adressColumns elenca le colonne lette da scanAdress, nello stesso ordine
+const adressColumns = "adress_id, user_id, label, is_default IS NOT NULL, state, city, street, cap, province, number, apartment_number"
+
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore crea e restituisce un nuovo oggetto Store con il database passato come parametro
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// GetAddresses restituisce la rubrica dell'utente, a partire dall'indirizzo predefinito
+func (s *Store) GetAddresses(userID int) ([]types.Adress, error) {
+	rows, err := s.db.Query("SELECT "+adressColumns+" FROM adress WHERE user_id = ? ORDER BY is_default IS NULL, label, adress_id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := make([]types.Adress, 0)
+	for rows.Next() {
+		a, err := scanAdress(rows)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, *a)
+	}
+
+	return addresses, rows.Err()
+}
+
+// GetAddress restituisce un indirizzo della rubrica dell'utente
+func (s *Store) GetAddress(userID, adressID int) (*types.Adress, error) {
+	rows, err := s.db.Query("SELECT "+adressColumns+" FROM adress WHERE user_id = ? AND adress_id = ?", userID, adressID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("address not found")
+	}
+
+	return scanAdress(rows)
+}
+
+// CreateAddress aggiunge un indirizzo alla rubrica. Il primo indirizzo diventa quello predefinito,
+// come quello creato con Default
+func (s *Store) CreateAddress(adress *types.Adress) error {
+	// Inizia una transazione
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Assicura che il rollback venga eseguito in caso di errore
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM adress WHERE user_id = ? FOR UPDATE", adress.UserID).Scan(&count); err != nil {
+		return err
+	}
+
+	adress.Default = adress.Default || count == 0
+	if adress.Default {
+		if _, err := tx.Exec("UPDATE adress SET is_default = NULL WHERE user_id = ?", adress.UserID); err != nil {
+			return err
+		}
+	}
+
+	res, err := tx.Exec(`INSERT INTO adress (user_id, label, is_default, state, city, street, cap, province, number, apartment_number)
+		VALUES (?, ?, IF(?, TRUE, NULL), ?, ?, ?, ?, ?, ?, ?)`,
+		adress.UserID, adress.Label, adress.Default, adress.Country, adress.City, adress.Street, adress.Cap, adress.Province, adress.Number, adress.Apartment_number)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	adress.ID = int(id)
+
+	// Conferma la transazione
+	return tx.Commit()
+}
+
+// UpdateAddress modifica un indirizzo della rubrica; gli ordini già fatti conservano la loro copia
+func (s *Store) UpdateAddress(adress *types.Adress) error {
+	_, err := s.db.Exec(`UPDATE adress SET label = ?, state = ?, city = ?, street = ?, cap = ?, province = ?, number = ?, apartment_number = ?
+		WHERE adress_id = ? AND user_id = ?`,
+		adress.Label, adress.Country, adress.City, adress.Street, adress.Cap, adress.Province, adress.Number, adress.Apartment_number, adress.ID, adress.UserID)
+	if err != nil {
+		return err
+	}
+
+	if adress.Default {
+		return s.SetDefaultAddress(adress.UserID, adress.ID)
+	}
+	return nil
+}
+
+// DeleteAddress toglie un indirizzo dalla rubrica. Se era quello predefinito, lo diventa il più recente
+// tra quelli rimasti
+func (s *Store) DeleteAddress(userID, adressID int) error {
+	// Inizia una transazione
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Assicura che il rollback venga eseguito in caso di errore
+
+	var isDefault bool
+	err = tx.QueryRow("SELECT is_default IS NOT NULL FROM adress WHERE adress_id = ? AND user_id = ? FOR UPDATE", adressID, userID).Scan(&isDefault)
+	if err != nil {
+		return fmt.Errorf("address not found")
+	}
+
+	if _, err := tx.Exec("DELETE FROM adress WHERE adress_id = ?", adressID); err != nil {
+		return err
+	}
+
+	if isDefault {
+		_, err = tx.Exec("UPDATE adress SET is_default = TRUE WHERE user_id = ? ORDER BY adress_id DESC LIMIT 1", userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Conferma la transazione
+	return tx.Commit()
+}
+
+// SetDefaultAddress rende predefinito un indirizzo della rubrica
+func (s *Store) SetDefaultAddress(userID, adressID int) error {
+	// Inizia una transazione
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Assicura che il rollback venga eseguito in caso di errore
+
+	var exists bool
+	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM adress WHERE adress_id = ? AND user_id = ?)", adressID, userID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("address not found")
+	}
+
+	if _, err := tx.Exec("UPDATE adress SET is_default = NULL WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE adress SET is_default = TRUE WHERE adress_id = ?", adressID); err != nil {
+		return err
+	}
+
+	// Conferma la transazione
+	return tx.Commit()
+}
+
+func scanAdress(rows *sql.Rows) (*types.Adress, error) {
+	a := new(types.Adress)
+	var apartment sql.NullString
+	err := rows.Scan(&a.ID, &a.UserID, &a.Label, &a.Default, &a.Country, &a.City, &a.Street, &a.Cap, &a.Province, &a.Number, &apartment)
+	if err != nil {
+		return nil, err
+	}
+	a.Apartment_number = apartment.String
+
+	return a, nil
+}
@@ -0,0 +1,201 @@
+package admin
+
+import (
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+type Handler struct {
+	store        types.AdminStore
+	usersStore   types.UserStore
+	sessionStore *auth.AuthStore
+}
+
+func NewHandler(s types.AdminStore, us types.UserStore, sessionStore *auth.AuthStore) *Handler {
+	return &Handler{s, us, sessionStore}
+}
+
+// RegisterRouter registra le route /admin riservate agli amministratori; ogni chiamata finisce
+// nel registro delle attività tramite auth.WithRole
+func (h *Handler) RegisterRouter(router *mux.Router) {
+	router.HandleFunc("/admin/users", h.adminOnly(h.handleSearchUsers)).Methods("GET")
+	router.HandleFunc("/admin/users/{userID:[0-9]+}/suspend", h.adminOnly(h.handleSuspend)).Methods("POST")
+	router.HandleFunc("/admin/users/{userID:[0-9]+}/unsuspend", h.adminOnly(h.handleUnsuspend)).Methods("POST")
+	router.HandleFunc("/admin/users/{userID:[0-9]+}/credits", h.adminOnly(h.handleAdjustCredits)).Methods("POST")
+	router.HandleFunc("/admin/users/{userID:[0-9]+}/role", h.adminOnly(h.handleSetRole)).Methods("PUT")
+	router.HandleFunc("/admin/orders/{orderID:[0-9]+}", h.adminOnly(h.handleGetOrder)).Methods("GET")
+	router.HandleFunc("/admin/stats", h.adminOnly(h.handleStats)).Methods("GET")
+	router.HandleFunc("/admin/audit", h.adminOnly(h.handleAuditLog)).Methods("GET")
+}
+
+func (h *Handler) adminOnly(handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return auth.WithRole(handlerFunc, h.usersStore, h.sessionStore, types.RoleAdmin)
+}
+
+// handleSearchUsers elenca gli utenti; q cerca in nome ed email, role e status filtrano
+func (h *Handler) handleSearchUsers(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := utils.ParsePage(r, DefaultPageSize, MaxPageSize)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := types.AdminUserFilter{Query: query.Get("q"), Role: query.Get("role"), Status: query.Get("status")}
+
+	users, total, err := h.store.SearchUsers(filter, limit, (page-1)*limit)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.AdminUserPage{Results: users, Total: total, Page: page, Limit: limit})
+}
+
+// handleSuspend sospende un account: l'utente esce da tutte le sessioni e non può più entrare.
+// Il motivo resta nel registro delle attività
+func (h *Handler) handleSuspend(w http.ResponseWriter, r *http.Request) {
+	if _, err := utils.DecodePayload[types.SuspendPayload](w, r); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	h.setStatus(w, r, types.UserBlocked)
+}
+
+func (h *Handler) handleUnsuspend(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, types.UserActive)
+}
+
+func (h *Handler) setStatus(w http.ResponseWriter, r *http.Request, status string) {
+	u, ok := h.targetUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.SetUserStatus(u.ID, status); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"status": status})
+}
+
+// handleAdjustCredits aggiunge o toglie crediti, ad esempio per rimborsare uno scambio andato male
+func (h *Handler) handleAdjustCredits(w http.ResponseWriter, r *http.Request) {
+	payload, err := utils.DecodePayload[types.CreditsPayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	u, ok := h.targetUser(w, r)
+	if !ok {
+		return
+	}
+
+	credits, err := h.store.AdjustCredits(u.ID, payload.Delta)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]int{"credits": credits})
+}
+
+func (h *Handler) handleSetRole(w http.ResponseWriter, r *http.Request) {
+	payload, err := utils.DecodePayload[types.RolePayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	u, ok := h.targetUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.SetUserRole(u.ID, payload.Role); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"role": payload.Role})
+}
+
+func (h *Handler) handleGetOrder(w http.ResponseWriter, r *http.Request) {
+	orderID, _ := strconv.Atoi(mux.Vars(r)["orderID"])
+
+	order, err := h.store.GetOrder(orderID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, order)
+}
+
+func (h *Handler) handleStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.store.GetStats()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, stats)
+}
+
+func (h *Handler) handleAuditLog(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := utils.ParsePage(r, DefaultPageSize, MaxPageSize)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	entries, total, err := h.store.GetAuditLog(limit, (page-1)*limit)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.AuditPage{Results: entries, Total: total, Page: page, Limit: limit})
+}
+
+// targetUser carica l'utente dell'URL. Un amministratore non può agire sul proprio account, così
+// non può sospendersi o togliersi il ruolo per errore, e gli account cancellati non si toccano
+func (h *Handler) targetUser(w http.ResponseWriter, r *http.Request) (*types.User, bool) {
+	userID, _ := strconv.Atoi(mux.Vars(r)["userID"])
+
+	adminID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return nil, false
+	}
+	if userID == adminID {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you cannot change your own account from the admin API"))
+		return nil, false
+	}
+
+	u, err := h.usersStore.GetUserByID(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return nil, false
+	}
+	if u.Status == types.UserDeleted {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("the account has been deleted"))
+		return nil, false
+	}
+
+	return u, true
+}
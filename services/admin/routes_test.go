@@ -0,0 +1,138 @@
+package admin
+
+import (
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/sessions"
+)
+
+func TestSuspend(t *testing.T) {
+	users := &mockUserStore{users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleAdmin, Status: types.UserActive},
+		7: {ID: 7, Role: types.RoleUser, Status: types.UserActive},
+		8: {ID: 8, Status: types.UserDeleted},
+	}}
+	store := &mockAdminStore{statuses: map[int]string{}}
+	handler := NewHandler(store, users, &auth.AuthStore{Store: sessions.NewCookieStore([]byte{5})})
+
+	request := func(userID int, action string, h http.HandlerFunc, payload any) *httptest.ResponseRecorder {
+		marshalled, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/users/%d/%s", userID, action), bytes.NewBuffer(marshalled))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, 1))
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/admin/users/{userID:[0-9]+}/"+action, h)
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	reason := types.SuspendPayload{Reason: "spam negli ordini"}
+
+	t.Run("should require a reason", func(t *testing.T) {
+		if rr := request(7, "suspend", handler.handleSuspend, types.SuspendPayload{}); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should not let admins suspend themselves", func(t *testing.T) {
+		if rr := request(1, "suspend", handler.handleSuspend, reason); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should leave deleted accounts alone", func(t *testing.T) {
+		if rr := request(8, "suspend", handler.handleSuspend, reason); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rr.Code)
+		}
+		if rr := request(9, "suspend", handler.handleSuspend, reason); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should suspend and unsuspend", func(t *testing.T) {
+		if rr := request(7, "suspend", handler.handleSuspend, reason); rr.Code != http.StatusOK || store.statuses[7] != types.UserBlocked {
+			t.Errorf("expected user 7 to be suspended, got %d %v", rr.Code, store.statuses)
+		}
+		if rr := request(7, "unsuspend", handler.handleUnsuspend, nil); rr.Code != http.StatusOK || store.statuses[7] != types.UserActive {
+			t.Errorf("expected user 7 to be active again, got %d %v", rr.Code, store.statuses)
+		}
+	})
+
+	t.Run("should reject a balance out of range", func(t *testing.T) {
+		store.credits = 3
+		payload := types.CreditsPayload{Delta: -5, Reason: "rimborso annullato"}
+		if rr := request(7, "credits", handler.handleAdjustCredits, payload); rr.Code != http.StatusBadRequest || store.credits != 3 {
+			t.Errorf("expected the adjustment to be refused, got %d with %d credits", rr.Code, store.credits)
+		}
+
+		payload.Delta = 2
+		if rr := request(7, "credits", handler.handleAdjustCredits, payload); rr.Code != http.StatusOK || store.credits != 5 {
+			t.Errorf("expected a balance of 5 credits, got %d with %d credits", rr.Code, store.credits)
+		}
+	})
+}
+
+func TestSearchUsers(t *testing.T) {
+	store := &mockAdminStore{}
+	handler := NewHandler(store, &mockUserStore{}, &auth.AuthStore{Store: sessions.NewCookieStore([]byte{5})})
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/users?q=anna&status=blocked&page=2&limit=20", nil)
+	rr := httptest.NewRecorder()
+	handler.handleSearchUsers(rr, req)
+
+	var page types.AdminUserPage
+	if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("expected a page of users, got %d %s", rr.Code, rr.Body)
+	}
+	if fmt.Sprint(store.filter, store.offset, page.Page, page.Limit) != "{anna  blocked} 20 2 20" {
+		t.Errorf("unexpected search %v offset %d page %d limit %d", store.filter, store.offset, page.Page, page.Limit)
+	}
+}
+
+type mockAdminStore struct {
+	types.AdminStore
+	statuses map[int]string
+	credits  int
+	filter   types.AdminUserFilter
+	offset   int
+}
+
+func (m *mockAdminStore) SearchUsers(filter types.AdminUserFilter, limit, offset int) ([]types.AdminUser, int, error) {
+	m.filter, m.offset = filter, offset
+	return []types.AdminUser{{ID: 7, Name: "Anna", Status: types.UserBlocked}}, 21, nil
+}
+
+func (m *mockAdminStore) SetUserStatus(userID int, status string) error {
+	m.statuses[userID] = status
+	return nil
+}
+
+func (m *mockAdminStore) AdjustCredits(userID, delta int) (int, error) {
+	if m.credits+delta < 0 || m.credits+delta > 127 {
+		return 0, fmt.Errorf("the balance would be %d", m.credits+delta)
+	}
+	m.credits += delta
+	return m.credits, nil
+}
+
+type mockUserStore struct {
+	types.UserStore
+	users map[int]*types.User
+}
+
+func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
+	u, ok := m.users[id]
+	if !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+	return u, nil
+}
@@ -0,0 +1,240 @@
+package admin
+
+import (
+	"backend/seed-savers/types"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore crea e restituisce un nuovo oggetto Store con il database passato come parametro
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// LogAction scrive un'azione nel registro delle attività degli amministratori
+func (s *Store) LogAction(entry *types.AuditEntry) error {
+	var details any
+	if len(entry.Details) > 0 {
+		details = string(entry.Details)
+	}
+
+	_, err := s.db.Exec("INSERT INTO admin_audit (actor_user_id, actor_role, action, path, details) VALUES (NULLIF(?, 0), ?, ?, ?, ?)",
+		entry.ActorID, entry.ActorRole, entry.Action, entry.Path, details)
+	return err
+}
+
+// GetAuditLog restituisce una pagina del registro, dalle azioni più recenti, e il numero totale di azioni
+func (s *Store) GetAuditLog(limit, offset int) ([]types.AuditEntry, int, error) {
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM admin_audit").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.Query(`SELECT a.audit_id, COALESCE(a.actor_user_id, 0), COALESCE(u.name, ''), a.actor_role, a.action, a.path, a.details, a.created_at
+		FROM admin_audit a
+		LEFT JOIN users u ON u.user_id = a.actor_user_id
+		ORDER BY a.created_at DESC, a.audit_id DESC
+		LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := make([]types.AuditEntry, 0)
+	for rows.Next() {
+		var e types.AuditEntry
+		var details sql.NullString
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.ActorName, &e.ActorRole, &e.Action, &e.Path, &details, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		if details.Valid {
+			e.Details = []byte(details.String)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, total, rows.Err()
+}
+
+// SearchUsers cerca gli utenti per nome o email, con ruolo e stato facoltativi
+func (s *Store) SearchUsers(filter types.AdminUserFilter, limit, offset int) ([]types.AdminUser, int, error) {
+	conditions := []string{"1 = 1"}
+	args := []any{}
+	if filter.Query != "" {
+		like := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(filter.Query) + "%"
+		conditions = append(conditions, "(name LIKE ? OR email LIKE ?)")
+		args = append(args, like, like)
+	}
+	if filter.Role != "" {
+		conditions = append(conditions, "role = ?")
+		args = append(args, filter.Role)
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	where := " WHERE " + strings.Join(conditions, " AND ")
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM users"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.Query(`SELECT user_id, COALESCE(name, ''), email, role, status, COALESCE(credits, 0), email_verified_at IS NOT NULL,
+			created_at, deletion_requested_at
+		FROM users`+where+` ORDER BY user_id DESC LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := make([]types.AdminUser, 0)
+	for rows.Next() {
+		var u types.AdminUser
+		err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Role, &u.Status, &u.Credits, &u.EmailVerified, &u.CreatedAt, &u.DeletionRequestedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, u)
+	}
+
+	return users, total, rows.Err()
+}
+
+// GetOrder restituisce un ordine qualsiasi con i nomi di mittente e destinatario e l'indirizzo di spedizione
+func (s *Store) GetOrder(orderID int) (*types.Order, error) {
+	o := new(types.Order)
+	var label, country, city, street, postalCode, province, number, apartment sql.NullString
+	var img sql.NullString
+
+	err := s.db.QueryRow(`SELECT o.order_id, o.state, o.order_date, o.sender_user_id, sender.name, o.reciver_user_id, reciver.name,
+			o.shipping_label, o.shipping_state, o.shipping_city, o.shipping_street, o.shipping_cap, o.shipping_province,
+			o.shipping_number, o.shipping_apartment_number, s.seed_id, s.variety_name, s.img, od.quantity
+		FROM orders o
+		INNER JOIN users sender ON sender.user_id = o.sender_user_id
+		INNER JOIN users reciver ON reciver.user_id = o.reciver_user_id
+		INNER JOIN order_detail od ON od.order_id = o.order_id
+		INNER JOIN seed s ON s.seed_id = od.seed_id
+		WHERE o.order_id = ?`, orderID).Scan(
+		&o.ID, &o.State, &o.OrderDate, &o.SenderID, &o.SenderName, &o.ReciverID, &o.ReciverName,
+		&label, &country, &city, &street, &postalCode, &province, &number, &apartment,
+		&o.Seed.ID, &o.Seed.Variety_name, &img, &o.Seed.Quantity,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("order not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	o.Seed.Image = img.String
+	o.ReciverAdress = types.Adress{
+		Label:            label.String,
+		Country:          country.String,
+		City:             city.String,
+		Street:           street.String,
+		Cap:              postalCode.String,
+		Province:         province.String,
+		Number:           number.String,
+		Apartment_number: apartment.String,
+	}
+
+	return o, nil
+}
+
+// SetUserStatus sospende o riattiva un account. La sospensione chiude anche tutte le sessioni aperte;
+// gli account cancellati restano tali
+func (s *Store) SetUserStatus(userID int, status string) error {
+	_, err := s.db.Exec(`UPDATE users SET token_version = token_version + IF(? = 'blocked', 1, 0), status = ?
+		WHERE user_id = ? AND status <> 'deleted'`, status, status, userID)
+	return err
+}
+
+// AdjustCredits aggiunge (o toglie, se delta è negativo) crediti a un utente e restituisce il nuovo saldo,
+// che deve restare tra 0 e 127
+func (s *Store) AdjustCredits(userID, delta int) (int, error) {
+	// Inizia una transazione
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() // Assicura che il rollback venga eseguito in caso di errore
+
+	var credits int
+	err = tx.QueryRow("SELECT COALESCE(credits, 0) FROM users WHERE user_id = ? AND status <> 'deleted' FOR UPDATE", userID).Scan(&credits)
+	if err != nil {
+		return 0, fmt.Errorf("user not found")
+	}
+
+	credits += delta
+	if credits < 0 || credits > 127 {
+		return 0, fmt.Errorf("the balance would be %d, it must stay between 0 and 127", credits)
+	}
+
+	if _, err := tx.Exec("UPDATE users SET credits = ? WHERE user_id = ?", credits, userID); err != nil {
+		return 0, err
+	}
+
+	// Conferma la transazione
+	return credits, tx.Commit()
+}
+
+// SetUserRole cambia il ruolo di un utente
+func (s *Store) SetUserRole(userID int, role string) error {
+	_, err := s.db.Exec("UPDATE users SET role = ? WHERE user_id = ? AND status <> 'deleted'", role, userID)
+	return err
+}
+
+// GetStats conta utenti, semi, ordini, resoconti segnalati e segnalazioni aperte per la pagina di riepilogo
+func (s *Store) GetStats() (*types.SystemStats, error) {
+	stats := &types.SystemStats{}
+	var err error
+
+	if stats.Users, err = s.countBy("SELECT status, COUNT(*) FROM users GROUP BY status"); err != nil {
+		return nil, err
+	}
+	if stats.Roles, err = s.countBy("SELECT role, COUNT(*) FROM users WHERE status <> 'deleted' GROUP BY role"); err != nil {
+		return nil, err
+	}
+	if stats.Orders, err = s.countBy("SELECT state, COUNT(*) FROM orders GROUP BY state"); err != nil {
+		return nil, err
+	}
+
+	err = s.db.QueryRow(`SELECT
+			(SELECT COUNT(*) FROM orders WHERE order_date >= NOW() - INTERVAL 30 DAY),
+			(SELECT COUNT(*) FROM seed),
+			(SELECT COUNT(*) FROM users WHERE deletion_requested_at IS NOT NULL),
+			(SELECT COUNT(DISTINCT report_id) FROM grow_report_flag),
+			(SELECT COUNT(*) FROM abuse_report WHERE status = 'open')`).Scan(
+		&stats.OrdersLast30Days, &stats.Seeds, &stats.PendingDeletions, &stats.FlaggedReports, &stats.OpenAbuseReports)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (s *Store) countBy(query string) (map[string]int, error) {
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var key string
+		var n int
+		if err := rows.Scan(&key, &n); err != nil {
+			return nil, err
+		}
+		counts[key] = n
+	}
+
+	return counts, rows.Err()
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"backend/seed-savers/types"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+
+	"github.com/gorilla/mux"
+)
+
+// AuditLog receives every successful request behind WithRole, reads included. It is set once
+// at startup; when nil nothing is recorded
+var AuditLog types.AuditStore
+
+// maxAuditDetails is the largest request body kept in the audit log
+const maxAuditDetails = 4096
+
+// statusRecorder remembers the status code written by the handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// varPattern strips the regular expressions from route variables: {id:[0-9]+} becomes {id}
+var varPattern = regexp.MustCompile(`\{([^:}]+):[^}]*\}`)
+
+// withAudit runs the handler and, if it succeeded, writes the action to AuditLog. Reads are recorded
+// too, since they can show personal data: their details are the query parameters
+func withAudit(handlerFunc http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
+	if AuditLog == nil {
+		handlerFunc(w, r)
+		return
+	}
+
+	var details json.RawMessage
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		if query := r.URL.Query(); len(query) > 0 {
+			details, _ = json.Marshal(query)
+		}
+	} else if r.Body != nil {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxAuditDetails+1))
+		if err == nil && len(body) <= maxAuditDetails && json.Valid(body) {
+			details = body
+		}
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+	}
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	handlerFunc(rec, r)
+	if rec.status >= http.StatusBadRequest {
+		return
+	}
+
+	action := r.Method + " " + r.URL.Path
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			action = r.Method + " " + varPattern.ReplaceAllString(template, "{$1}")
+		}
+	}
+
+	userID, _ := GetUserIDFromContext(r.Context())
+	entry := &types.AuditEntry{
+		ActorID:   userID,
+		ActorRole: GetRoleFromContext(r.Context()),
+		Action:    action,
+		Path:      r.URL.Path,
+		Details:   details,
+	}
+	if err := AuditLog.LogAction(entry); err != nil {
+		log.Printf("failed to write the audit log for %s by user %d: %v", entry.Path, userID, err)
+	}
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"backend/seed-savers/types"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/sessions"
+)
+
+func TestWithRoleAudit(t *testing.T) {
+	audit := &mockAuditStore{}
+	AuditLog = audit
+	defer func() { AuditLog = nil }()
+
+	users := &versionedUserStore{role: types.RoleAdmin}
+	session := &AuthStore{Store: sessions.NewCookieStore([]byte{5})}
+
+	var received string
+	router := mux.NewRouter()
+	router.HandleFunc("/admin/users/{userID:[0-9]+}/suspend", WithRole(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		if strings.Contains(received, "fail") {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}, users, session, types.RoleAdmin)).Methods("POST", "GET")
+
+	call := func(method, body string) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, sessionRequest(t, session, method, "/admin/users/12/suspend", strings.NewReader(body)))
+	}
+	read := func(query string) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, sessionRequest(t, session, http.MethodGet, "/admin/users/12/suspend?"+query, nil))
+	}
+
+	call(http.MethodPost, `{"reason": "spam"}`)
+	if received != `{"reason": "spam"}` {
+		t.Errorf("the handler should still read the body, got %q", received)
+	}
+	if len(audit.entries) != 1 {
+		t.Fatalf("expected one audit entry, got %d", len(audit.entries))
+	}
+	e := audit.entries[0]
+	got := fmt.Sprint(e.ActorID, " ", e.ActorRole, " ", e.Action, " ", e.Path, " ", string(e.Details))
+	if got != `7 admin POST /admin/users/{userID}/suspend /admin/users/12/suspend {"reason": "spam"}` {
+		t.Errorf("unexpected audit entry %s", got)
+	}
+
+	call(http.MethodPost, `{"reason": "fail"}`)
+	if len(audit.entries) != 1 {
+		t.Errorf("failed requests should not be recorded, got %d entries", len(audit.entries))
+	}
+
+	read("page=2")
+	if len(audit.entries) != 2 {
+		t.Fatalf("reads should be recorded too, got %d entries", len(audit.entries))
+	}
+	e = audit.entries[1]
+	got = fmt.Sprint(e.Action, " ", e.Path, " ", string(e.Details))
+	if got != `GET /admin/users/{userID}/suspend /admin/users/12/suspend {"page":["2"]}` {
+		t.Errorf("unexpected audit entry %s", got)
+	}
+
+	read("")
+	if len(audit.entries) != 3 || audit.entries[2].Details != nil {
+		t.Fatalf("expected a read without details, got %+v", audit.entries)
+	}
+
+	users.role = types.RoleUser
+	call(http.MethodPost, `{"reason": "spam"}`)
+	if len(audit.entries) != 3 {
+		t.Errorf("refused requests should not be recorded, got %d entries", len(audit.entries))
+	}
+}
+
+type mockAuditStore struct {
+	entries []types.AuditEntry
+}
+
+func (m *mockAuditStore) LogAction(entry *types.AuditEntry) error {
+	if len(entry.Details) > 0 && !json.Valid(entry.Details) {
+		return fmt.Errorf("invalid details")
+	}
+	m.entries = append(m.entries, *entry)
+	return nil
+}
@@ -17,62 +17,104 @@ import (
 type contextKey string
 
 const UserKey contextKey = "userID"
+const VerifiedKey contextKey = "emailVerified"
 
 func WithJWTAuth(handlerFunc http.HandlerFunc, store types.UserStore, sessionStore *AuthStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		tokenString, err := sessionStore.GetSessionUserToken(r)
-		if err != nil{
-			log.Printf("failed to validate token: %v", err)
-			permissionDenied(w)
-			return
-		}
-		
-		token, err := ValidateJWT(tokenString)
+		u, err := authenticate(r, store, sessionStore)
 		if err != nil {
-			log.Printf("failed to validate token: %v", err)
+			log.Println(err)
 			permissionDenied(w)
 			return
 		}
 
-		if !token.Valid {
-			log.Println("invalid token")
-			permissionDenied(w)
-			return
+		// Call the function if the token is valid
+		handlerFunc(w, withUser(r, u))
+	}
+}
+
+// WithOptionalJWTAuth adds the user to the context when the request carries a valid session,
+// otherwise it calls the handler anyway as an anonymous request
+func WithOptionalJWTAuth(handlerFunc http.HandlerFunc, store types.UserStore, sessionStore *AuthStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if u, err := authenticate(r, store, sessionStore); err == nil {
+			r = withUser(r, u)
 		}
 
-		claims := token.Claims.(jwt.MapClaims)
-		str := claims["userID"].(string)
+		handlerFunc(w, r)
+	}
+}
+
+func authenticate(r *http.Request, store types.UserStore, sessionStore *AuthStore) (*types.User, error) {
+	tokenString, err := sessionStore.GetSessionUserToken(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate token: %v", err)
+	}
 
-		userID, err := strconv.Atoi(str)
-		if err != nil {
-			log.Printf("failed to convert userID to int: %v", err)
-			permissionDenied(w)
-			return
-		}
+	token, err := ValidateJWT(tokenString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate token: %v", err)
+	}
 
-		u, err := store.GetUserByID(userID)
-		if err != nil {
-			log.Printf("failed to get user by id: %v", err)
-			permissionDenied(w)
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	str, _ := claims["userID"].(string)
+
+	userID, err := strconv.Atoi(str)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert userID to int: %v", err)
+	}
+
+	u, err := store.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by id: %v", err)
+	}
+
+	// tokens issued before the last password change are no longer valid
+	version, _ := claims["tokenVersion"].(float64)
+	if int(version) != u.TokenVersion {
+		return nil, fmt.Errorf("session revoked by a password change")
+	}
+
+	if u.Status == types.UserBlocked || u.Status == types.UserDeleted {
+		return nil, fmt.Errorf("user %d is %s", u.ID, u.Status)
+	}
+
+	return u, nil
+}
+
+// Add the user to the context
+func withUser(r *http.Request, u *types.User) *http.Request {
+	ctx := r.Context()
+	ctx = context.WithValue(ctx, UserKey, u.ID)
+	ctx = context.WithValue(ctx, RoleKey, u.Role)
+	ctx = context.WithValue(ctx, VerifiedKey, u.EmailVerifiedAt != nil)
+	return r.WithContext(ctx)
+}
+
+// WithVerifiedEmail works like WithJWTAuth but also requires the user to have verified the email address
+func WithVerifiedEmail(handlerFunc http.HandlerFunc, store types.UserStore, sessionStore *AuthStore) http.HandlerFunc {
+	return WithJWTAuth(func(w http.ResponseWriter, r *http.Request) {
+		if !IsEmailVerified(r.Context()) {
+			utils.WriteError(w, http.StatusForbidden, fmt.Errorf("verify your email address to continue"))
 			return
 		}
 
-		// Add the user to the context
-		ctx := r.Context()
-		ctx = context.WithValue(ctx, UserKey, u.ID)
-		r = r.WithContext(ctx)
-		
-		// Call the function if the token is valid
 		handlerFunc(w, r)
-	}
+	}, store, sessionStore)
 }
 
-func CreateJWT(secret []byte, userID uint64) (string, error) {
+func CreateJWT(secret []byte, userID uint64, tokenVersion int) (string, error) {
 	expiration := time.Second * time.Duration(config.Envs.JWTExpirationInSeconds)
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userID":    strconv.Itoa(int(userID)),
-		"expiresAt": time.Now().Add(expiration).Unix(),
+		"userID":       strconv.Itoa(int(userID)),
+		"tokenVersion": tokenVersion,
+		"issuedAt":     time.Now().Unix(),
+		"expiresAt":    time.Now().Add(expiration).Unix(),
 	})
 
 	tokenString, err := token.SignedString(secret)
@@ -93,6 +135,23 @@ func ValidateJWT(tokenString string) (*jwt.Token, error) {
 	})
 }
 
+// SessionIssuedAt returns when the session token of the request was issued, so that sensitive
+// actions can ask for a recent login. Tokens without the claim count as issued long ago
+func SessionIssuedAt(r *http.Request, sessionStore *AuthStore) (time.Time, error) {
+	tokenString, err := sessionStore.GetSessionUserToken(r)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	token, err := ValidateJWT(tokenString)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	issuedAt, _ := token.Claims.(jwt.MapClaims)["issuedAt"].(float64)
+	return time.Unix(int64(issuedAt), 0), nil
+}
+
 func GetUserIDFromContext(ctx context.Context) (int, error) {
 	userID, ok := ctx.Value(UserKey).(int)
 	if !ok {
@@ -102,6 +161,11 @@ func GetUserIDFromContext(ctx context.Context) (int, error) {
 	return userID, nil
 }
 
+// IsEmailVerified reports whether the authenticated user has verified the email address
+func IsEmailVerified(ctx context.Context) bool {
+	verified, _ := ctx.Value(VerifiedKey).(bool)
+	return verified
+}
 
 func permissionDenied(w http.ResponseWriter) {
 	utils.WriteError(w, http.StatusForbidden, fmt.Errorf("permission denied"))
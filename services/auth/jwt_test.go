@@ -1,10 +1,19 @@
 package auth
 
-import "testing"
+import (
+	"backend/seed-savers/config"
+	"backend/seed-savers/types"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/sessions"
+)
 
 func TestJwtCreation(t *testing.T){
 	secret := []byte("super secret")
-	token, err := CreateJWT(secret, 1)
+	token, err := CreateJWT(secret, 1, 0)
 
 	if err != nil{
 		t.Errorf("error on creation of jwt %v", err)
@@ -13,4 +22,75 @@ func TestJwtCreation(t *testing.T){
 	if token == ""{
 		t.Errorf("token must not be empty")
 	}
-}
\ No newline at end of file
+}
+
+func TestSessionRevokedByPasswordChange(t *testing.T) {
+	users := &versionedUserStore{}
+	session := &AuthStore{Store: sessions.NewCookieStore([]byte{5})}
+
+	token, _ := CreateJWT([]byte(config.Envs.JWTSecret), 7, 0)
+	login := httptest.NewRecorder()
+	session.StoreUserSession(login, httptest.NewRequest(http.MethodPost, "/login", nil), token)
+
+	call := func() (int, bool) {
+		req := httptest.NewRequest(http.MethodGet, "/user/me", nil)
+		for _, c := range login.Result().Cookies() {
+			req.AddCookie(c)
+		}
+
+		called := false
+		rr := httptest.NewRecorder()
+		WithJWTAuth(func(w http.ResponseWriter, r *http.Request) { called = true }, users, session)(rr, req)
+		return rr.Code, called
+	}
+
+	if _, called := call(); !called {
+		t.Fatal("expected the session to be valid before the password change")
+	}
+
+	users.version = 1
+	if code, called := call(); called || code != http.StatusForbidden {
+		t.Errorf("expected the old session to be refused, got %d", code)
+	}
+}
+
+func TestSuspendedUserRejected(t *testing.T) {
+	users := &versionedUserStore{status: types.UserBlocked}
+	session := &AuthStore{Store: sessions.NewCookieStore([]byte{5})}
+
+	req := sessionRequest(t, session, http.MethodGet, "/user/me", nil)
+	called := false
+	rr := httptest.NewRecorder()
+	WithJWTAuth(func(w http.ResponseWriter, r *http.Request) { called = true }, users, session)(rr, req)
+
+	if called || rr.Code != http.StatusForbidden {
+		t.Errorf("expected a suspended user to be refused, got %d", rr.Code)
+	}
+}
+
+// sessionRequest builds a request carrying a valid session of user 7
+func sessionRequest(t *testing.T, session *AuthStore, method, path string, body io.Reader) *http.Request {
+	token, err := CreateJWT([]byte(config.Envs.JWTSecret), 7, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	login := httptest.NewRecorder()
+	session.StoreUserSession(login, httptest.NewRequest(http.MethodPost, "/login", nil), token)
+
+	req := httptest.NewRequest(method, path, body)
+	for _, c := range login.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	return req
+}
+
+type versionedUserStore struct {
+	types.UserStore
+	version int
+	status  string
+	role    string
+}
+
+func (m *versionedUserStore) GetUserByID(id int) (*types.User, error) {
+	return &types.User{ID: id, TokenVersion: m.version, Status: m.status, Role: m.role}, nil
+}
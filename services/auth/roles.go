@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"backend/seed-savers/types"
+	"context"
+	"log"
+	"net/http"
+)
+
+// RoleKey holds the role of the authenticated user. Roles are assigned from the admin API
+const RoleKey contextKey = "role"
+
+// WithRole works like WithJWTAuth but also requires the user to have one of the given roles.
+// The changes made through it are written to AuditLog
+func WithRole(handlerFunc http.HandlerFunc, store types.UserStore, sessionStore *AuthStore, roles ...string) http.HandlerFunc {
+	return WithJWTAuth(func(w http.ResponseWriter, r *http.Request) {
+		if !HasRole(r.Context(), roles...) {
+			log.Printf("role %q is not allowed", GetRoleFromContext(r.Context()))
+			permissionDenied(w)
+			return
+		}
+
+		withAudit(handlerFunc, w, r)
+	}, store, sessionStore)
+}
+
+func GetRoleFromContext(ctx context.Context) string {
+	role, ok := ctx.Value(RoleKey).(string)
+	if !ok || role == "" {
+		return types.RoleUser
+	}
+
+	return role
+}
+
+// HasRole reports whether the authenticated user has one of the given roles
+func HasRole(ctx context.Context, roles ...string) bool {
+	role := GetRoleFromContext(ctx)
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
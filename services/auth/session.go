@@ -22,11 +22,6 @@ type AuthStore struct {
 	Store sessions.Store
 }
 
-// CreateAdress implements types.UserStore.
-func (authStore *AuthStore) CreateAdress(adress *types.Adress) error {
-	panic("unimplemented")
-}
-
 // CreateUser implements types.UserStore.
 func (authStore *AuthStore) CreateUser(user *types.User) error {
 	panic("unimplemented")
@@ -57,11 +52,6 @@ func (authStore *AuthStore) GetUserByID(ID int) (*types.User, error) {
 	panic("unimplemented")
 }
 
-// ModifyAdress implements types.UserStore.
-func (authStore *AuthStore) ModifyAdress(adress *types.Adress) error {
-	panic("unimplemented")
-}
-
 // ModifySeedQuantity implements types.UserStore.
 func (authStore *AuthStore) ModifySeedQuantity(seed *types.Seed, userID int) error {
 	panic("unimplemented")
@@ -95,13 +85,8 @@ func (authStore *AuthStore) StoreUserSession(w http.ResponseWriter, r *http.Requ
 
 	session.Values["user"] = userID
 
-	err := session.Save(r, w)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return err
-	}
-
-	return nil
+	// the caller writes the error: the response may still need a body or a redirect
+	return session.Save(r, w)
 }
 
 func (authStore *AuthStore) GetSessionUserToken(r *http.Request) (string, error) {
@@ -0,0 +1,91 @@
+package calendar
+
+import (
+	"backend/seed-savers/geo"
+	"backend/seed-savers/types"
+)
+
+// DefaultZone è la zona climatica di riferimento dei periodi salvati nel catalogo
+const DefaultZone = "D"
+
+// zoneOffsets sposta i periodi di semina in mesi rispetto alla zona D: le zone più calde
+// (sud e isole) anticipano, quelle più fredde (Alpi e Appennino) posticipano
+var zoneOffsets = map[string]int{
+	"A": -2,
+	"B": -1,
+	"C": -1,
+	"D": 0,
+	"E": 1,
+	"F": 2,
+}
+
+// ZoneFor restituisce la zona climatica dell'utente: quella scelta da lui se presente,
+// altrimenti quella della provincia del suo indirizzo
+func ZoneFor(province, override string) string {
+	if _, ok := zoneOffsets[override]; ok {
+		return override
+	}
+	if p, ok := geo.LookupProvince(province); ok {
+		return p.ClimateZone
+	}
+	return DefaultZone
+}
+
+// ForZone adatta i periodi di semina di riferimento alla zona climatica indicata
+func ForZone(w types.SowingWindows, zone string) types.SowingWindows {
+	offset := zoneOffsets[zone]
+	return types.SowingWindows{
+		SowIndoor:  shift(w.SowIndoor, offset),
+		SowOutdoor: shift(w.SowOutdoor, offset),
+		Transplant: shift(w.Transplant, offset),
+		Harvest:    shift(w.Harvest, offset),
+	}
+}
+
+// Build costruisce il calendario dei dodici mesi per i semi dell'inventario
+func Build(seeds []types.Seed, zone string) []types.CalendarMonth {
+	months := make([]types.CalendarMonth, 12)
+	for i := range months {
+		months[i] = types.CalendarMonth{
+			Month:      i + 1,
+			SowIndoor:  []types.CalendarSeed{},
+			SowOutdoor: []types.CalendarSeed{},
+			Transplant: []types.CalendarSeed{},
+			Harvest:    []types.CalendarSeed{},
+		}
+	}
+
+	for _, seed := range seeds {
+		w := ForZone(seed.Sowing, zone)
+		ref := types.CalendarSeed{ID: seed.ID, Variety_name: seed.Variety_name, Vegetable: seed.Vegetable, Quantity: seed.Quantity}
+
+		for i := range months {
+			m := &months[i]
+			if w.SowIndoor.Contains(m.Month) {
+				m.SowIndoor = append(m.SowIndoor, ref)
+			}
+			if w.SowOutdoor.Contains(m.Month) {
+				m.SowOutdoor = append(m.SowOutdoor, ref)
+			}
+			if w.Transplant.Contains(m.Month) {
+				m.Transplant = append(m.Transplant, ref)
+			}
+			if w.Harvest.Contains(m.Month) {
+				m.Harvest = append(m.Harvest, ref)
+			}
+		}
+	}
+
+	return months
+}
+
+func shift(r *types.MonthRange, offset int) *types.MonthRange {
+	if r == nil {
+		return nil
+	}
+	return &types.MonthRange{Start: wrapMonth(r.Start + offset), End: wrapMonth(r.End + offset)}
+}
+
+func wrapMonth(m int) int {
+	return ((m-1)%12+12)%12 + 1
+}
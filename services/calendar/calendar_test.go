@@ -0,0 +1,75 @@
+package calendar
+
+import (
+	"backend/seed-savers/types"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestZoneFor(t *testing.T) {
+	if zone := ZoneFor("Palermo", ""); zone != "B" {
+		t.Errorf("expected zone B for Palermo but got %s", zone)
+	}
+	if zone := ZoneFor("CN", ""); zone != "F" {
+		t.Errorf("expected zone F for Cuneo but got %s", zone)
+	}
+	if zone := ZoneFor("CN", "C"); zone != "C" {
+		t.Errorf("the user override must win, got %s", zone)
+	}
+	if zone := ZoneFor("", ""); zone != DefaultZone {
+		t.Errorf("expected the default zone without an address, got %s", zone)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	seeds := []types.Seed{
+		{
+			ID: 1, Variety_name: "san marzano", Vegetable: "pomodoro",
+			Sowing: types.SowingWindows{
+				SowIndoor:  &types.MonthRange{Start: 2, End: 3},
+				Transplant: &types.MonthRange{Start: 4, End: 5},
+				Harvest:    &types.MonthRange{Start: 7, End: 9},
+			},
+		},
+		{
+			ID: 2, Variety_name: "rosso di sulmona", Vegetable: "aglio",
+			Sowing: types.SowingWindows{SowOutdoor: &types.MonthRange{Start: 11, End: 1}},
+		},
+	}
+
+	months := Build(seeds, "E")
+
+	if len(months[2].SowIndoor) != 1 || len(months[1].SowIndoor) != 0 {
+		t.Errorf("zone E must delay indoor sowing by one month, got %+v", months[1:4])
+	}
+	if len(months[0].SowOutdoor) != 1 || len(months[1].SowOutdoor) != 1 || len(months[10].SowOutdoor) != 0 {
+		t.Errorf("garlic sowing must wrap over the year (December-February), got %+v", months)
+	}
+}
+
+func TestWriteICS(t *testing.T) {
+	seeds := []types.Seed{{
+		ID: 1, Variety_name: "cuore di bue, albenga", Vegetable: "pomodoro",
+		Sowing: types.SowingWindows{Harvest: &types.MonthRange{Start: 11, End: 2}},
+	}}
+
+	buf := new(bytes.Buffer)
+	if err := WriteICS(buf, seeds, "D", 2024, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	ics := buf.String()
+
+	for _, expected := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"DTSTART;VALUE=DATE:20241101\r\n",
+		"DTEND;VALUE=DATE:20250301\r\n",
+		"RRULE:FREQ=YEARLY\r\n",
+		`cuore di bue\, albenga`,
+	} {
+		if !strings.Contains(ics, expected) {
+			t.Errorf("expected %q in calendar:\n%s", expected, ics)
+		}
+	}
+}
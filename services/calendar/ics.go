@@ -0,0 +1,96 @@
+package calendar
+
+import (
+	"backend/seed-savers/types"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var activities = []struct {
+	key   string
+	label string
+	get   func(types.SowingWindows) *types.MonthRange
+}{
+	{"sow-indoor", "Semina in semenzaio", func(w types.SowingWindows) *types.MonthRange { return w.SowIndoor }},
+	{"sow-outdoor", "Semina in piena terra", func(w types.SowingWindows) *types.MonthRange { return w.SowOutdoor }},
+	{"transplant", "Trapianto", func(w types.SowingWindows) *types.MonthRange { return w.Transplant }},
+	{"harvest", "Raccolta", func(w types.SowingWindows) *types.MonthRange { return w.Harvest }},
+}
+
+// WriteICS scrive il calendario come feed iCalendar: un evento di tutto il periodo per ogni
+// attività di ogni seme, ripetuto ogni anno a partire da year
+func WriteICS(w io.Writer, seeds []types.Seed, zone string, year int, now time.Time) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Seed Savers//Calendario di semina//IT",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:Calendario di semina",
+	}
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, seed := range seeds {
+		windows := ForZone(seed.Sowing, zone)
+		for _, a := range activities {
+			r := a.get(windows)
+			if r == nil {
+				continue
+			}
+
+			start := time.Date(year, time.Month(r.Start), 1, 0, 0, 0, 0, time.UTC)
+			endYear := year
+			if r.End < r.Start {
+				endYear++
+			}
+			// DTEND è esclusivo: il primo giorno del mese successivo alla fine del periodo
+			end := time.Date(endYear, time.Month(r.End)+1, 1, 0, 0, 0, 0, time.UTC)
+
+			lines = append(lines,
+				"BEGIN:VEVENT",
+				fmt.Sprintf("UID:seed-%d-%s@seed-savers", seed.ID, a.key),
+				"DTSTAMP:"+stamp,
+				"DTSTART;VALUE=DATE:"+start.Format("20060102"),
+				"DTEND;VALUE=DATE:"+end.Format("20060102"),
+				"RRULE:FREQ=YEARLY",
+				"SUMMARY:"+escapeText(fmt.Sprintf("%s: %s %s", a.label, seed.Vegetable, seed.Variety_name)),
+				"TRANSP:TRANSPARENT",
+				"END:VEVENT",
+			)
+		}
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, fold(line)+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// fold spezza le righe più lunghe di 75 byte come richiesto da RFC 5545, senza tagliare i caratteri UTF-8
+func fold(line string) string {
+	if len(line) <= 75 {
+		return line
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
@@ -0,0 +1,134 @@
+package calendar
+
+import (
+	"backend/seed-savers/config"
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store        types.CalendarStore
+	usersStore   types.UserStore
+	sessionStore *auth.AuthStore
+}
+
+func NewHandler(s types.CalendarStore, us types.UserStore, sessionStore *auth.AuthStore) *Handler {
+	return &Handler{s, us, sessionStore}
+}
+
+func (h *Handler) RegisterRouter(router *mux.Router) {
+	router.HandleFunc("/calendar", auth.WithJWTAuth(h.handleCalendar, h.usersStore, h.sessionStore)).Methods("GET")
+	router.HandleFunc("/calendar/zone", auth.WithJWTAuth(h.handleSetZone, h.usersStore, h.sessionStore)).Methods("PUT")
+	router.HandleFunc("/calendar/feed", auth.WithJWTAuth(h.handleCreateFeed, h.usersStore, h.sessionStore)).Methods("POST")
+	router.HandleFunc("/calendar/feed/{token:[0-9a-f]+}.ics", h.handleFeed).Methods("GET")
+}
+
+func (h *Handler) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	seeds, zone, err := h.calendarData(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]any{
+		"zone":   zone,
+		"months": Build(seeds, zone),
+	})
+}
+
+func (h *Handler) handleSetZone(w http.ResponseWriter, r *http.Request) {
+	payload, err := utils.DecodePayload[types.ClimateZonePayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	if err := h.store.SetClimateZone(userID, payload.Zone); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// handleCreateFeed crea (o rigenera, invalidando il precedente) l'indirizzo segreto del feed ICS
+func (h *Handler) handleCreateFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	token := hex.EncodeToString(b)
+
+	if err := h.store.SetCalendarToken(userID, token); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, map[string]string{
+		"url": fmt.Sprintf("%s:%s/calendar/feed/%s.ics", config.Envs.PublicHost, config.Envs.Port, token),
+	})
+}
+
+// handleFeed non usa la sessione perché le app calendario non inviano cookie: il token nell'URL fa da credenziale
+func (h *Handler) handleFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.store.GetUserIDByCalendarToken(mux.Vars(r)["token"])
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	seeds, zone, err := h.calendarData(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	now := time.Now()
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	if err := WriteICS(w, seeds, zone, now.Year(), now); err != nil {
+		log.Printf("failed to write calendar feed: %v", err)
+	}
+}
+
+func (h *Handler) calendarData(userID int) ([]types.Seed, string, error) {
+	province, override, err := h.store.GetUserZoneInfo(userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	seeds, err := h.store.GetCalendarSeeds(userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return seeds, ZoneFor(province, override), nil
+}
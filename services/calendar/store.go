@@ -0,0 +1,82 @@
+package calendar
+
+import (
+	"backend/seed-savers/services/seed"
+	"backend/seed-savers/types"
+	"database/sql"
+	"fmt"
+)
+
+// Store gestisce l'accesso al database per il calendario di semina
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore crea e restituisce un nuovo oggetto Store con il database passato come parametro
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// GetCalendarSeeds restituisce i semi disponibili nell'inventario dell'utente con i periodi di semina
+func (s *Store) GetCalendarSeeds(userID int) ([]types.Seed, error) {
+	rows, err := s.db.Query(`SELECT s.seed_id, s.variety_name, s.vegetable, us.quantity,
+		s.sow_indoor_start, s.sow_indoor_end, s.sow_outdoor_start, s.sow_outdoor_end,
+		s.transplant_start, s.transplant_end, s.harvest_start, s.harvest_end
+		FROM users_seed us JOIN seed s ON us.seed_id = s.seed_id
+		WHERE us.user_id = ? AND us.quantity > 0
+		ORDER BY s.vegetable, s.variety_name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seeds := make([]types.Seed, 0)
+	for rows.Next() {
+		var sd types.Seed
+		var sowing [8]sql.NullInt64
+		err := rows.Scan(&sd.ID, &sd.Variety_name, &sd.Vegetable, &sd.Quantity,
+			&sowing[0], &sowing[1], &sowing[2], &sowing[3],
+			&sowing[4], &sowing[5], &sowing[6], &sowing[7])
+		if err != nil {
+			return nil, err
+		}
+		sd.Sowing = seed.ScanSowing(sowing)
+		seeds = append(seeds, sd)
+	}
+
+	return seeds, rows.Err()
+}
+
+// GetUserZoneInfo restituisce la provincia dell'indirizzo e la zona climatica scelta dall'utente
+func (s *Store) GetUserZoneInfo(userID int) (string, string, error) {
+	var province, zone sql.NullString
+	err := s.db.QueryRow(`SELECT a.province, u.climate_zone FROM users u
+		LEFT JOIN adress a ON a.user_id = u.user_id AND a.is_default WHERE u.user_id = ?`, userID).Scan(&province, &zone)
+	if err != nil {
+		return "", "", err
+	}
+
+	return province.String, zone.String, nil
+}
+
+// SetClimateZone salva la zona climatica scelta dall'utente; una stringa vuota torna a quella della provincia
+func (s *Store) SetClimateZone(userID int, zone string) error {
+	_, err := s.db.Exec("UPDATE users SET climate_zone = ? WHERE user_id = ?", sql.NullString{String: zone, Valid: zone != ""}, userID)
+	return err
+}
+
+// SetCalendarToken salva il token segreto usato per l'indirizzo del feed ICS
+func (s *Store) SetCalendarToken(userID int, token string) error {
+	_, err := s.db.Exec("UPDATE users SET calendar_token = ? WHERE user_id = ?", token, userID)
+	return err
+}
+
+// GetUserIDByCalendarToken trova l'utente a cui appartiene il token del feed
+func (s *Store) GetUserIDByCalendarToken(token string) (int, error) {
+	var userID int
+	err := s.db.QueryRow("SELECT user_id FROM users WHERE calendar_token = ?", token).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("calendar not found")
+	}
+	return userID, err
+}
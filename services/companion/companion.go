@@ -0,0 +1,94 @@
+package companion
+
+import (
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+)
+
+// Canonical normalizza i nomi degli ortaggi e, per le relazioni simmetriche, li mette in ordine
+// alfabetico così la stessa coppia non può essere salvata due volte
+func Canonical(relation *types.CropRelation) {
+	relation.CropA = utils.NormalizeName(relation.CropA)
+	relation.CropB = utils.NormalizeName(relation.CropB)
+	if relation.Kind != types.RelationRotationFollows && relation.CropB < relation.CropA {
+		relation.CropA, relation.CropB = relation.CropB, relation.CropA
+	}
+}
+
+// ForCrop raccoglie consociazioni, antagonismi e rotazioni di un ortaggio
+func ForCrop(crop string, relations []types.CropRelation) types.CompanionInfo {
+	crop = utils.NormalizeName(crop)
+	info := types.CompanionInfo{
+		Crop:        crop,
+		Companions:  []types.CropNote{},
+		Antagonists: []types.CropNote{},
+		GrowAfter:   []types.CropNote{},
+		GrowBefore:  []types.CropNote{},
+	}
+
+	for _, r := range relations {
+		if r.CropA != crop && r.CropB != crop {
+			continue
+		}
+		other := r.CropA
+		if other == crop {
+			other = r.CropB
+		}
+		note := types.CropNote{Crop: other, Note: r.Note}
+
+		switch r.Kind {
+		case types.RelationCompanion:
+			info.Companions = append(info.Companions, note)
+		case types.RelationAntagonist:
+			info.Antagonists = append(info.Antagonists, note)
+		case types.RelationRotationFollows:
+			if r.CropA == crop {
+				info.GrowAfter = append(info.GrowAfter, note)
+			} else {
+				info.GrowBefore = append(info.GrowBefore, note)
+			}
+		}
+	}
+
+	return info
+}
+
+// Check confronta a coppie i semi scelti e segnala gli ortaggi che non vanno seminati vicini
+// (e, per completezza, quelli che si aiutano a vicenda)
+func Check(seeds []types.Seed, relations []types.CropRelation) types.CompanionReport {
+	index := make(map[[3]string]types.CropRelation, len(relations))
+	for _, r := range relations {
+		if r.Kind != types.RelationRotationFollows {
+			index[[3]string{r.CropA, r.CropB, r.Kind}] = r
+		}
+	}
+
+	report := types.CompanionReport{Conflicts: []types.CompanionPair{}, Companions: []types.CompanionPair{}}
+	for i := 0; i < len(seeds); i++ {
+		for j := i + 1; j < len(seeds); j++ {
+			a, b := seeds[i], seeds[j]
+			pair := types.CropRelation{CropA: a.Vegetable, CropB: b.Vegetable}
+			Canonical(&pair)
+			if pair.CropA == pair.CropB {
+				continue
+			}
+
+			for kind, list := range map[string]*[]types.CompanionPair{
+				types.RelationAntagonist: &report.Conflicts,
+				types.RelationCompanion:  &report.Companions,
+			} {
+				if r, ok := index[[3]string{pair.CropA, pair.CropB, kind}]; ok {
+					*list = append(*list, types.CompanionPair{
+						SeedA: a.ID,
+						SeedB: b.ID,
+						CropA: utils.NormalizeName(a.Vegetable),
+						CropB: utils.NormalizeName(b.Vegetable),
+						Note:  r.Note,
+					})
+				}
+			}
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,136 @@
+package companion
+
+import (
+	"backend/seed-savers/types"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var relations = []types.CropRelation{
+	{ID: 1, CropA: "basilico", CropB: "pomodoro", Kind: types.RelationCompanion},
+	{ID: 2, CropA: "finocchio", CropB: "pomodoro", Kind: types.RelationAntagonist, Note: "Il finocchio frena la crescita"},
+	{ID: 3, CropA: "pomodoro", CropB: "fagiolo", Kind: types.RelationRotationFollows},
+	{ID: 4, CropA: "aglio", CropB: "pomodoro", Kind: types.RelationRotationFollows},
+}
+
+func TestCanonical(t *testing.T) {
+	r := types.CropRelation{CropA: " Pomodoro ", CropB: "Basilico", Kind: types.RelationCompanion}
+	Canonical(&r)
+	if r.CropA != "basilico" || r.CropB != "pomodoro" {
+		t.Errorf("symmetric relations must be stored in alphabetical order, got %+v", r)
+	}
+
+	r = types.CropRelation{CropA: "Pomodoro", CropB: "Fagiolo", Kind: types.RelationRotationFollows}
+	Canonical(&r)
+	if r.CropA != "pomodoro" || r.CropB != "fagiolo" {
+		t.Errorf("rotation relations are directional and must keep their order, got %+v", r)
+	}
+}
+
+func TestForCrop(t *testing.T) {
+	info := ForCrop("Pomodoro", relations)
+
+	if len(info.Companions) != 1 || info.Companions[0].Crop != "basilico" {
+		t.Errorf("expected basilico as companion, got %+v", info.Companions)
+	}
+	if len(info.Antagonists) != 1 || info.Antagonists[0].Crop != "finocchio" {
+		t.Errorf("expected finocchio as antagonist, got %+v", info.Antagonists)
+	}
+	if len(info.GrowAfter) != 1 || info.GrowAfter[0].Crop != "fagiolo" {
+		t.Errorf("tomatoes should be grown after beans, got %+v", info.GrowAfter)
+	}
+	if len(info.GrowBefore) != 1 || info.GrowBefore[0].Crop != "aglio" {
+		t.Errorf("garlic should follow tomatoes, got %+v", info.GrowBefore)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	seeds := []types.Seed{
+		{ID: 1, Vegetable: "Pomodoro"},
+		{ID: 2, Vegetable: "Finocchio"},
+		{ID: 3, Vegetable: "basilico"},
+		{ID: 4, Vegetable: "pomodoro"},
+	}
+
+	report := Check(seeds, relations)
+
+	if len(report.Conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts (both tomatoes with fennel), got %+v", report.Conflicts)
+	}
+	if report.Conflicts[0].SeedA != 1 || report.Conflicts[0].SeedB != 2 || report.Conflicts[0].Note == "" {
+		t.Errorf("unexpected conflict %+v", report.Conflicts[0])
+	}
+	if len(report.Companions) != 2 {
+		t.Errorf("expected basil to help both tomatoes, got %+v", report.Companions)
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	store := &mockStore{}
+	seeds := &mockSeedStore{seeds: map[int]types.Seed{1: {ID: 1, Vegetable: "Pomodoro"}, 2: {ID: 2, Vegetable: "Finocchio"}}}
+	handler := NewHandler(store, seeds, nil, nil)
+
+	post := func(h http.HandlerFunc, payload any) *httptest.ResponseRecorder {
+		marshalled, _ := json.Marshal(payload)
+		rr := httptest.NewRecorder()
+		h(rr, httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(marshalled)))
+		return rr
+	}
+
+	t.Run("should load the seeds at once", func(t *testing.T) {
+		rr := post(handler.handleCheck, types.CompanionCheckPayload{SeedIDs: []int{1, 2}})
+		if rr.Code != http.StatusOK || seeds.calls != 1 {
+			t.Errorf("expected one lookup and status code %d, got %d calls and %d", http.StatusOK, seeds.calls, rr.Code)
+		}
+		if rr := post(handler.handleCheck, types.CompanionCheckPayload{SeedIDs: []int{1, 3}}); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should limit the seeds to check", func(t *testing.T) {
+		if rr := post(handler.handleCheck, types.CompanionCheckPayload{SeedIDs: make([]int, 51)}); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should refuse a duplicate relation", func(t *testing.T) {
+		store.createErr = ErrRelationExists
+		payload := types.CropRelationPayload{CropA: "basilico", CropB: "pomodoro", Kind: types.RelationCompanion}
+		if rr := post(handler.handleCreateRelation, payload); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+}
+
+type mockStore struct {
+	types.CompanionStore
+	createErr error
+}
+
+func (m *mockStore) GetRelationsForCrops(crops []string) ([]types.CropRelation, error) {
+	return relations, nil
+}
+
+func (m *mockStore) CreateRelation(relation *types.CropRelation) error {
+	return m.createErr
+}
+
+type mockSeedStore struct {
+	types.SeedStore
+	seeds map[int]types.Seed
+	calls int
+}
+
+func (m *mockSeedStore) GetSeedsByIDs(ids []int) (map[int]types.Seed, error) {
+	m.calls++
+	found := make(map[int]types.Seed)
+	for _, id := range ids {
+		if seed, ok := m.seeds[id]; ok {
+			found[id] = seed
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,168 @@
+package companion
+
+import (
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store        types.CompanionStore
+	seedStore    types.SeedStore
+	usersStore   types.UserStore
+	sessionStore *auth.AuthStore
+}
+
+func NewHandler(s types.CompanionStore, seedStore types.SeedStore, us types.UserStore, sessionStore *auth.AuthStore) *Handler {
+	return &Handler{s, seedStore, us, sessionStore}
+}
+
+func (h *Handler) RegisterRouter(router *mux.Router) {
+	router.HandleFunc("/seeds/{seedID:[0-9]+}/companions", h.handleSeedCompanions).Methods("GET")
+	router.HandleFunc("/companions/check", h.handleCheck).Methods("POST")
+	router.HandleFunc("/admin/companions", auth.WithRole(h.handleListRelations, h.usersStore, h.sessionStore, types.RoleAdmin)).Methods("GET")
+	router.HandleFunc("/admin/companions", auth.WithRole(h.handleCreateRelation, h.usersStore, h.sessionStore, types.RoleAdmin)).Methods("POST")
+	router.HandleFunc("/admin/companions/{id:[0-9]+}", auth.WithRole(h.handleUpdateRelation, h.usersStore, h.sessionStore, types.RoleAdmin)).Methods("PUT")
+	router.HandleFunc("/admin/companions/{id:[0-9]+}", auth.WithRole(h.handleDeleteRelation, h.usersStore, h.sessionStore, types.RoleAdmin)).Methods("DELETE")
+}
+
+func (h *Handler) handleSeedCompanions(w http.ResponseWriter, r *http.Request) {
+	seedID, _ := strconv.Atoi(mux.Vars(r)["seedID"])
+
+	seed, err := h.seedStore.GetSeedByID(seedID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	crop := utils.NormalizeName(seed.Vegetable)
+	relations, err := h.store.GetRelationsForCrops([]string{crop})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, ForCrop(crop, relations))
+}
+
+func (h *Handler) handleCheck(w http.ResponseWriter, r *http.Request) {
+	payload, err := utils.DecodePayload[types.CompanionCheckPayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	found, err := h.seedStore.GetSeedsByIDs(payload.SeedIDs)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	seeds := make([]types.Seed, 0, len(payload.SeedIDs))
+	crops := make([]string, 0, len(payload.SeedIDs))
+	for _, id := range payload.SeedIDs {
+		seed, ok := found[id]
+		if !ok {
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("seed %d not found", id))
+			return
+		}
+		seeds = append(seeds, seed)
+		crops = append(crops, utils.NormalizeName(seed.Vegetable))
+	}
+
+	relations, err := h.store.GetRelationsForCrops(crops)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, Check(seeds, relations))
+}
+
+func (h *Handler) handleListRelations(w http.ResponseWriter, r *http.Request) {
+	relations, err := h.store.GetRelations()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, relations)
+}
+
+func (h *Handler) handleCreateRelation(w http.ResponseWriter, r *http.Request) {
+	payload, err := utils.DecodePayload[types.CropRelationPayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	relation := relationFromPayload(payload)
+	if relation.CropA == relation.CropB {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("a crop cannot be related to itself"))
+		return
+	}
+
+	err = h.store.CreateRelation(&relation)
+	if errors.Is(err, ErrRelationExists) {
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, relation)
+}
+
+func (h *Handler) handleUpdateRelation(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	payload, err := utils.DecodePayload[types.CropRelationPayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	relation := relationFromPayload(payload)
+	if relation.CropA == relation.CropB {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("a crop cannot be related to itself"))
+		return
+	}
+	relation.ID = id
+
+	err = h.store.UpdateRelation(&relation)
+	if errors.Is(err, ErrRelationExists) {
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, relation)
+}
+
+func (h *Handler) handleDeleteRelation(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err := h.store.DeleteRelation(id); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func relationFromPayload(payload *types.CropRelationPayload) types.CropRelation {
+	relation := types.CropRelation{CropA: payload.CropA, CropB: payload.CropB, Kind: payload.Kind, Note: payload.Note}
+	Canonical(&relation)
+	return relation
+}
@@ -0,0 +1,142 @@
+package companion
+
+import (
+	"backend/seed-savers/types"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrRelationExists indica che la stessa relazione tra i due ortaggi è già nella base di conoscenza
+var ErrRelationExists = errors.New("the relation already exists")
+
+// Store gestisce l'accesso al database per le relazioni tra ortaggi
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore crea e restituisce un nuovo oggetto Store con il database passato come parametro
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+const relationColumns = "relation_id, crop_a, crop_b, kind, note"
+
+// GetRelations restituisce tutte le relazioni della base di conoscenza
+func (s *Store) GetRelations() ([]types.CropRelation, error) {
+	return s.queryRelations("SELECT " + relationColumns + " FROM crop_relation ORDER BY crop_a, crop_b, kind")
+}
+
+// GetRelationsForCrops restituisce le relazioni in cui compare almeno uno degli ortaggi indicati
+func (s *Store) GetRelationsForCrops(crops []string) ([]types.CropRelation, error) {
+	if len(crops) == 0 {
+		return []types.CropRelation{}, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(crops)), ",")
+	args := make([]any, 0, len(crops)*2)
+	for _, c := range crops {
+		args = append(args, c)
+	}
+	args = append(args, args...)
+
+	return s.queryRelations(fmt.Sprintf("SELECT %s FROM crop_relation WHERE crop_a IN (%s) OR crop_b IN (%s) ORDER BY crop_a, crop_b, kind",
+		relationColumns, placeholders, placeholders), args...)
+}
+
+// GetRelationByID restituisce una singola relazione
+func (s *Store) GetRelationByID(id int) (*types.CropRelation, error) {
+	relations, err := s.queryRelations("SELECT "+relationColumns+" FROM crop_relation WHERE relation_id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(relations) == 0 {
+		return nil, fmt.Errorf("relation not found")
+	}
+
+	return &relations[0], nil
+}
+
+// CreateRelation inserisce una nuova relazione dopo averla portata in forma canonica
+func (s *Store) CreateRelation(relation *types.CropRelation) error {
+	Canonical(relation)
+	res, err := s.db.Exec("INSERT INTO crop_relation (crop_a, crop_b, kind, note) VALUES (?, ?, ?, ?)",
+		relation.CropA, relation.CropB, relation.Kind, relation.Note)
+	if err != nil {
+		return duplicate(err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	relation.ID = int(id)
+
+	return nil
+}
+
+// UpdateRelation modifica una relazione esistente
+func (s *Store) UpdateRelation(relation *types.CropRelation) error {
+	Canonical(relation)
+	res, err := s.db.Exec("UPDATE crop_relation SET crop_a = ?, crop_b = ?, kind = ?, note = ? WHERE relation_id = ?",
+		relation.CropA, relation.CropB, relation.Kind, relation.Note, relation.ID)
+	if err != nil {
+		return duplicate(err)
+	}
+
+	return checkAffected(res)
+}
+
+// DeleteRelation elimina una relazione
+func (s *Store) DeleteRelation(id int) error {
+	res, err := s.db.Exec("DELETE FROM crop_relation WHERE relation_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res)
+}
+
+func (s *Store) queryRelations(query string, args ...any) ([]types.CropRelation, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	relations := make([]types.CropRelation, 0)
+	for rows.Next() {
+		var r types.CropRelation
+		var note sql.NullString
+		if err := rows.Scan(&r.ID, &r.CropA, &r.CropB, &r.Kind, &note); err != nil {
+			return nil, err
+		}
+		r.Note = note.String
+		relations = append(relations, r)
+	}
+
+	return relations, rows.Err()
+}
+
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("relation not found")
+	}
+	return nil
+}
+
+// duplicate traduce la violazione del vincolo UNIQUE (crop_a, crop_b, kind) in ErrRelationExists
+func duplicate(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		return ErrRelationExists
+	}
+	return err
+}
@@ -0,0 +1,106 @@
+package conservation
+
+import (
+	"backend/seed-savers/types"
+	"strings"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	cases := []struct {
+		name                            string
+		owners, regions, harvest, score int
+		atRisk                          bool
+	}{
+		{"single grower with fresh seeds", 1, 1, 2026, 75, true},
+		{"two growers in one region", 2, 1, 2025, 60, true},
+		{"two growers far apart", 2, 2, 2025, 45, false},
+		{"two growers far apart with old seeds", 2, 2, 2021, 70, true},
+		{"widely grown", 8, 5, 2026, 0, false},
+		{"unknown harvest", 4, 3, 0, 20, false},
+	}
+
+	for _, c := range cases {
+		score := Score(c.owners, c.regions, c.harvest, 2026)
+		if score != c.score || (score >= AtRiskScore) != c.atRisk {
+			t.Errorf("%s: expected %d, got %d", c.name, c.score, score)
+		}
+	}
+}
+
+func TestAssessAndAtRisk(t *testing.T) {
+	holders := []types.SeedHolder{
+		// seme 1: due coltivatori piemontesi
+		{SeedID: 1, UserID: 2, Cap: "10121", LastHarvest: 2025},
+		{SeedID: 1, UserID: 3, Province: "CN", LastHarvest: 2024},
+		// seme 2: Piemonte e Sicilia, raccolto recente
+		{SeedID: 2, UserID: 2, Cap: "10121", LastHarvest: 2026},
+		{SeedID: 2, UserID: 4, Province: "Palermo", LastHarvest: 2026},
+	}
+	rarity := Assess(holders, 2026)
+
+	if r := rarity[1]; r.Owners != 2 || r.Regions != 1 || r.NewestHarvest != 2025 || !r.AtRisk {
+		t.Errorf("unexpected rarity for seed 1: %+v", r)
+	}
+	if r := rarity[2]; r.Regions != 2 || r.AtRisk {
+		t.Errorf("unexpected rarity for seed 2: %+v", r)
+	}
+
+	seeds := []types.Seed{
+		{ID: 1, Variety_name: "fagiolo di saluggia"},
+		{ID: 2, Variety_name: "pomodoro costoluto"},
+		{ID: 3, Variety_name: "mais ottofile", ConservationStatus: types.ConservationEndangered},
+		{ID: 4, Variety_name: "zucca senza possessori"},
+	}
+	atRisk := AtRisk(seeds, rarity)
+	if len(atRisk) != 2 || atRisk[0].Seed.ID != 3 || atRisk[1].Seed.ID != 1 {
+		t.Fatalf("expected the endangered variety first, then seed 1, got %+v", atRisk)
+	}
+	if b := atRisk[0].Seed.Badges; len(b) != 2 || b[0] != types.ConservationEndangered || b[1] != types.BadgeAtRisk {
+		t.Errorf("unexpected badges %v", b)
+	}
+	if b := Badges(seeds[1], rarity[2]); b != nil {
+		t.Errorf("a widely grown variety must have no badges, got %v", b)
+	}
+}
+
+type mockStore struct {
+	types.ConservationStore
+}
+
+func (m *mockStore) GetContacts(userIDs []int) (map[int]types.AlertRecipient, error) {
+	contacts := make(map[int]types.AlertRecipient)
+	for _, id := range userIDs {
+		contacts[id] = types.AlertRecipient{UserID: id, Name: "Anna", Email: "anna@example.com"}
+	}
+	return contacts, nil
+}
+
+func TestNotifyHolders(t *testing.T) {
+	atRisk := []types.AtRiskSeed{
+		{Seed: types.Seed{ID: 1, Variety_name: "fagiolo di saluggia", Vegetable: "fagiolo"}},
+		{Seed: types.Seed{ID: 3, Variety_name: "mais ottofile", Vegetable: "mais"}},
+	}
+	holders := []types.SeedHolder{
+		{SeedID: 1, UserID: 2},
+		{SeedID: 3, UserID: 2},
+		{SeedID: 2, UserID: 4},
+	}
+
+	notices := HoldersToNotify(atRisk, holders)
+	if len(notices) != 1 || len(notices[2]) != 2 {
+		t.Fatalf("expected one notice for user 2 with both varieties, got %+v", notices)
+	}
+
+	sent := make([]string, 0)
+	h := &Handler{store: &mockStore{}, send: func(to string, msg []byte) error {
+		sent = append(sent, string(msg))
+		return nil
+	}}
+	if err := h.sendNotices(notices); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || !strings.Contains(sent[0], "fagiolo di saluggia") || !strings.Contains(sent[0], "mais ottofile") {
+		t.Errorf("unexpected messages %v", sent)
+	}
+}
@@ -0,0 +1,112 @@
+package conservation
+
+import (
+	"backend/seed-savers/geo"
+	"backend/seed-savers/types"
+	"sort"
+)
+
+// AtRiskScore è il punteggio da cui una varietà è considerata a rischio
+const AtRiskScore = 60
+
+// Score calcola il rischio di perdita di una varietà. Pesano soprattutto i possessori (fino a 50
+// punti), poi la diffusione sul territorio (fino a 25) e l'età del raccolto più recente (fino a 25),
+// perché i semi vecchi germinano sempre meno. newestHarvest vale 0 se l'anno non è noto
+func Score(owners, regions, newestHarvest, year int) int {
+	score := 0
+	switch {
+	case owners <= 1:
+		score += 50
+	case owners == 2:
+		score += 35
+	case owners == 3:
+		score += 20
+	case owners <= 5:
+		score += 10
+	}
+
+	switch {
+	case regions <= 1:
+		score += 25
+	case regions == 2:
+		score += 10
+	}
+
+	age := year - newestHarvest
+	switch {
+	case newestHarvest == 0:
+		score += 10
+	case age >= 5:
+		score += 25
+	case age >= 3:
+		score += 15
+	case age >= 2:
+		score += 5
+	}
+
+	return score
+}
+
+// Assess valuta la rarità di ogni seme che ha almeno un possessore. Le regioni sono ricavate dal CAP
+// o dalla provincia di ciascun possessore; chi non è collocabile non allarga la diffusione
+func Assess(holders []types.SeedHolder, year int) map[int]types.Rarity {
+	regions := make(map[int]map[string]bool)
+	rarity := make(map[int]types.Rarity)
+	for _, h := range holders {
+		r := rarity[h.SeedID]
+		r.SeedID = h.SeedID
+		r.Owners++
+		if h.LastHarvest > r.NewestHarvest {
+			r.NewestHarvest = h.LastHarvest
+		}
+		rarity[h.SeedID] = r
+
+		if regions[h.SeedID] == nil {
+			regions[h.SeedID] = make(map[string]bool)
+		}
+		if p, ok := geo.Locate(h.Cap, h.Province); ok {
+			regions[h.SeedID][p.Region] = true
+		}
+	}
+
+	for id, r := range rarity {
+		r.Regions = len(regions[id])
+		r.Score = Score(r.Owners, r.Regions, r.NewestHarvest, year)
+		r.AtRisk = r.Score >= AtRiskScore
+		rarity[id] = r
+	}
+	return rarity
+}
+
+// AtRisk restituisce le varietà a rischio, dalla più minacciata: quelle con un punteggio alto e quelle
+// con uno stato di conservazione ufficiale. Una varietà ufficialmente minacciata che nessuno ha più in
+// inventario ha il punteggio massimo
+func AtRisk(seeds []types.Seed, rarity map[int]types.Rarity) []types.AtRiskSeed {
+	out := make([]types.AtRiskSeed, 0)
+	for _, seed := range seeds {
+		r, held := rarity[seed.ID]
+		if !held {
+			r = types.Rarity{SeedID: seed.ID, Score: 100, AtRisk: true}
+		}
+		if (held && r.AtRisk) || seed.ConservationStatus != "" {
+			seed.Badges = Badges(seed, r)
+			out = append(out, types.AtRiskSeed{Seed: seed, Rarity: r})
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Rarity.Score > out[j].Rarity.Score })
+	return out
+}
+
+// Badges restituisce i contrassegni da mostrare nel catalogo: lo stato ufficiale, se c'è, e at-risk
+// quando il punteggio supera la soglia
+func Badges(seed types.Seed, r types.Rarity) []string {
+	var badges []string
+	if seed.ConservationStatus != "" {
+		badges = append(badges, seed.ConservationStatus)
+	}
+	if r.AtRisk {
+		badges = append(badges, types.BadgeAtRisk)
+	}
+	return badges
+}
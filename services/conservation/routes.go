@@ -0,0 +1,165 @@
+package conservation
+
+import (
+	"backend/seed-savers/config"
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/services/email"
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store        types.ConservationStore
+	seedStore    types.SeedStore
+	usersStore   types.UserStore
+	sessionStore *auth.AuthStore
+	send         func(to string, msg []byte) error
+}
+
+func NewHandler(s types.ConservationStore, seedStore types.SeedStore, us types.UserStore, sessionStore *auth.AuthStore) *Handler {
+	return &Handler{s, seedStore, us, sessionStore, email.SendMail}
+}
+
+func (h *Handler) RegisterRouter(router *mux.Router) {
+	router.HandleFunc("/conservation/at-risk", h.handleAtRisk).Methods("GET")
+	router.HandleFunc("/admin/seeds/{seedID:[0-9]+}/conservation", auth.WithRole(h.handleSetStatus, h.usersStore, h.sessionStore, types.RoleCurator, types.RoleAdmin)).Methods("PUT")
+	router.HandleFunc("/admin/conservation/notify", auth.WithRole(h.handleNotifyHolders, h.usersStore, h.sessionStore, types.RoleCurator, types.RoleAdmin)).Methods("POST")
+}
+
+func (h *Handler) handleAtRisk(w http.ResponseWriter, r *http.Request) {
+	atRisk, _, err := h.atRisk()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, atRisk)
+}
+
+func (h *Handler) atRisk() ([]types.AtRiskSeed, []types.SeedHolder, error) {
+	seeds, err := h.seedStore.GetSeeds()
+	if err != nil {
+		return nil, nil, err
+	}
+	holders, err := h.seedStore.GetHoldings(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return AtRisk(seeds, Assess(holders, time.Now().Year())), holders, nil
+}
+
+func (h *Handler) handleSetStatus(w http.ResponseWriter, r *http.Request) {
+	seedID, _ := strconv.Atoi(mux.Vars(r)["seedID"])
+
+	payload, err := utils.DecodePayload[types.ConservationPayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	seed, err := h.seedStore.GetSeedByID(seedID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	//senza stato non ha senso conservare la fonte
+	if payload.Status == "" {
+		payload.Source = ""
+	}
+	if err := h.store.SetConservationStatus(seed.ID, payload.Status, payload.Source, userID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, payload)
+}
+
+// handleNotifyHolders chiede a chi possiede varietà a rischio di darne priorità nelle prossime
+// riproduzioni. Le email partono in background, la risposta riporta quante ne verranno inviate
+func (h *Handler) handleNotifyHolders(w http.ResponseWriter, r *http.Request) {
+	atRisk, holders, err := h.atRisk()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	notices := HoldersToNotify(atRisk, holders)
+	go func() {
+		if err := h.sendNotices(notices); err != nil {
+			log.Printf("failed to notify holders of at-risk varieties: %v", err)
+		}
+	}()
+
+	utils.WriteJSON(w, http.StatusAccepted, map[string]int{"varieties": len(atRisk), "recipients": len(notices)})
+}
+
+// HoldersToNotify raggruppa per possessore le varietà a rischio che ha in inventario
+func HoldersToNotify(atRisk []types.AtRiskSeed, holders []types.SeedHolder) map[int][]types.Seed {
+	bySeed := make(map[int]types.Seed, len(atRisk))
+	for _, a := range atRisk {
+		bySeed[a.Seed.ID] = a.Seed
+	}
+
+	notices := make(map[int][]types.Seed)
+	for _, h := range holders {
+		if seed, ok := bySeed[h.SeedID]; ok {
+			notices[h.UserID] = append(notices[h.UserID], seed)
+		}
+	}
+	return notices
+}
+
+func (h *Handler) sendNotices(notices map[int][]types.Seed) error {
+	userIDs := make([]int, 0, len(notices))
+	for id := range notices {
+		userIDs = append(userIDs, id)
+	}
+	sort.Ints(userIDs)
+
+	contacts, err := h.store.GetContacts(userIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range userIDs {
+		c, ok := contacts[id]
+		if !ok {
+			continue
+		}
+		if err := h.send(c.Email, noticeMessage(c, notices[id])); err != nil {
+			log.Printf("failed to notify user %d: %v", id, err)
+		}
+	}
+	return nil
+}
+
+func noticeMessage(c types.AlertRecipient, seeds []types.Seed) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<html><body><h1>Ciao %s, alcune tue varietà rischiano di andare perdute</h1>", html.EscapeString(c.Name))
+	b.WriteString("<p>Sono custodite da pochissimi coltivatori. Se puoi, dai loro la precedenza nella prossima riproduzione:</p><ul>")
+	for _, s := range seeds {
+		fmt.Fprintf(&b, "<li><a href='%s:%s/seeds/%d'>%s (%s)</a></li>",
+			config.Envs.PublicHost, config.Envs.Port, s.ID, html.EscapeString(s.Variety_name), html.EscapeString(s.Vegetable))
+	}
+	b.WriteString("</ul></body></html>")
+
+	return email.Message("Varietà a rischio nel tuo orto", b.String())
+}
@@ -0,0 +1,67 @@
+package conservation
+
+import (
+	"backend/seed-savers/types"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore crea e restituisce un nuovo oggetto Store con il database passato come parametro
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// SetConservationStatus assegna lo stato ufficiale a una varietà; uno stato vuoto lo rimuove
+func (s *Store) SetConservationStatus(seedID int, status, source string, curatorID int) error {
+	res, err := s.db.Exec(`UPDATE seed SET conservation_status = NULLIF(?, ''), conservation_source = NULLIF(?, ''),
+		conservation_set_by = ? WHERE seed_id = ?`, status, source, curatorID, seedID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("seed not found")
+	}
+	return nil
+}
+
+// GetContacts restituisce nome ed email degli utenti indicati, per gli avvisi ai possessori
+func (s *Store) GetContacts(userIDs []int) (map[int]types.AlertRecipient, error) {
+	contacts := make(map[int]types.AlertRecipient)
+	if len(userIDs) == 0 {
+		return contacts, nil
+	}
+
+	args := make([]any, len(userIDs))
+	for i, id := range userIDs {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(userIDs)), ",")
+
+	rows, err := s.db.Query("SELECT user_id, name, email FROM users WHERE user_id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c types.AlertRecipient
+		var name sql.NullString
+		if err := rows.Scan(&c.UserID, &name, &c.Email); err != nil {
+			return nil, err
+		}
+		c.Name = name.String
+		contacts[c.UserID] = c
+	}
+
+	return contacts, rows.Err()
+}
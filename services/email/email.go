@@ -3,7 +3,9 @@ package email
 import (
 	"backend/seed-savers/config"
 	"fmt"
+	"mime"
 	"net/smtp"
+	"strings"
 )
 
 func initAuth() smtp.Auth {
@@ -20,3 +22,12 @@ func SendMail(reciver string,  msg []byte) error {
 
 	return smtp.SendMail(fmt.Sprintf("%v:%v", config.Envs.Hostsmtp, "587"), auth, config.Envs.Email, []string{reciver},msg)
 }
+
+// Message compone un messaggio HTML pronto per SendMail. L'oggetto può contenere testo scritto dagli
+// utenti: gli a capo diventano spazi, così non si possono aggiungere intestazioni, e il resto viene
+// codificato secondo la RFC 2047
+func Message(subject, html string) []byte {
+	subject = strings.NewReplacer("\r", " ", "\n", " ").Replace(subject)
+	headers := "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
+	return []byte("Subject: " + mime.QEncoding.Encode("UTF-8", subject) + "\n" + headers + html)
+}
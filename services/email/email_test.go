@@ -0,0 +1,18 @@
+package email
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMessage(t *testing.T) {
+	msg := string(Message("Cuore di bue\r\nBcc: spam@example.com è disponibile", "<p>ciao</p>"))
+
+	headers, _, _ := strings.Cut(msg, "\n\n")
+	if strings.Contains(headers, "\nBcc:") || strings.Count(headers, "\n") != 2 {
+		t.Errorf("the subject must stay on a single header line, got %q", headers)
+	}
+	if !strings.HasPrefix(msg, "Subject: =?UTF-8?q?") {
+		t.Errorf("a subject with accents must be encoded, got %q", msg)
+	}
+}
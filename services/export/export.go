@@ -0,0 +1,174 @@
+package export
+
+import (
+	"archive/zip"
+	"backend/seed-savers/types"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats associa ogni formato supportato al suo Content-Type
+var Formats = map[string]string{
+	"csv":   "text/csv; charset=utf-8",
+	"jsonl": "application/x-ndjson",
+	"zip":   "application/zip",
+}
+
+type writer interface {
+	Write(seed types.ExportSeed) error
+	Close() error
+}
+
+// Write esporta il catalogo nel formato richiesto scrivendo ogni seme appena viene letto dal database
+func Write(w io.Writer, format string, store types.ExportStore, filter types.ExportFilter) error {
+	var out writer
+	var err error
+	switch format {
+	case "csv":
+		out, err = newCSVWriter(w)
+	case "jsonl":
+		out = &jsonlWriter{enc: json.NewEncoder(w)}
+	case "zip":
+		out, err = newDwCWriter(w)
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := store.StreamCatalog(filter, out.Write); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+var csvHeader = []string{"id", "variety_name", "vegetable", "description", "total_quantity", "owners", "updated_at"}
+
+func csvRecord(seed types.ExportSeed) []string {
+	return []string{
+		strconv.Itoa(seed.ID),
+		seed.Variety_name,
+		seed.Vegetable,
+		seed.Description,
+		strconv.Itoa(seed.TotalQuantity),
+		strconv.Itoa(seed.Owners),
+		seed.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w)}
+	return cw, cw.w.Write(csvHeader)
+}
+
+func (c *csvWriter) Write(seed types.ExportSeed) error {
+	return c.w.Write(csvRecord(seed))
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonlWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonlWriter) Write(seed types.ExportSeed) error {
+	return j.enc.Encode(seed)
+}
+
+func (j *jsonlWriter) Close() error {
+	return nil
+}
+
+// archivio Darwin Core: il core è di tipo Taxon, con l'ortaggio come nome volgare e la varietà
+// come epiteto della cultivar. La disponibilità finisce in dynamicProperties
+const dwcMeta = `<?xml version="1.0" encoding="UTF-8"?>
+<archive xmlns="http://rs.tdwg.org/dwc/text/">
+  <core encoding="UTF-8" fieldsTerminatedBy="\t" linesTerminatedBy="\n" fieldsEnclosedBy="" ignoreHeaderLines="1" rowType="http://rs.tdwg.org/dwc/terms/Taxon">
+    <files>
+      <location>taxon.txt</location>
+    </files>
+    <id index="0"/>
+    <field index="0" term="http://rs.tdwg.org/dwc/terms/taxonID"/>
+    <field index="1" term="http://rs.tdwg.org/dwc/terms/vernacularName"/>
+    <field index="2" term="http://rs.tdwg.org/dwc/terms/cultivarEpithet"/>
+    <field index="3" term="http://rs.tdwg.org/dwc/terms/taxonRemarks"/>
+    <field index="4" term="http://purl.org/dc/terms/modified"/>
+    <field index="5" term="http://rs.tdwg.org/dwc/terms/dynamicProperties"/>
+    <field term="http://rs.tdwg.org/dwc/terms/taxonRank" default="cultivar"/>
+    <field term="http://purl.org/dc/terms/language" default="it"/>
+  </core>
+</archive>
+`
+
+var dwcHeader = []string{"taxonID", "vernacularName", "cultivarEpithet", "taxonRemarks", "modified", "dynamicProperties"}
+
+type dwcWriter struct {
+	zip   *zip.Writer
+	taxon io.Writer
+}
+
+func newDwCWriter(w io.Writer) (*dwcWriter, error) {
+	zw := zip.NewWriter(w)
+
+	meta, err := zw.Create("meta.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(meta, dwcMeta); err != nil {
+		return nil, err
+	}
+
+	// i file dello zip vanno scritti uno dopo l'altro, quindi taxon.txt resta aperto fino a Close
+	taxon, err := zw.Create("taxon.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	d := &dwcWriter{zip: zw, taxon: taxon}
+	return d, d.writeLine(dwcHeader)
+}
+
+func (d *dwcWriter) Write(seed types.ExportSeed) error {
+	properties, err := json.Marshal(map[string]int{"totalQuantity": seed.TotalQuantity, "owners": seed.Owners})
+	if err != nil {
+		return err
+	}
+
+	return d.writeLine([]string{
+		strconv.Itoa(seed.ID),
+		seed.Vegetable,
+		seed.Variety_name,
+		seed.Description,
+		seed.UpdatedAt.UTC().Format(time.RFC3339),
+		string(properties),
+	})
+}
+
+func (d *dwcWriter) Close() error {
+	return d.zip.Close()
+}
+
+// il formato testo di Darwin Core non usa virgolette: tabulazioni e a capo nei valori diventano spazi
+var dwcEscaper = strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+
+func (d *dwcWriter) writeLine(fields []string) error {
+	escaped := make([]string, len(fields))
+	for i, f := range fields {
+		escaped[i] = dwcEscaper.Replace(f)
+	}
+	_, err := io.WriteString(d.taxon, strings.Join(escaped, "\t")+"\n")
+	return err
+}
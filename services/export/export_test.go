@@ -0,0 +1,112 @@
+package export
+
+import (
+	"archive/zip"
+	"backend/seed-savers/types"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockExportStore struct {
+	seeds  []types.ExportSeed
+	filter types.ExportFilter
+}
+
+func (m *mockExportStore) StreamCatalog(filter types.ExportFilter, fn func(types.ExportSeed) error) error {
+	m.filter = filter
+	for _, s := range m.seeds {
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var updated = time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+func newMockStore() *mockExportStore {
+	return &mockExportStore{seeds: []types.ExportSeed{
+		{ID: 1, Variety_name: "san marzano", Vegetable: "pomodoro", Description: "allungato,\nda salsa", TotalQuantity: 30, Owners: 2, UpdatedAt: updated},
+		{ID: 2, Variety_name: "borlotto", Vegetable: "fagiolo", Description: "rampicante\tlungo", UpdatedAt: updated},
+	}}
+}
+
+func TestWriteCSV(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := Write(buf, "csv", newMockStore(), types.ExportFilter{}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.SplitN(buf.String(), "\n", 2)
+	if lines[0] != strings.Join(csvHeader, ",") {
+		t.Errorf("unexpected header %q", lines[0])
+	}
+	if !strings.Contains(buf.String(), `1,san marzano,pomodoro,"allungato,`) || !strings.Contains(buf.String(), ",30,2,2026-03-01T10:00:00Z") {
+		t.Errorf("unexpected CSV %q", buf.String())
+	}
+}
+
+func TestWriteJSONL(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := Write(buf, "jsonl", newMockStore(), types.ExportFilter{}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one line per seed, got %d", len(lines))
+	}
+	var seed types.ExportSeed
+	if err := json.Unmarshal([]byte(lines[0]), &seed); err != nil || seed.TotalQuantity != 30 {
+		t.Errorf("unexpected line %s (%v)", lines[0], err)
+	}
+}
+
+func TestWriteDarwinCore(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := Write(buf, "zip", newMockStore(), types.ExportFilter{}); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+
+	if !strings.Contains(files["meta.xml"], "cultivarEpithet") {
+		t.Error("meta.xml must describe the taxon columns")
+	}
+	lines := strings.Split(strings.TrimSpace(files["taxon.txt"]), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, got %q", files["taxon.txt"])
+	}
+	if fields := strings.Split(lines[2], "\t"); len(fields) != len(dwcHeader) || fields[3] != "rampicante lungo" {
+		t.Errorf("tabs inside values must be replaced, got %q", lines[2])
+	}
+	if !strings.Contains(lines[1], `{"owners":2,"totalQuantity":30}`) {
+		t.Errorf("availability must be in dynamicProperties, got %q", lines[1])
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	if ts, err := parseSince("2026-01-15"); err != nil || ts.Day() != 15 {
+		t.Errorf("expected a plain date to be accepted, got %v %v", ts, err)
+	}
+	if _, err := parseSince("2026-01-15T08:00:00+01:00"); err != nil {
+		t.Error(err)
+	}
+	if _, err := parseSince("ieri"); err == nil {
+		t.Error("expected an error for an invalid date")
+	}
+}
@@ -0,0 +1,57 @@
+package export
+
+import (
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store types.ExportStore
+}
+
+func NewHandler(s types.ExportStore) *Handler {
+	return &Handler{s}
+}
+
+func (h *Handler) RegisterRouter(router *mux.Router) {
+	router.HandleFunc("/export/catalog.{format:csv|jsonl|zip}", h.handleExport).Methods("GET")
+}
+
+// handleExport esporta il catalogo pubblico. Filtri: vegetable e since (data o data e ora RFC 3339)
+func (h *Handler) handleExport(w http.ResponseWriter, r *http.Request) {
+	format := mux.Vars(r)["format"]
+
+	filter := types.ExportFilter{Vegetable: r.URL.Query().Get("vegetable")}
+	if since := r.URL.Query().Get("since"); since != "" {
+		t, err := parseSince(since)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		filter.Since = t
+	}
+
+	w.Header().Set("Content-Type", Formats[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="seed-catalog.%s"`, format))
+
+	//una volta iniziato lo streaming lo stato non si può più cambiare, l'errore va solo nel log
+	if err := Write(w, format, h.store, filter); err != nil {
+		log.Printf("catalog export failed: %v", err)
+	}
+}
+
+func parseSince(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("since must be a date (2006-01-02) or an RFC 3339 timestamp")
+}
@@ -0,0 +1,67 @@
+package export
+
+import (
+	"backend/seed-savers/services/seed"
+	"backend/seed-savers/types"
+	"database/sql"
+	"strings"
+)
+
+// Store gestisce l'accesso al database per l'esportazione del catalogo
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore crea e restituisce un nuovo oggetto Store con il database passato come parametro
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// StreamCatalog legge il catalogo una riga alla volta insieme alla quantità totale disponibile
+// (la somma delle scorte pubbliche, seed.PublicStock, calcolata per tutti i semi in un'unica query).
+// Un seme conta come aggiornato anche quando qualcuno ne rifornisce le scorte
+func (s *Store) StreamCatalog(filter types.ExportFilter, fn func(types.ExportSeed) error) error {
+	where := make([]string, 0, 2)
+	args := make([]any, 0, 2)
+	if filter.Vegetable != "" {
+		where = append(where, "LOWER(s.vegetable) = LOWER(?)")
+		args = append(args, filter.Vegetable)
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "(s.updated_at >= ? OR a.restocked_at >= ?)")
+		args = append(args, filter.Since, filter.Since)
+	}
+
+	query := `SELECT s.seed_id, s.variety_name, s.vegetable, s.description,
+		GREATEST(s.updated_at, COALESCE(a.restocked_at, s.updated_at)),
+		COALESCE(a.total, 0), COALESCE(a.owners, 0)
+		FROM seed s LEFT JOIN (
+			SELECT us.seed_id, SUM(us.quantity) AS total, COUNT(*) AS owners, MAX(us.restocked_at) AS restocked_at
+			FROM users_seed us INNER JOIN users u ON us.user_id = u.user_id
+			WHERE ` + seed.PublicStock + ` GROUP BY us.seed_id
+		) a ON a.seed_id = s.seed_id`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY s.seed_id"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var seed types.ExportSeed
+		err := rows.Scan(&seed.ID, &seed.Variety_name, &seed.Vegetable, &seed.Description, &seed.UpdatedAt,
+			&seed.TotalQuantity, &seed.Owners)
+		if err != nil {
+			return err
+		}
+		if err := fn(seed); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,169 @@
+package growreport
+
+import (
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestSummarize(t *testing.T) {
+	summary := Summarize([]types.GrowTally{
+		{Province: "TO", Reports: 2, Sums: [4]int{170, 7, 0, 9}, Counts: [4]int{2, 2, 0, 2}},
+		{Province: "CN", Reports: 1, Sums: [4]int{60, 3, 4, 0}, Counts: [4]int{1, 1, 1, 0}},
+		{Province: "PA", Reports: 1, Sums: [4]int{0, 5, 0, 0}, Counts: [4]int{0, 1, 0, 0}},
+		{Province: "", Reports: 1, Sums: [4]int{0, 0, 0, 2}, Counts: [4]int{0, 0, 0, 1}},
+	})
+
+	if summary.Reports != 5 || *summary.Germination != 76.7 || *summary.Yield != 3.8 || *summary.DiseaseResistance != 4 || *summary.Taste != 3.7 {
+		t.Errorf("unexpected totals %+v", summary)
+	}
+	if len(summary.Regions) != 2 || summary.Regions[0].Region != "Piemonte" || summary.Regions[0].Reports != 3 {
+		t.Fatalf("unexpected regions %+v", summary.Regions)
+	}
+	if r := summary.Regions[1]; r.Region != "Sicilia" || r.Germination != nil || *r.Yield != 5 {
+		t.Errorf("unexpected Sicilian summary %+v", r)
+	}
+}
+
+type mockStore struct {
+	types.GrowReportStore
+	created *types.GrowReport
+	flags   int
+	hidden  bool
+}
+
+func (m *mockStore) OrderHasSeed(orderID, userID, seedID int) (bool, error) {
+	return orderID == 12, nil
+}
+
+func (m *mockStore) CountUserSeedImages(userID, seedID int, imageIDs []int) (int, error) {
+	n := 0
+	for _, id := range imageIDs {
+		if id < 100 {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *mockStore) CreateGrowReport(report *types.GrowReport) error {
+	report.ID = 1
+	m.created = report
+	return nil
+}
+
+func (m *mockStore) GetGrowReportByID(id int) (*types.GrowReport, error) {
+	if id != 1 {
+		return nil, fmt.Errorf("grow report not found")
+	}
+	return &types.GrowReport{ID: 1, UserID: 5, Status: types.GrowReportPublished}, nil
+}
+
+func (m *mockStore) FlagGrowReport(reportID, userID int, reason string) (int, error) {
+	m.flags++
+	return m.flags, nil
+}
+
+func (m *mockStore) SetGrowReportStatus(id int, status string) error {
+	m.hidden = status == types.GrowReportHidden
+	return nil
+}
+
+type mockSeedStore struct {
+	types.SeedStore
+}
+
+func (m *mockSeedStore) GetSeedByID(id int) (*types.Seed, error) {
+	return &types.Seed{ID: id}, nil
+}
+
+func (m *mockSeedStore) GetUserArea(userID int) (string, string, error) {
+	return "90121", "", nil
+}
+
+func request(method, path, body string, userID int) *http.Request {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	ctx := context.WithValue(req.Context(), auth.UserKey, userID)
+	ctx = context.WithValue(ctx, auth.RoleKey, types.RoleUser)
+	return req.WithContext(ctx)
+}
+
+func TestGrowReportHandlers(t *testing.T) {
+	store := &mockStore{}
+	handler := NewHandler(store, &mockSeedStore{}, nil, nil)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/seeds/{seedID}/grow-reports", handler.handleCreateReport)
+		router.ServeHTTP(rr, request(http.MethodPost, "/seeds/3/grow-reports", body, 7))
+		return rr
+	}
+
+	t.Run("should create a report located from the address", func(t *testing.T) {
+		rr := create(`{"year": 2025, "germination": 80, "taste": 5, "orderId": 12, "imageIds": [4, 4, 9]}`)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+		if store.created.Province != "PA" || store.created.SeedID != 3 || len(store.created.ImageIDs) != 2 {
+			t.Errorf("unexpected report %+v", store.created)
+		}
+	})
+
+	for name, body := range map[string]string{
+		"without ratings or notes":      `{"year": 2025}`,
+		"with a rating out of range":    `{"year": 2025, "yield": 9}`,
+		"from the future":               `{"year": 2999, "taste": 3}`,
+		"with an unknown province":      `{"year": 2025, "taste": 3, "province": "Atlantide"}`,
+		"with an order of someone else": `{"year": 2025, "taste": 3, "orderId": 13}`,
+		"with photos of someone else":   `{"year": 2025, "taste": 3, "imageIds": [4, 120]}`,
+	} {
+		t.Run("should reject a report "+name, func(t *testing.T) {
+			if rr := create(body); rr.Code != http.StatusBadRequest {
+				t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+			}
+		})
+	}
+
+	t.Run("should hide a report after enough flags", func(t *testing.T) {
+		for i := 0; i < AutoHideFlags; i++ {
+			rr := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/grow-reports/{reportID}/flag", handler.handleFlagReport)
+			router.ServeHTTP(rr, request(http.MethodPost, "/grow-reports/1/flag", `{"reason": "spam"}`, 10+i))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected status code %d but got %d", http.StatusOK, rr.Code)
+			}
+			if store.hidden != (i == AutoHideFlags-1) {
+				t.Errorf("after %d flags hidden is %v", i+1, store.hidden)
+			}
+		}
+	})
+
+	t.Run("should not let authors flag their own report", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/grow-reports/{reportID}/flag", handler.handleFlagReport)
+		router.ServeHTTP(rr, request(http.MethodPost, "/grow-reports/1/flag", `{}`, 5))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should only let the author or curators delete a report", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/grow-reports/{reportID}", handler.handleDeleteReport)
+		router.ServeHTTP(rr, request(http.MethodDelete, "/grow-reports/1", "", 7))
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+}
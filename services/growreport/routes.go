@@ -0,0 +1,293 @@
+package growreport
+
+import (
+	"backend/seed-savers/geo"
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 50
+
+	// AutoHideFlags è il numero di segnalazioni che nasconde un resoconto in attesa di un curatore
+	AutoHideFlags = 3
+)
+
+type Handler struct {
+	store        types.GrowReportStore
+	seedStore    types.SeedStore
+	usersStore   types.UserStore
+	sessionStore *auth.AuthStore
+}
+
+func NewHandler(s types.GrowReportStore, seedStore types.SeedStore, us types.UserStore, sessionStore *auth.AuthStore) *Handler {
+	return &Handler{s, seedStore, us, sessionStore}
+}
+
+func (h *Handler) RegisterRouter(router *mux.Router) {
+	router.HandleFunc("/seeds/{seedID:[0-9]+}/grow-reports", h.handleGetReports).Methods("GET")
+	router.HandleFunc("/seeds/{seedID:[0-9]+}/grow-reports", auth.WithJWTAuth(h.handleCreateReport, h.usersStore, h.sessionStore)).Methods("POST")
+	router.HandleFunc("/grow-reports/{reportID:[0-9]+}", auth.WithJWTAuth(h.handleDeleteReport, h.usersStore, h.sessionStore)).Methods("DELETE")
+	router.HandleFunc("/grow-reports/{reportID:[0-9]+}/flag", auth.WithJWTAuth(h.handleFlagReport, h.usersStore, h.sessionStore)).Methods("POST")
+	router.HandleFunc("/admin/grow-reports/flagged", auth.WithRole(h.handleFlaggedReports, h.usersStore, h.sessionStore, types.RoleCurator, types.RoleAdmin)).Methods("GET")
+	router.HandleFunc("/admin/grow-reports/{reportID:[0-9]+}/hide", auth.WithRole(h.handleModerateReport(types.GrowReportHidden), h.usersStore, h.sessionStore, types.RoleCurator, types.RoleAdmin)).Methods("POST")
+	router.HandleFunc("/admin/grow-reports/{reportID:[0-9]+}/publish", auth.WithRole(h.handleModerateReport(types.GrowReportPublished), h.usersStore, h.sessionStore, types.RoleCurator, types.RoleAdmin)).Methods("POST")
+}
+
+// handleGetReports restituisce una pagina di resoconti con il riepilogo dei voti di tutti quelli pubblicati
+func (h *Handler) handleGetReports(w http.ResponseWriter, r *http.Request) {
+	seedID, _ := strconv.Atoi(mux.Vars(r)["seedID"])
+
+	page, limit, err := utils.ParsePage(r, DefaultPageSize, MaxPageSize)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	seed, err := h.seedStore.GetSeedByID(seedID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	reports, total, err := h.store.GetGrowReports(seed.ID, limit, (page-1)*limit)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	tallies, err := h.store.GetGrowTallies(seed.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	for i := range reports {
+		reports[i].Region = region(reports[i].Province)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.GrowReportPage{
+		Results: reports,
+		Summary: Summarize(tallies),
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+	})
+}
+
+func (h *Handler) handleCreateReport(w http.ResponseWriter, r *http.Request) {
+	seedID, _ := strconv.Atoi(mux.Vars(r)["seedID"])
+
+	payload, err := utils.DecodePayload[types.GrowReportPayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	seed, err := h.seedStore.GetSeedByID(seedID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if payload.Germination == nil && payload.Yield == nil && payload.DiseaseResistance == nil && payload.Taste == nil && payload.Notes == "" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("rate the variety or describe how it grew"))
+		return
+	}
+	if payload.Year > time.Now().Year() {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("year cannot be in the future"))
+		return
+	}
+
+	report := &types.GrowReport{
+		SeedID:            seed.ID,
+		UserID:            userID,
+		OrderID:           payload.OrderID,
+		Year:              payload.Year,
+		Germination:       payload.Germination,
+		Yield:             payload.Yield,
+		DiseaseResistance: payload.DiseaseResistance,
+		Taste:             payload.Taste,
+		Notes:             payload.Notes,
+		Status:            types.GrowReportPublished,
+		ImageIDs:          unique(payload.ImageIDs),
+	}
+
+	//senza provincia si usa quella dell'indirizzo, se c'è
+	if payload.Province != "" {
+		p, ok := geo.LookupProvince(payload.Province)
+		if !ok {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown province %q", payload.Province))
+			return
+		}
+		report.Province = p.Code
+	} else if postalCode, province, err := h.seedStore.GetUserArea(userID); err == nil {
+		if p, ok := geo.Locate(postalCode, province); ok {
+			report.Province = p.Code
+		}
+	}
+
+	if report.OrderID != 0 {
+		ok, err := h.store.OrderHasSeed(report.OrderID, userID, seed.ID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if !ok {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("order %d did not bring you this seed", report.OrderID))
+			return
+		}
+	}
+
+	//le foto devono essere immagini di questo seme caricate dall'autore
+	if len(report.ImageIDs) > 0 {
+		n, err := h.store.CountUserSeedImages(userID, seed.ID, report.ImageIDs)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if n != len(report.ImageIDs) {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("photos must be images of this seed you uploaded"))
+			return
+		}
+	}
+
+	if err := h.store.CreateGrowReport(report); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	report.Region = region(report.Province)
+	report.Photos = make([]string, 0, len(report.ImageIDs))
+	for _, id := range report.ImageIDs {
+		report.Photos = append(report.Photos, fmt.Sprintf("/images/%d", id))
+	}
+	utils.WriteJSON(w, http.StatusCreated, report)
+}
+
+func (h *Handler) handleDeleteReport(w http.ResponseWriter, r *http.Request) {
+	reportID, _ := strconv.Atoi(mux.Vars(r)["reportID"])
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	report, err := h.store.GetGrowReportByID(reportID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	//solo l'autore, i curatori e gli admin possono cancellarlo
+	if report.UserID != userID && !auth.HasRole(r.Context(), types.RoleCurator, types.RoleAdmin) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only the author, curators and admins can delete a grow report"))
+		return
+	}
+
+	if err := h.store.DeleteGrowReport(report.ID); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// handleFlagReport segnala un resoconto ai curatori; dopo AutoHideFlags segnalazioni viene nascosto
+// finché un curatore non lo ripubblica
+func (h *Handler) handleFlagReport(w http.ResponseWriter, r *http.Request) {
+	reportID, _ := strconv.Atoi(mux.Vars(r)["reportID"])
+
+	payload, err := utils.DecodePayload[types.GrowReportFlagPayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	report, err := h.store.GetGrowReportByID(reportID)
+	if err != nil || report.Status != types.GrowReportPublished {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("grow report not found"))
+		return
+	}
+	if report.UserID == userID {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you cannot flag your own grow report"))
+		return
+	}
+
+	flags, err := h.store.FlagGrowReport(report.ID, userID, payload.Reason)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if flags >= AutoHideFlags {
+		if err := h.store.SetGrowReportStatus(report.ID, types.GrowReportHidden); err != nil {
+			log.Printf("failed to hide grow report %d: %v", report.ID, err)
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]int{"flags": flags})
+}
+
+func (h *Handler) handleFlaggedReports(w http.ResponseWriter, r *http.Request) {
+	reports, err := h.store.GetFlaggedGrowReports()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, reports)
+}
+
+func (h *Handler) handleModerateReport(status string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reportID, _ := strconv.Atoi(mux.Vars(r)["reportID"])
+
+		if err := h.store.SetGrowReportStatus(reportID, status); err != nil {
+			utils.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, nil)
+	}
+}
+
+func region(province string) string {
+	if p, ok := geo.LookupProvince(province); ok {
+		return p.Region
+	}
+	return ""
+}
+
+func unique(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	out := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
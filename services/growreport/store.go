@@ -0,0 +1,292 @@
+package growreport
+
+import (
+	"backend/seed-savers/types"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// reportQuery legge i resoconti con nome dell'autore e numero di segnalazioni, da completare con WHERE
+const reportQuery = `SELECT r.report_id, r.seed_id, r.user_id, u.name, r.order_id, r.year, r.province,
+	r.germination, r.yield, r.disease_resistance, r.taste, r.notes, r.status, r.created_at,
+	(SELECT COUNT(*) FROM grow_report_flag f WHERE f.report_id = r.report_id) AS flags
+	FROM grow_report r INNER JOIN users u ON r.user_id = u.user_id `
+
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore crea e restituisce un nuovo oggetto Store con il database passato come parametro
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// CreateGrowReport salva il resoconto e lo collega alle foto indicate
+func (s *Store) CreateGrowReport(report *types.GrowReport) error {
+	// Inizia una transazione
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Assicura che il rollback venga eseguito in caso di errore
+
+	res, err := tx.Exec(`INSERT INTO grow_report (seed_id, user_id, order_id, year, province,
+		germination, yield, disease_resistance, taste, notes) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		report.SeedID, report.UserID, nullInt(report.OrderID), report.Year, nullString(report.Province),
+		report.Germination, report.Yield, report.DiseaseResistance, report.Taste, nullString(report.Notes))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	report.ID = int(id)
+
+	for _, imageID := range report.ImageIDs {
+		if _, err := tx.Exec("INSERT IGNORE INTO grow_report_image (report_id, image_id) VALUES (?, ?)", report.ID, imageID); err != nil {
+			return err
+		}
+	}
+
+	// Conferma la transazione
+	return tx.Commit()
+}
+
+// GetGrowReports restituisce una pagina dei resoconti pubblicati di un seme, dal più recente, e il totale
+func (s *Store) GetGrowReports(seedID, limit, offset int) ([]types.GrowReport, int, error) {
+	var total int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM grow_report WHERE seed_id = ? AND status = 'published'", seedID).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	reports, err := s.queryReports(reportQuery+"WHERE r.seed_id = ? AND r.status = 'published' ORDER BY r.created_at DESC, r.report_id DESC LIMIT ? OFFSET ?",
+		seedID, limit, offset)
+	return reports, total, err
+}
+
+func (s *Store) GetGrowReportByID(id int) (*types.GrowReport, error) {
+	reports, err := s.queryReports(reportQuery+"WHERE r.report_id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return nil, fmt.Errorf("grow report not found")
+	}
+	return &reports[0], nil
+}
+
+// GetFlaggedGrowReports restituisce i resoconti segnalati almeno una volta, dal più segnalato
+func (s *Store) GetFlaggedGrowReports() ([]types.GrowReport, error) {
+	return s.queryReports(reportQuery + `WHERE EXISTS (SELECT 1 FROM grow_report_flag f WHERE f.report_id = r.report_id)
+		ORDER BY flags DESC, r.created_at`)
+}
+
+func (s *Store) queryReports(query string, args ...any) ([]types.GrowReport, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := make([]types.GrowReport, 0)
+	for rows.Next() {
+		var r types.GrowReport
+		var name, province, notes sql.NullString
+		var orderID, germination, yield, resistance, taste sql.NullInt64
+		err := rows.Scan(&r.ID, &r.SeedID, &r.UserID, &name, &orderID, &r.Year, &province,
+			&germination, &yield, &resistance, &taste, &notes, &r.Status, &r.CreatedAt, &r.Flags)
+		if err != nil {
+			return nil, err
+		}
+		r.UserName, r.Province, r.Notes = name.String, province.String, notes.String
+		r.OrderID = int(orderID.Int64)
+		r.Germination, r.Yield, r.DiseaseResistance, r.Taste = intPtr(germination), intPtr(yield), intPtr(resistance), intPtr(taste)
+		r.Photos = []string{}
+		reports = append(reports, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reports, s.loadPhotos(reports)
+}
+
+// loadPhotos aggiunge le foto a tutti i resoconti con un'unica query
+func (s *Store) loadPhotos(reports []types.GrowReport) error {
+	if len(reports) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(reports))
+	args := make([]any, len(reports))
+	for i, r := range reports {
+		index[r.ID] = i
+		args[i] = r.ID
+	}
+
+	rows, err := s.db.Query(`SELECT ri.report_id, ri.image_id FROM grow_report_image ri
+		INNER JOIN seed_image i ON i.image_id = ri.image_id
+		WHERE ri.report_id IN (`+placeholders(len(reports))+`) ORDER BY i.position, i.image_id`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reportID, imageID int
+		if err := rows.Scan(&reportID, &imageID); err != nil {
+			return err
+		}
+		r := &reports[index[reportID]]
+		r.ImageIDs = append(r.ImageIDs, imageID)
+		r.Photos = append(r.Photos, fmt.Sprintf("/images/%d", imageID))
+	}
+	return rows.Err()
+}
+
+// GetGrowTallies somma i voti pubblicati di un seme per provincia, così la media per regione si
+// calcola senza leggere i singoli resoconti
+func (s *Store) GetGrowTallies(seedID int) ([]types.GrowTally, error) {
+	rows, err := s.db.Query(`SELECT COALESCE(province, ''), COUNT(*),
+		COALESCE(SUM(germination), 0), COUNT(germination),
+		COALESCE(SUM(yield), 0), COUNT(yield),
+		COALESCE(SUM(disease_resistance), 0), COUNT(disease_resistance),
+		COALESCE(SUM(taste), 0), COUNT(taste)
+		FROM grow_report WHERE seed_id = ? AND status = 'published'
+		GROUP BY province`, seedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tallies := make([]types.GrowTally, 0)
+	for rows.Next() {
+		var t types.GrowTally
+		err := rows.Scan(&t.Province, &t.Reports,
+			&t.Sums[0], &t.Counts[0], &t.Sums[1], &t.Counts[1],
+			&t.Sums[2], &t.Counts[2], &t.Sums[3], &t.Counts[3])
+		if err != nil {
+			return nil, err
+		}
+		tallies = append(tallies, t)
+	}
+
+	return tallies, rows.Err()
+}
+
+func (s *Store) DeleteGrowReport(id int) error {
+	res, err := s.db.Exec("DELETE FROM grow_report WHERE report_id = ?", id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res, "grow report not found")
+}
+
+// FlagGrowReport registra la segnalazione dell'utente (una sola per utente) e restituisce quante
+// segnalazioni ha ora il resoconto
+func (s *Store) FlagGrowReport(reportID, userID int, reason string) (int, error) {
+	_, err := s.db.Exec(`INSERT INTO grow_report_flag (report_id, user_id, reason) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE reason = VALUES(reason)`, reportID, userID, reason)
+	if err != nil {
+		return 0, err
+	}
+
+	var flags int
+	err = s.db.QueryRow("SELECT COUNT(*) FROM grow_report_flag WHERE report_id = ?", reportID).Scan(&flags)
+	return flags, err
+}
+
+// SetGrowReportStatus nasconde o pubblica un resoconto. Pubblicarlo chiude le segnalazioni ricevute
+func (s *Store) SetGrowReportStatus(id int, status string) error {
+	// Inizia una transazione
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Assicura che il rollback venga eseguito in caso di errore
+
+	res, err := tx.Exec("UPDATE grow_report SET status = ? WHERE report_id = ?", status, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM grow_report WHERE report_id = ?)", id).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("grow report not found")
+		}
+	}
+
+	if status == types.GrowReportPublished {
+		if _, err := tx.Exec("DELETE FROM grow_report_flag WHERE report_id = ?", id); err != nil {
+			return err
+		}
+	}
+
+	// Conferma la transazione
+	return tx.Commit()
+}
+
+// CountUserSeedImages conta quante delle immagini indicate sono foto di quel seme caricate dall'utente
+func (s *Store) CountUserSeedImages(userID, seedID int, imageIDs []int) (int, error) {
+	if len(imageIDs) == 0 {
+		return 0, nil
+	}
+
+	args := []any{userID, seedID}
+	for _, id := range imageIDs {
+		args = append(args, id)
+	}
+
+	var n int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM seed_image WHERE user_id = ? AND seed_id = ? AND image_id IN ("+placeholders(len(imageIDs))+")", args...).Scan(&n)
+	return n, err
+}
+
+// OrderHasSeed indica se l'utente ha ricevuto il seme con quell'ordine, che non deve essere stato annullato
+func (s *Store) OrderHasSeed(orderID, userID, seedID int) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM orders o INNER JOIN order_detail od ON o.order_id = od.order_id
+		WHERE o.order_id = ? AND o.reciver_user_id = ? AND od.seed_id = ? AND o.state <> 'Annullato')`, orderID, userID, seedID).Scan(&exists)
+	return exists, err
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+func nullInt(v int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(v), Valid: v != 0}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func intPtr(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	n := int(v.Int64)
+	return &n
+}
+
+func checkAffected(res sql.Result, msg string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package growreport
+
+import (
+	"backend/seed-savers/geo"
+	"backend/seed-savers/types"
+	"math"
+	"sort"
+)
+
+// Summarize calcola le medie dei voti di una varietà, in totale e per regione. I resoconti senza una
+// provincia riconoscibile contano nel totale ma non in nessuna regione
+func Summarize(tallies []types.GrowTally) types.GrowSummary {
+	var total types.GrowTally
+	regions := make(map[string]*types.GrowTally)
+	for _, t := range tallies {
+		merge(&total, t)
+
+		p, ok := geo.LookupProvince(t.Province)
+		if !ok {
+			continue
+		}
+		if regions[p.Region] == nil {
+			regions[p.Region] = &types.GrowTally{}
+		}
+		merge(regions[p.Region], t)
+	}
+
+	summary := types.GrowSummary{
+		Reports:     total.Reports,
+		GrowRatings: ratings(total),
+		Regions:     make([]types.RegionGrowSummary, 0, len(regions)),
+	}
+	for name, t := range regions {
+		summary.Regions = append(summary.Regions, types.RegionGrowSummary{Region: name, Reports: t.Reports, GrowRatings: ratings(*t)})
+	}
+	sort.Slice(summary.Regions, func(i, j int) bool {
+		a, b := summary.Regions[i], summary.Regions[j]
+		if a.Reports != b.Reports {
+			return a.Reports > b.Reports
+		}
+		return a.Region < b.Region
+	})
+
+	return summary
+}
+
+func merge(dst *types.GrowTally, t types.GrowTally) {
+	dst.Reports += t.Reports
+	for i := range t.Sums {
+		dst.Sums[i] += t.Sums[i]
+		dst.Counts[i] += t.Counts[i]
+	}
+}
+
+func ratings(t types.GrowTally) types.GrowRatings {
+	avg := func(i int) *float64 {
+		if t.Counts[i] == 0 {
+			return nil
+		}
+		// una cifra decimale basta per mostrare la media
+		v := math.Round(float64(t.Sums[i])/float64(t.Counts[i])*10) / 10
+		return &v
+	}
+
+	return types.GrowRatings{Germination: avg(0), Yield: avg(1), DiseaseResistance: avg(2), Taste: avg(3)}
+}
@@ -0,0 +1,118 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+const (
+	maxSide   = 2048
+	thumbSide = 320
+
+	// maxPixels limita le dimensioni dichiarate dal file: pochi KB di PNG possono chiedere gigabyte di memoria
+	maxPixels = 40_000_000
+)
+
+var allowedTypes = []string{"image/jpeg", "image/png"}
+
+type processed struct {
+	full        []byte
+	thumb       []byte
+	contentType string
+	extension   string
+}
+
+// process verifica il tipo reale del file e le sue dimensioni prima di decodificarlo, lo ricodifica (eliminando EXIF e ogni altro metadato,
+// compresa la posizione GPS) e genera la miniatura
+func process(data []byte) (*processed, error) {
+	mime := mimetype.Detect(data)
+	if !mimetype.EqualsAny(mime.String(), allowedTypes...) {
+		return nil, fmt.Errorf("unsupported file type %s, allowed: jpeg, png", mime.String())
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > maxPixels/cfg.Height {
+		return nil, fmt.Errorf("the image is %dx%d pixels, at most %d megapixels are allowed", cfg.Width, cfg.Height, maxPixels/1_000_000)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %w", err)
+	}
+
+	full, err := encode(fit(img, maxSide), mime.String())
+	if err != nil {
+		return nil, err
+	}
+
+	thumb, err := encode(fit(img, thumbSide), "image/jpeg")
+	if err != nil {
+		return nil, err
+	}
+
+	return &processed{full: full, thumb: thumb, contentType: mime.String(), extension: mime.Extension()}, nil
+}
+
+func encode(img image.Image, contentType string) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	var err error
+	if contentType == "image/png" {
+		err = png.Encode(buf, img)
+	} else {
+		err = jpeg.Encode(buf, img, &jpeg.Options{Quality: 85})
+	}
+	return buf.Bytes(), err
+}
+
+// fit riduce l'immagine in modo che il lato maggiore non superi side, mantenendo le proporzioni
+func fit(src image.Image, side int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= side && h <= side {
+		return src
+	}
+
+	if w >= h {
+		h = max(1, h*side/w)
+		w = side
+	} else {
+		w = max(1, w*side/h)
+		h = side
+	}
+	return resize(src, w, h)
+}
+
+// resize ridimensiona facendo la media dei pixel sorgente coperti da ogni pixel di destinazione
+func resize(src image.Image, w, h int) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := max(y0+1, b.Min.Y+(y+1)*b.Dy()/h)
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := max(x0+1, b.Min.X+(x+1)*b.Dx()/w)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,324 @@
+package image
+
+import (
+	"backend/seed-savers/config"
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/services/storage"
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxFilesPerUpload = 10
+	maxImagesPerSeed  = 20
+)
+
+type Handler struct {
+	store        types.SeedImageStore
+	seedStore    types.SeedStore
+	usersStore   types.UserStore
+	blobs        types.BlobStorage
+	sessionStore *auth.AuthStore
+}
+
+func NewHandler(s types.SeedImageStore, seedStore types.SeedStore, us types.UserStore, blobs types.BlobStorage, sessionStore *auth.AuthStore) *Handler {
+	return &Handler{s, seedStore, us, blobs, sessionStore}
+}
+
+func (h *Handler) RegisterRouter(router *mux.Router) {
+	router.HandleFunc("/seeds/{seedID}/images", h.handleGetSeedImages).Methods("GET")
+	router.HandleFunc("/seeds/{seedID}/images", auth.WithJWTAuth(h.handleUploadImages, h.usersStore, h.sessionStore)).Methods("POST")
+	router.HandleFunc("/seeds/{seedID}/images/order", auth.WithJWTAuth(h.handleReorderImages, h.usersStore, h.sessionStore)).Methods("PUT")
+	router.HandleFunc("/images/{imageID}", h.handleServeImage).Methods("GET")
+	router.HandleFunc("/images/{imageID}/thumb", h.handleServeThumb).Methods("GET")
+	router.HandleFunc("/images/{imageID}", auth.WithJWTAuth(h.handleDeleteImage, h.usersStore, h.sessionStore)).Methods("DELETE")
+}
+
+func (h *Handler) handleGetSeedImages(w http.ResponseWriter, r *http.Request) {
+	seedID, err := strconv.Atoi(mux.Vars(r)["seedID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	images, err := h.store.GetSeedImages(seedID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, images)
+}
+
+func (h *Handler) handleUploadImages(w http.ResponseWriter, r *http.Request) {
+	seedID, err := strconv.Atoi(mux.Vars(r)["seedID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	if _, err := h.seedStore.GetSeedByID(seedID); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	// solo chi ha il seme in inventario può fotografarlo
+	owns, err := h.store.UserHasSeed(userID, seedID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !owns {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("you can only add photos to seeds in your inventory"))
+		return
+	}
+
+	maxSize := config.Envs.MaxImageSizeMB << 20
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize*maxFilesPerUpload)
+	if err := r.ParseMultipartForm(maxSize); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid multipart form: %w", err))
+		return
+	}
+
+	files := r.MultipartForm.File["images"]
+	if len(files) == 0 || len(files) > maxFilesPerUpload {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("send between 1 and %d files in the 'images' field", maxFilesPerUpload))
+		return
+	}
+
+	existing, err := h.store.GetSeedImages(seedID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if len(existing)+len(files) > maxImagesPerSeed {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("a seed can have at most %d photos", maxImagesPerSeed))
+		return
+	}
+
+	// valida tutti i file prima di salvarne qualcuno
+	images := make([]*processed, 0, len(files))
+	for _, fh := range files {
+		if fh.Size > maxSize {
+			utils.WriteError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("%s is larger than %d MB", fh.Filename, config.Envs.MaxImageSizeMB))
+			return
+		}
+
+		f, err := fh.Open()
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		img, err := process(data)
+		if err != nil {
+			utils.WriteError(w, http.StatusUnsupportedMediaType, fmt.Errorf("%s: %w", fh.Filename, err))
+			return
+		}
+		images = append(images, img)
+	}
+
+	created := make([]types.SeedImage, 0, len(images))
+	for _, img := range images {
+		seedImage, err := h.saveImage(seedID, userID, img)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		created = append(created, *seedImage)
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, created)
+}
+
+func (h *Handler) saveImage(seedID, userID int, img *processed) (*types.SeedImage, error) {
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+
+	seedImage := &types.SeedImage{
+		SeedID:      seedID,
+		UserID:      userID,
+		StorageKey:  fmt.Sprintf("seeds/%d/%s%s", seedID, name, img.extension),
+		ThumbKey:    fmt.Sprintf("seeds/%d/%s_thumb.jpg", seedID, name),
+		ContentType: img.contentType,
+	}
+
+	if err := h.blobs.Put(seedImage.StorageKey, bytes.NewReader(img.full), img.contentType); err != nil {
+		return nil, err
+	}
+	if err := h.blobs.Put(seedImage.ThumbKey, bytes.NewReader(img.thumb), "image/jpeg"); err != nil {
+		h.blobs.Delete(seedImage.StorageKey)
+		return nil, err
+	}
+
+	if err := h.store.CreateSeedImage(seedImage); err != nil {
+		h.blobs.Delete(seedImage.StorageKey)
+		h.blobs.Delete(seedImage.ThumbKey)
+		return nil, err
+	}
+
+	seedImage.URL = fmt.Sprintf("/images/%d", seedImage.ID)
+	seedImage.ThumbURL = fmt.Sprintf("/images/%d/thumb", seedImage.ID)
+	return seedImage, nil
+}
+
+func (h *Handler) handleServeImage(w http.ResponseWriter, r *http.Request) {
+	h.serveImage(w, r, false)
+}
+
+func (h *Handler) handleServeThumb(w http.ResponseWriter, r *http.Request) {
+	h.serveImage(w, r, true)
+}
+
+func (h *Handler) serveImage(w http.ResponseWriter, r *http.Request, thumb bool) {
+	imageID, err := strconv.Atoi(mux.Vars(r)["imageID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	img, err := h.store.GetSeedImageByID(imageID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	key, contentType := img.StorageKey, img.ContentType
+	if thumb {
+		key, contentType = img.ThumbKey, "image/jpeg"
+	}
+
+	// le chiavi non vengono mai riutilizzate, quindi il contenuto è immutabile
+	etag := `"` + key + `"`
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	blob, err := h.blobs.Get(key)
+	if err == storage.ErrNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, blob); err != nil {
+		log.Printf("failed to serve image %d: %v", imageID, err)
+	}
+}
+
+func (h *Handler) handleReorderImages(w http.ResponseWriter, r *http.Request) {
+	seedID, err := strconv.Atoi(mux.Vars(r)["seedID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	payload, err := utils.DecodePayload[types.ReorderImagesPayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	owns, err := h.store.UserHasSeed(userID, seedID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !owns {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("permission denied"))
+		return
+	}
+
+	if err := h.store.ReorderSeedImages(seedID, payload.ImageIDs); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *Handler) handleDeleteImage(w http.ResponseWriter, r *http.Request) {
+	imageID, err := strconv.Atoi(mux.Vars(r)["imageID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	img, err := h.store.GetSeedImageByID(imageID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if img.UserID != userID {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only the uploader can delete this photo"))
+		return
+	}
+
+	if err := h.store.DeleteSeedImage(imageID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// i file orfani non sono un problema grave: si registra l'errore senza fallire la richiesta
+	for _, key := range []string{img.StorageKey, img.ThumbKey} {
+		if err := h.blobs.Delete(key); err != nil {
+			log.Printf("failed to delete blob %s: %v", key, err)
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,230 @@
+package image
+
+import (
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	stdimage "image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestImageServiceHandlers(t *testing.T) {
+	store := &mockImageStore{images: map[int]*types.SeedImage{}}
+	blobs := &memoryBlobs{objects: map[string][]byte{}}
+	handler := NewHandler(store, &mockSeedStore{}, nil, blobs, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/seeds/{seedID}/images", withUser(handler.handleUploadImages, 1)).Methods("POST")
+	router.HandleFunc("/images/{imageID}", handler.handleServeImage).Methods("GET")
+	router.HandleFunc("/images/{imageID}/thumb", handler.handleServeThumb).Methods("GET")
+
+	t.Run("should reject files that are not images", func(t *testing.T) {
+		req := uploadRequest(t, "/seeds/1/images", []byte("just some text"))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("expected status code %d but got %d", http.StatusUnsupportedMediaType, rr.Code)
+		}
+	})
+
+	t.Run("should reject images with too many pixels before decoding them", func(t *testing.T) {
+		req := uploadRequest(t, "/seeds/1/images", pngDeclaring(t, 50000, 50000))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusUnsupportedMediaType || !bytes.Contains(rr.Body.Bytes(), []byte("megapixels")) {
+			t.Errorf("expected the image to be refused for its size, got %d: %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("should store the photo without exif data and a thumbnail", func(t *testing.T) {
+		req := uploadRequest(t, "/seeds/1/images", jpegWithExif(t, 800, 400))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+
+		img := store.images[1]
+		if bytes.Contains(blobs.objects[img.StorageKey], []byte("Exif")) {
+			t.Errorf("stored image still contains exif data")
+		}
+
+		thumb, err := jpeg.Decode(bytes.NewReader(blobs.objects[img.ThumbKey]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := thumb.Bounds(); b.Dx() != thumbSide || b.Dy() != thumbSide/2 {
+			t.Errorf("expected a %dx%d thumbnail but got %dx%d", thumbSide, thumbSide/2, b.Dx(), b.Dy())
+		}
+	})
+
+	t.Run("should serve images with cache headers", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/images/1/thumb", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, rr.Code)
+		}
+		if rr.Header().Get("Content-Type") != "image/jpeg" || rr.Header().Get("Cache-Control") == "" {
+			t.Errorf("unexpected headers %v", rr.Header())
+		}
+
+		req.Header.Set("If-None-Match", rr.Header().Get("ETag"))
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNotModified {
+			t.Errorf("expected status code %d but got %d", http.StatusNotModified, rr.Code)
+		}
+	})
+
+	t.Run("should keep png uploads as png", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		png.Encode(buf, stdimage.NewRGBA(stdimage.Rect(0, 0, 10, 10)))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, uploadRequest(t, "/seeds/1/images", buf.Bytes()))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d", http.StatusCreated, rr.Code)
+		}
+		if store.images[2].ContentType != "image/png" {
+			t.Errorf("expected image/png but got %s", store.images[2].ContentType)
+		}
+	})
+}
+
+func withUser(next http.HandlerFunc, userID int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next(w, r.WithContext(context.WithValue(r.Context(), auth.UserKey, userID)))
+	}
+}
+
+func uploadRequest(t *testing.T, path string, data []byte) *http.Request {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	part, err := mw.CreateFormFile("images", "photo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	mw.Close()
+
+	req, err := http.NewRequest(http.MethodPost, path, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+// pngDeclaring restituisce un PNG di un pixel il cui header dichiara w×h pixel
+func pngDeclaring(t *testing.T, w, h int) []byte {
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, stdimage.NewGray(stdimage.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	// firma di 8 byte, poi lunghezza e tipo del chunk IHDR e i suoi 13 byte di dati seguiti dal CRC
+	binary.BigEndian.PutUint32(data[16:], uint32(w))
+	binary.BigEndian.PutUint32(data[20:], uint32(h))
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+// jpegWithExif encodes a jpeg and inserts an APP1 Exif segment right after the SOI marker
+func jpegWithExif(t *testing.T, w, h int) []byte {
+	img := stdimage.NewRGBA(stdimage.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		img.Set(x, x%h, color.RGBA{R: 200, A: 255})
+	}
+
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	payload := []byte("Exif\x00\x00GPS 45.07N 7.68E")
+	segment := []byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}
+	segment = append(segment, payload...)
+
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+type mockImageStore struct {
+	images map[int]*types.SeedImage
+}
+
+func (m *mockImageStore) CreateSeedImage(image *types.SeedImage) error {
+	image.ID = len(m.images) + 1
+	m.images[image.ID] = image
+	return nil
+}
+
+func (m *mockImageStore) GetSeedImages(seedID int) ([]types.SeedImage, error) {
+	return []types.SeedImage{}, nil
+}
+
+func (m *mockImageStore) GetSeedImageByID(id int) (*types.SeedImage, error) {
+	if img, ok := m.images[id]; ok {
+		return img, nil
+	}
+	return nil, fmt.Errorf("image not found")
+}
+
+func (m *mockImageStore) DeleteSeedImage(id int) error {
+	delete(m.images, id)
+	return nil
+}
+
+func (m *mockImageStore) ReorderSeedImages(seedID int, imageIDs []int) error {
+	return nil
+}
+
+func (m *mockImageStore) UserHasSeed(userID, seedID int) (bool, error) {
+	return true, nil
+}
+
+type mockSeedStore struct {
+	types.SeedStore
+}
+
+func (m *mockSeedStore) GetSeedByID(id int) (*types.Seed, error) {
+	return &types.Seed{ID: id}, nil
+}
+
+type memoryBlobs struct {
+	objects map[string][]byte
+}
+
+func (m *memoryBlobs) Put(key string, data io.Reader, contentType string) error {
+	b, err := io.ReadAll(data)
+	m.objects[key] = b
+	return err
+}
+
+func (m *memoryBlobs) Get(key string) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(m.objects[key])), nil
+}
+
+func (m *memoryBlobs) Delete(key string) error {
+	delete(m.objects, key)
+	return nil
+}
@@ -0,0 +1,149 @@
+package image
+
+import (
+	"backend/seed-savers/types"
+	"database/sql"
+	"fmt"
+)
+
+// Store gestisce l'accesso al database per le foto dei semi
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore crea e restituisce un nuovo oggetto Store con il database passato come parametro
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// CreateSeedImage salva una nuova foto mettendola in fondo all'ordinamento del seme
+func (s *Store) CreateSeedImage(image *types.SeedImage) error {
+	// Inizia una transazione
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Esegui rollback in caso di errore
+
+	// La posizione è calcolata nella stessa query così due upload contemporanei non si sovrappongono
+	res, err := tx.Exec(`INSERT INTO seed_image (seed_id, user_id, storage_key, thumb_key, content_type, position)
+		SELECT ?, ?, ?, ?, ?, COALESCE(MAX(position) + 1, 0) FROM seed_image WHERE seed_id = ?`,
+		image.SeedID, image.UserID, image.StorageKey, image.ThumbKey, image.ContentType, image.SeedID)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	image.ID = int(id)
+
+	// Conferma la transazione
+	return tx.Commit()
+}
+
+// GetSeedImages restituisce le foto di un seme nell'ordine scelto
+func (s *Store) GetSeedImages(seedID int) ([]types.SeedImage, error) {
+	rows, err := s.db.Query(`SELECT image_id, seed_id, user_id, storage_key, thumb_key, content_type, position, created_at
+		FROM seed_image WHERE seed_id = ? ORDER BY position, image_id`, seedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := make([]types.SeedImage, 0)
+	for rows.Next() {
+		image, err := ScanRowIntoSeedImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, *image)
+	}
+
+	return images, rows.Err()
+}
+
+// GetSeedImageByID restituisce una foto dato il suo ID
+func (s *Store) GetSeedImageByID(id int) (*types.SeedImage, error) {
+	rows, err := s.db.Query(`SELECT image_id, seed_id, user_id, storage_key, thumb_key, content_type, position, created_at
+		FROM seed_image WHERE image_id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, fmt.Errorf("image not found")
+	}
+
+	return ScanRowIntoSeedImage(rows)
+}
+
+// DeleteSeedImage elimina una foto dal database
+func (s *Store) DeleteSeedImage(id int) error {
+	_, err := s.db.Exec("DELETE FROM seed_image WHERE image_id = ?", id)
+	return err
+}
+
+// ReorderSeedImages assegna le posizioni seguendo l'ordine degli ID ricevuti
+func (s *Store) ReorderSeedImages(seedID int, imageIDs []int) error {
+	// Inizia una transazione
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Esegui rollback in caso di errore
+
+	for position, id := range imageIDs {
+		res, err := tx.Exec("UPDATE seed_image SET position = ? WHERE image_id = ? AND seed_id = ?", position, id, seedID)
+		if err != nil {
+			return err
+		}
+
+		// Un ID che non appartiene al seme annulla tutto il riordino
+		if n, _ := res.RowsAffected(); n == 0 {
+			var exists bool
+			if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM seed_image WHERE image_id = ? AND seed_id = ?)", id, seedID).Scan(&exists); err != nil {
+				return err
+			}
+			if !exists {
+				return fmt.Errorf("image %d does not belong to seed %d", id, seedID)
+			}
+		}
+	}
+
+	// Conferma la transazione
+	return tx.Commit()
+}
+
+// UserHasSeed indica se l'utente ha il seme nel proprio inventario
+func (s *Store) UserHasSeed(userID, seedID int) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users_seed WHERE user_id = ? AND seed_id = ?)", userID, seedID).Scan(&exists)
+	return exists, err
+}
+
+// ScanRowIntoSeedImage esegue il binding dei dati di una riga su un oggetto SeedImage
+func ScanRowIntoSeedImage(rows *sql.Rows) (*types.SeedImage, error) {
+	image := new(types.SeedImage)
+
+	err := rows.Scan(
+		&image.ID,
+		&image.SeedID,
+		&image.UserID,
+		&image.StorageKey,
+		&image.ThumbKey,
+		&image.ContentType,
+		&image.Position,
+		&image.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	image.URL = fmt.Sprintf("/images/%d", image.ID)
+	image.ThumbURL = fmt.Sprintf("/images/%d/thumb", image.ID)
+
+	return image, nil
+}
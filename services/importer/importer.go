@@ -0,0 +1,122 @@
+package importer
+
+import (
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// DefaultBatchSize è il numero di righe applicate in ogni transazione
+const DefaultBatchSize = 50
+
+func key(variety, vegetable string) string {
+	return utils.NormalizeName(vegetable) + "|" + utils.NormalizeName(variety)
+}
+
+// Prepare legge il file e lo confronta con il catalogo attuale, senza scrivere nulla: il report
+// restituito è quello della modalità di prova
+func Prepare(store types.ImportStore, r io.Reader, format string, mapping map[string]string) ([]types.ImportRow, *types.ImportReport, error) {
+	rows, parseErrors, err := Parse(r, format, mapping)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	catalog, err := store.GetCatalog()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	valid, report := Plan(rows, catalog, parseErrors)
+	return valid, report, nil
+}
+
+// Plan confronta le righe con il catalogo: le varietà già presenti vengono collegate al seme esistente
+// (SeedID), le altre saranno create. Una varietà ripetuta nello stesso file è un errore, per non
+// sommare due volte la stessa scorta per sbaglio
+func Plan(rows []types.ImportRow, catalog []types.Seed, parseErrors []types.ImportRowResult) ([]types.ImportRow, *types.ImportReport) {
+	existing := make(map[string]int, len(catalog))
+	for _, s := range catalog {
+		existing[key(s.Variety_name, s.Vegetable)] = s.ID
+	}
+
+	report := &types.ImportReport{Rows: make([]types.ImportRowResult, 0, len(rows)+len(parseErrors))}
+	report.Rows = append(report.Rows, parseErrors...)
+	report.Errors = len(parseErrors)
+
+	valid := make([]types.ImportRow, 0, len(rows))
+	seen := make(map[string]int, len(rows))
+	for _, row := range rows {
+		result := types.ImportRowResult{Line: row.Line, Variety_name: row.Variety_name, Vegetable: row.Vegetable}
+		k := key(row.Variety_name, row.Vegetable)
+
+		if first, ok := seen[k]; ok {
+			result.Status = types.ImportError
+			result.Error = fmt.Sprintf("same variety as line %d", first)
+			report.Errors++
+			report.Rows = append(report.Rows, result)
+			continue
+		}
+		seen[k] = row.Line
+
+		if id, ok := existing[k]; ok {
+			row.SeedID = id
+			result.Status = types.ImportMatched
+			result.SeedID = id
+			report.Matched++
+		} else {
+			result.Status = types.ImportNew
+			report.New++
+		}
+		report.Rows = append(report.Rows, result)
+		valid = append(valid, row)
+	}
+
+	sort.Slice(report.Rows, func(i, j int) bool { return report.Rows[i].Line < report.Rows[j].Line })
+	return valid, report
+}
+
+// Apply scrive le righe valide a blocchi di batchSize, ognuno in una transazione. Dopo ogni blocco
+// chiama progress con il numero di righe elaborate e aggiorna nel report l'ID dei semi creati.
+// Se un blocco fallisce i blocchi precedenti restano applicati
+func Apply(store types.ImportStore, rows []types.ImportRow, report *types.ImportReport, userID, batchSize int, progress func(int)) error {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	lines := make(map[int]int, len(report.Rows))
+	for i, r := range report.Rows {
+		lines[r.Line] = i
+	}
+
+	for start := 0; start < len(rows); start += batchSize {
+		end := min(start+batchSize, len(rows))
+		batch := rows[start:end]
+
+		if err := store.ApplyImportBatch(batch, userID); err != nil {
+			return fmt.Errorf("rows %d-%d: %v", batch[0].Line, batch[len(batch)-1].Line, err)
+		}
+
+		for _, row := range batch {
+			report.Rows[lines[row.Line]].SeedID = row.SeedID
+		}
+		if progress != nil {
+			progress(end)
+		}
+	}
+
+	return nil
+}
+
+// NotifyAvailable avvisa notifier delle righe applicate che hanno aggiunto una scorta all'inventario
+func NotifyAvailable(notifier types.AvailabilityNotifier, rows []types.ImportRow, userID int) {
+	if notifier == nil {
+		return
+	}
+	for _, row := range rows {
+		if row.Quantity > 0 {
+			notifier.SeedAvailable(row.SeedID, userID)
+		}
+	}
+}
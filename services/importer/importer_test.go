@@ -0,0 +1,162 @@
+package importer
+
+import (
+	"backend/seed-savers/types"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockImportStore struct {
+	catalog []types.Seed
+	batches [][]types.ImportRow
+	nextID  int
+	failAt  int
+}
+
+func (m *mockImportStore) GetCatalog() ([]types.Seed, error) {
+	return m.catalog, nil
+}
+
+func (m *mockImportStore) ApplyImportBatch(rows []types.ImportRow, userID int) error {
+	if m.failAt > 0 && len(m.batches)+1 == m.failAt {
+		return fmt.Errorf("db down")
+	}
+	for i := range rows {
+		if rows[i].SeedID == 0 {
+			m.nextID++
+			rows[i].SeedID = m.nextID
+		}
+	}
+	m.batches = append(m.batches, rows)
+	return nil
+}
+
+func TestParseCSVWithMapping(t *testing.T) {
+	data := "\ufeffVarietà,Ortaggio,Quantità\n" +
+		"San Marzano,Pomodoro,10\n" +
+		",Fagiolo,3\n" +
+		"Borlotto,Fagiolo,tanti\n"
+
+	rows, errs, err := Parse(strings.NewReader(data), "csv", map[string]string{
+		"variety_name": "Varietà",
+		"vegetable":    "Ortaggio",
+		"quantity":     "quantità",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 1 || rows[0].Variety_name != "San Marzano" || rows[0].Quantity != 10 || rows[0].Line != 2 {
+		t.Errorf("unexpected rows %+v", rows)
+	}
+	if len(errs) != 2 || errs[0].Line != 3 || errs[1].Line != 4 {
+		t.Errorf("expected errors on lines 3 and 4, got %+v", errs)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	data := `[{"variety_name": "Cuore di bue", "vegetable": "pomodoro", "quantity": 5, "description": null}]`
+
+	rows, errs, err := Parse(strings.NewReader(data), "json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 0 || len(rows) != 1 || rows[0].Quantity != 5 {
+		t.Errorf("unexpected result %+v %+v", rows, errs)
+	}
+
+	if _, _, err := Parse(strings.NewReader(data), "xlsx", nil); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+	if _, _, err := Parse(strings.NewReader(data), "json", map[string]string{"colour": "x"}); err == nil {
+		t.Error("expected an error for an unknown mapped field")
+	}
+}
+
+func TestPlan(t *testing.T) {
+	catalog := []types.Seed{{ID: 7, Variety_name: "san marzano", Vegetable: "Pomodoro"}}
+	rows := []types.ImportRow{
+		{Line: 2, Variety_name: "San  Marzano", Vegetable: "pomodoro"},
+		{Line: 3, Variety_name: "Borlotto", Vegetable: "fagiolo"},
+		{Line: 5, Variety_name: "borlotto", Vegetable: "Fagiolo"},
+	}
+	parseErrors := []types.ImportRowResult{{Line: 4, Status: types.ImportError, Error: "missing vegetable"}}
+
+	valid, report := Plan(rows, catalog, parseErrors)
+
+	if report.New != 1 || report.Matched != 1 || report.Errors != 2 {
+		t.Errorf("unexpected counts %+v", report)
+	}
+	if len(valid) != 2 || valid[0].SeedID != 7 || valid[1].SeedID != 0 {
+		t.Errorf("unexpected valid rows %+v", valid)
+	}
+	for i, line := range []int{2, 3, 4, 5} {
+		if report.Rows[i].Line != line {
+			t.Errorf("report rows must be ordered by line, got %+v", report.Rows)
+			break
+		}
+	}
+}
+
+func TestApplyInBatches(t *testing.T) {
+	store := &mockImportStore{nextID: 100}
+	rows := make([]types.ImportRow, 5)
+	report := &types.ImportReport{}
+	for i := range rows {
+		rows[i] = types.ImportRow{Line: i + 2, Variety_name: fmt.Sprint("v", i), Vegetable: "fagiolo", Quantity: 1}
+		report.Rows = append(report.Rows, types.ImportRowResult{Line: i + 2, Status: types.ImportNew})
+	}
+
+	progress := []int{}
+	if err := Apply(store, rows, report, 1, 2, func(n int) { progress = append(progress, n) }); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(store.batches) != 3 || fmt.Sprint(progress) != "[2 4 5]" {
+		t.Errorf("expected 3 batches with progress [2 4 5], got %d batches and %v", len(store.batches), progress)
+	}
+	if report.Rows[4].SeedID != 105 {
+		t.Errorf("the report must contain the ID of created seeds, got %+v", report.Rows[4])
+	}
+
+	store = &mockImportStore{failAt: 2}
+	if err := Apply(store, rows, report, 1, 2, nil); err == nil || !strings.Contains(err.Error(), "rows 4-5") {
+		t.Errorf("expected an error naming the failed rows, got %v", err)
+	}
+}
+
+func TestJobsStart(t *testing.T) {
+	jobs := NewJobs()
+	rows := []types.ImportRow{{Line: 2, Variety_name: "borlotto", Vegetable: "fagiolo", Quantity: 1}}
+	report := &types.ImportReport{Rows: []types.ImportRowResult{{Line: 2, Status: types.ImportNew}}}
+
+	job := jobs.Start(&mockImportStore{}, rows, report, 1, 10, nil)
+	if job.Status != JobRunning || job.Total != 1 {
+		t.Errorf("expected the initial state of the job, got %+v", job)
+	}
+
+	// la copia restituita non cambia mentre l'import procede
+	deadline := time.Now().Add(time.Second)
+	for {
+		current, ok := jobs.Get(job.ID, 1)
+		if !ok {
+			t.Fatal("expected the job to be found")
+		}
+		if current.Status == JobDone {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the job did not finish, got %+v", current)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if job.Status != JobRunning || job.FinishedAt != nil {
+		t.Errorf("the returned copy must not follow the job, got %+v", job)
+	}
+
+	if _, ok := jobs.Get(job.ID, 2); ok {
+		t.Error("other users must not see the job")
+	}
+}
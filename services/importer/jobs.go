@@ -0,0 +1,96 @@
+package importer
+
+import (
+	"backend/seed-savers/types"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const (
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// jobRetention è quanto a lungo un import concluso resta consultabile
+const jobRetention = 24 * time.Hour
+
+// Jobs tiene in memoria gli import in corso e quelli conclusi di recente. Un riavvio del server
+// perde solo lo stato di avanzamento: i blocchi già applicati restano nel database
+type Jobs struct {
+	mu   sync.Mutex
+	jobs map[string]*types.ImportJob
+}
+
+func NewJobs() *Jobs {
+	return &Jobs{jobs: make(map[string]*types.ImportJob)}
+}
+
+// Start registra un nuovo import, lo esegue in background e ne restituisce una copia dello stato
+// iniziale. Alla fine notifier (se presente) viene avvisato delle scorte aggiunte, anche quando
+// l'import si è fermato a metà
+func (j *Jobs) Start(store types.ImportStore, rows []types.ImportRow, report *types.ImportReport, userID, batchSize int, notifier types.AvailabilityNotifier) types.ImportJob {
+	id := make([]byte, 12)
+	rand.Read(id)
+
+	job := &types.ImportJob{
+		ID:        hex.EncodeToString(id),
+		UserID:    userID,
+		Status:    JobRunning,
+		Total:     len(rows),
+		StartedAt: time.Now(),
+	}
+
+	j.mu.Lock()
+	j.cleanup()
+	j.jobs[job.ID] = job
+	started := *job
+	j.mu.Unlock()
+
+	go func() {
+		applied := 0
+		err := Apply(store, rows, report, userID, batchSize, func(processed int) {
+			applied = processed
+			j.mu.Lock()
+			job.Processed = processed
+			j.mu.Unlock()
+		})
+		NotifyAvailable(notifier, rows[:applied], userID)
+
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		now := time.Now()
+		job.FinishedAt = &now
+		job.Report = report
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+			return
+		}
+		job.Status = JobDone
+	}()
+
+	return started
+}
+
+// Get restituisce una copia dello stato dell'import, solo se appartiene all'utente
+func (j *Jobs) Get(id string, userID int) (types.ImportJob, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	job, ok := j.jobs[id]
+	if !ok || job.UserID != userID {
+		return types.ImportJob{}, false
+	}
+	return *job, true
+}
+
+func (j *Jobs) cleanup() {
+	for id, job := range j.jobs {
+		if job.FinishedAt != nil && time.Since(*job.FinishedAt) > jobRetention {
+			delete(j.jobs, id)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package importer
+
+import (
+	"backend/seed-savers/types"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Fields sono i campi del catalogo che si possono importare
+var Fields = []string{"variety_name", "vegetable", "description", "image", "quantity"}
+
+// DefaultMapping associa ogni campo alla colonna con lo stesso nome
+func DefaultMapping() map[string]string {
+	mapping := make(map[string]string, len(Fields))
+	for _, f := range Fields {
+		mapping[f] = f
+	}
+	return mapping
+}
+
+// Parse legge un file CSV (con intestazione) o JSON (array di oggetti) e traduce ogni riga nei
+// campi del catalogo usando la mappatura campo -> colonna. Le righe non valide finiscono tra gli errori
+func Parse(r io.Reader, format string, mapping map[string]string) ([]types.ImportRow, []types.ImportRowResult, error) {
+	full := DefaultMapping()
+	for field, column := range mapping {
+		if _, ok := full[field]; !ok {
+			return nil, nil, fmt.Errorf("unknown field %q in mapping", field)
+		}
+		full[field] = column
+	}
+
+	var records []map[string]string
+	var err error
+	switch format {
+	case "csv":
+		records, err = readCSV(r)
+	case "json":
+		records, err = readJSON(r)
+	default:
+		return nil, nil, fmt.Errorf("unsupported format %q, use csv or json", format)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows := make([]types.ImportRow, 0, len(records))
+	errors := make([]types.ImportRowResult, 0)
+	for i, record := range records {
+		row, err := toRow(record, full)
+		// la riga 1 del CSV è l'intestazione
+		if format == "csv" {
+			row.Line = i + 2
+		} else {
+			row.Line = i + 1
+		}
+		if err != nil {
+			errors = append(errors, types.ImportRowResult{
+				Line:         row.Line,
+				Status:       types.ImportError,
+				Variety_name: row.Variety_name,
+				Vegetable:    row.Vegetable,
+				Error:        err.Error(),
+			})
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, errors, nil
+}
+
+func toRow(record map[string]string, mapping map[string]string) (types.ImportRow, error) {
+	get := func(field string) string {
+		return strings.TrimSpace(record[strings.ToLower(mapping[field])])
+	}
+
+	row := types.ImportRow{
+		Variety_name: get("variety_name"),
+		Vegetable:    get("vegetable"),
+		Description:  get("description"),
+		Image:        get("image"),
+	}
+	if row.Variety_name == "" {
+		return row, fmt.Errorf("missing variety name")
+	}
+	if row.Vegetable == "" {
+		return row, fmt.Errorf("missing vegetable")
+	}
+	if len(row.Variety_name) > 100 || len(row.Vegetable) > 100 {
+		return row, fmt.Errorf("variety and vegetable must be at most 100 characters")
+	}
+
+	if q := get("quantity"); q != "" {
+		quantity, err := strconv.Atoi(q)
+		if err != nil || quantity < 0 {
+			return row, fmt.Errorf("invalid quantity %q", q)
+		}
+		row.Quantity = quantity
+	}
+
+	return row, nil
+}
+
+func readCSV(r io.Reader) ([]map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	// i fogli di calcolo esportano spesso righe con un numero variabile di colonne
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("the file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff")))
+	}
+
+	records := make([]map[string]string, 0)
+	for {
+		line, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		record := make(map[string]string, len(header))
+		for i, value := range line {
+			if i < len(header) {
+				record[header[i]] = value
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func readJSON(r io.Reader) ([]map[string]string, error) {
+	var raw []map[string]any
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON, expected an array of objects: %v", err)
+	}
+
+	records := make([]map[string]string, 0, len(raw))
+	for _, item := range raw {
+		record := make(map[string]string, len(item))
+		for key, value := range item {
+			switch v := value.(type) {
+			case nil:
+			case string:
+				record[strings.ToLower(key)] = v
+			case float64:
+				record[strings.ToLower(key)] = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				record[strings.ToLower(key)] = fmt.Sprint(v)
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"backend/seed-savers/config"
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const maxImportSize = 10 << 20
+
+type Handler struct {
+	store        types.ImportStore
+	jobs         *Jobs
+	notifier     types.AvailabilityNotifier
+	usersStore   types.UserStore
+	sessionStore *auth.AuthStore
+}
+
+func NewHandler(s types.ImportStore, notifier types.AvailabilityNotifier, us types.UserStore, sessionStore *auth.AuthStore) *Handler {
+	return &Handler{s, NewJobs(), notifier, us, sessionStore}
+}
+
+func (h *Handler) RegisterRouter(router *mux.Router) {
+	router.HandleFunc("/import", auth.WithVerifiedEmail(h.handleImport, h.usersStore, h.sessionStore)).Methods("POST")
+	router.HandleFunc("/import/jobs/{jobID:[0-9a-f]+}", auth.WithJWTAuth(h.handleJob, h.usersStore, h.sessionStore)).Methods("GET")
+}
+
+// handleImport riceve un form multipart con il file ('file'), il formato ('format', altrimenti
+// dedotto dall'estensione), la mappatura opzionale campo -> colonna in JSON ('mapping') e la
+// modalità ('mode': dry-run, il default, oppure apply)
+func (h *Handler) handleImport(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid multipart form: %w", err))
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing 'file' field"))
+		return
+	}
+	defer file.Close()
+
+	format := strings.ToLower(r.FormValue("format"))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+
+	var mapping map[string]string
+	if m := r.FormValue("mapping"); m != "" {
+		if err := json.Unmarshal([]byte(m), &mapping); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid mapping: %v", err))
+			return
+		}
+	}
+
+	mode := r.FormValue("mode")
+	if mode != "" && mode != "dry-run" && mode != "apply" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("mode must be dry-run or apply"))
+		return
+	}
+
+	rows, report, err := Prepare(h.store, file, format, mapping)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if mode != "apply" {
+		utils.WriteJSON(w, http.StatusOK, report)
+		return
+	}
+
+	job := h.jobs.Start(h.store, rows, report, userID, int(config.Envs.ImportBatchSize), h.notifier)
+	utils.WriteJSON(w, http.StatusAccepted, map[string]any{
+		"jobId":  job.ID,
+		"status": job.Status,
+		"total":  job.Total,
+	})
+}
+
+func (h *Handler) handleJob(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	job, ok := h.jobs.Get(mux.Vars(r)["jobID"], userID)
+	if !ok {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("import job not found"))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,85 @@
+package importer
+
+import (
+	"backend/seed-savers/types"
+	"database/sql"
+	"strings"
+)
+
+// Store gestisce l'accesso al database per l'importazione massiva
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore crea e restituisce un nuovo oggetto Store con il database passato come parametro
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// GetCatalog restituisce varietà e ortaggio di tutti i semi, usati per riconoscere quelli già presenti
+func (s *Store) GetCatalog() ([]types.Seed, error) {
+	rows, err := s.db.Query("SELECT seed_id, variety_name, vegetable FROM seed")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seeds := make([]types.Seed, 0)
+	for rows.Next() {
+		var seed types.Seed
+		if err := rows.Scan(&seed.ID, &seed.Variety_name, &seed.Vegetable); err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, seed)
+	}
+
+	return seeds, rows.Err()
+}
+
+// ApplyImportBatch crea i semi nuovi del blocco e somma le quantità all'inventario dell'utente,
+// registrando una partita per ogni scorta aggiunta. Tutto il blocco è applicato in una transazione
+func (s *Store) ApplyImportBatch(rows []types.ImportRow, userID int) error {
+	// Inizia una transazione
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Assicura che il rollback venga eseguito in caso di errore
+
+	for i := range rows {
+		row := &rows[i]
+
+		if row.SeedID == 0 {
+			res, err := tx.Exec("INSERT INTO seed (description, variety_name, vegetable, img, created_by) VALUES (?, ?, ?, ?, ?)",
+				row.Description, strings.ToLower(row.Variety_name), row.Vegetable, row.Image, userID)
+			if err != nil {
+				return err
+			}
+			id, err := res.LastInsertId()
+			if err != nil {
+				return err
+			}
+			row.SeedID = int(id)
+		}
+
+		// una riga senza quantità aggiunge solo la varietà al catalogo
+		if row.Quantity == 0 {
+			continue
+		}
+
+		_, err = tx.Exec(`INSERT INTO users_seed (user_id, seed_id, quantity, restocked_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+			ON DUPLICATE KEY UPDATE quantity = quantity + VALUES(quantity), restocked_at = CURRENT_TIMESTAMP`, userID, row.SeedID, row.Quantity)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`INSERT INTO seed_lot (seed_id, user_id, source, quantity, province)
+			SELECT ?, ?, 'registered', ?, (SELECT province FROM adress WHERE user_id = ? AND is_default)`, row.SeedID, userID, row.Quantity, userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Conferma la transazione
+	return tx.Commit()
+}
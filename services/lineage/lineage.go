@@ -0,0 +1,159 @@
+package lineage
+
+import (
+	"backend/seed-savers/geo"
+	"backend/seed-savers/types"
+	"sort"
+)
+
+// BuildTree costruisce l'albero genealogico delle partite di un utente: gli antenati fino
+// alle partite di origine e tutte le partite discese da quelle dell'utente
+func BuildTree(lots []types.SeedLot, userID int) []*types.LineageNode {
+	byID := make(map[int]*types.SeedLot, len(lots))
+	children := make(map[int][]int)
+	for i := range lots {
+		byID[lots[i].ID] = &lots[i]
+		if lots[i].ParentID != 0 {
+			children[lots[i].ParentID] = append(children[lots[i].ParentID], lots[i].ID)
+		}
+	}
+
+	// Le partite da mostrare sono quelle dell'utente, i loro antenati e i loro discendenti
+	include := make(map[int]bool)
+	for _, lot := range lots {
+		if lot.UserID == 0 || lot.UserID != userID {
+			continue
+		}
+
+		for id := lot.ID; id != 0 && !include[id]; {
+			include[id] = true
+			parent, ok := byID[id]
+			if !ok {
+				break
+			}
+			id = parent.ParentID
+		}
+
+		stack := []int{lot.ID}
+		for len(stack) > 0 {
+			id := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			include[id] = true
+			stack = append(stack, children[id]...)
+		}
+	}
+
+	nodes := make(map[int]*types.LineageNode, len(include))
+	for id := range include {
+		if lot, ok := byID[id]; ok {
+			nodes[id] = &types.LineageNode{SeedLot: *lot, Children: []*types.LineageNode{}}
+		}
+	}
+
+	roots := make([]*types.LineageNode, 0)
+	for _, node := range nodes {
+		if parent, ok := nodes[node.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	sortNodes(roots)
+	return roots
+}
+
+// Redact toglie dalle partite nascoste chi le coltiva e dove, lasciandole nell'albero
+// perché la discendenza delle altre resti intera
+func Redact(lots []types.SeedLot) {
+	for i := range lots {
+		if lots[i].Hidden {
+			lots[i].UserID = 0
+			lots[i].UserName = ""
+			lots[i].Province = ""
+		}
+	}
+}
+
+func sortNodes(nodes []*types.LineageNode) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	for _, n := range nodes {
+		sortNodes(n.Children)
+	}
+}
+
+// FeatureCollection è il sottoinsieme di GeoJSON usato per i percorsi delle varietà
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+type Feature struct {
+	Type       string         `json:"type"`
+	Geometry   Geometry       `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type Geometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// BuildGeoJSON trasforma l'albero in un percorso GeoJSON: un punto per ogni partita e una
+// linea per ogni ramo dall'origine alle foglie. Le coordinate sono quelle del capoluogo di
+// provincia, quindi l'indirizzo esatto dei coltivatori non viene mai esposto
+func BuildGeoJSON(roots []*types.LineageNode) FeatureCollection {
+	fc := FeatureCollection{Type: "FeatureCollection", Features: []Feature{}}
+
+	var walk func(node *types.LineageNode, path [][2]float64)
+	walk = func(node *types.LineageNode, path [][2]float64) {
+		province, ok := geo.LookupProvince(node.Province)
+		if ok {
+			point := [2]float64{province.Lon, province.Lat}
+			fc.Features = append(fc.Features, Feature{
+				Type:     "Feature",
+				Geometry: Geometry{Type: "Point", Coordinates: point},
+				Properties: map[string]any{
+					"lotId":    node.ID,
+					"grower":   node.UserName,
+					"province": province.Name,
+					"region":   province.Region,
+					"source":   node.Source,
+					"year":     lotYear(node.SeedLot),
+				},
+			})
+
+			if len(path) == 0 || path[len(path)-1] != point {
+				path = append(append([][2]float64{}, path...), point)
+			}
+		}
+
+		if len(node.Children) == 0 {
+			if len(path) > 1 {
+				fc.Features = append(fc.Features, Feature{
+					Type:       "Feature",
+					Geometry:   Geometry{Type: "LineString", Coordinates: path},
+					Properties: map[string]any{"toLotId": node.ID},
+				})
+			}
+			return
+		}
+
+		for _, child := range node.Children {
+			walk(child, path)
+		}
+	}
+
+	for _, root := range roots {
+		walk(root, nil)
+	}
+
+	return fc
+}
+
+func lotYear(lot types.SeedLot) int {
+	if lot.HarvestYear > 0 {
+		return lot.HarvestYear
+	}
+	return lot.CreatedAt.Year()
+}
@@ -0,0 +1,98 @@
+package lineage
+
+import (
+	"backend/seed-savers/types"
+	"testing"
+	"time"
+)
+
+func TestBuildTree(t *testing.T) {
+	year := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	lots := []types.SeedLot{
+		{ID: 1, UserID: 10, Source: types.LotRegistered, Province: "TO", CreatedAt: year},
+		{ID: 2, UserID: 20, ParentID: 1, Source: types.LotExchange, Province: "Bologna", CreatedAt: year},
+		{ID: 3, UserID: 30, ParentID: 1, Source: types.LotExchange, Province: "NA", CreatedAt: year},
+		{ID: 4, UserID: 20, ParentID: 2, Source: types.LotGrowOut, Province: "BO", HarvestYear: 2023, CreatedAt: year},
+		{ID: 5, UserID: 40, ParentID: 4, Source: types.LotExchange, Province: "PA", CreatedAt: year},
+	}
+
+	tree := BuildTree(lots, 20)
+	if len(tree) != 1 || tree[0].ID != 1 {
+		t.Fatalf("expected the origin lot as the only root, got %+v", tree)
+	}
+
+	root := tree[0]
+	if len(root.Children) != 1 || root.Children[0].ID != 2 {
+		t.Fatalf("lots of other branches must not be included, got %+v", root.Children)
+	}
+
+	growOut := root.Children[0].Children[0]
+	if growOut.ID != 4 || len(growOut.Children) != 1 || growOut.Children[0].ID != 5 {
+		t.Errorf("expected the descendants of the user's lots, got %+v", growOut)
+	}
+
+	if tree := BuildTree(lots, 99); len(tree) != 0 {
+		t.Errorf("expected an empty tree for a user without lots, got %+v", tree)
+	}
+}
+
+func TestBuildGeoJSON(t *testing.T) {
+	lots := []types.SeedLot{
+		{ID: 1, UserID: 10, Province: "TO"},
+		{ID: 2, UserID: 20, ParentID: 1, Province: "BO"},
+		{ID: 3, UserID: 20, ParentID: 2, Province: "Bologna"},
+	}
+
+	fc := BuildGeoJSON(BuildTree(lots, 20))
+
+	var points, lines int
+	for _, f := range fc.Features {
+		switch f.Geometry.Type {
+		case "Point":
+			points++
+		case "LineString":
+			lines++
+			// Torino -> Bologna: la seconda partita a Bologna non aggiunge un punto al percorso
+			if path := f.Geometry.Coordinates.([][2]float64); len(path) != 2 {
+				t.Errorf("expected a path of 2 points but got %v", path)
+			}
+		}
+	}
+
+	if points != 3 || lines != 1 {
+		t.Errorf("expected 3 points and 1 line but got %d and %d", points, lines)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	lots := []types.SeedLot{
+		{ID: 1, UserID: 10, UserName: "Anna", Province: "TO", Hidden: true},
+		{ID: 2, UserID: 20, UserName: "Bruno", ParentID: 1, Province: "BO"},
+		{ID: 3, UserID: 30, UserName: "Carla", ParentID: 2, Province: "NA", Hidden: true},
+	}
+
+	Redact(lots)
+
+	for _, lot := range lots {
+		if lot.Hidden && (lot.UserID != 0 || lot.UserName != "" || lot.Province != "") {
+			t.Errorf("expected the grower of lot %d to be redacted, got %+v", lot.ID, lot)
+		}
+	}
+	if lots[1].UserName != "Bruno" || lots[1].Province != "BO" {
+		t.Errorf("visible growers must be kept, got %+v", lots[1])
+	}
+
+	// l'albero resta intero attorno al coltivatore visibile
+	tree := BuildTree(lots, 20)
+	if len(tree) != 1 || tree[0].ID != 1 || tree[0].Children[0].Children[0].ID != 3 {
+		t.Errorf("expected the hidden lots to stay in the tree, got %+v", tree)
+	}
+
+	// un coltivatore nascosto non si può cercare per id
+	if tree := BuildTree(lots, 10); len(tree) != 0 {
+		t.Errorf("expected no tree for a hidden grower, got %+v", tree)
+	}
+	if tree := BuildTree(lots, 0); len(tree) != 0 {
+		t.Errorf("expected no tree for redacted lots, got %+v", tree)
+	}
+}
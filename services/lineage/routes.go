@@ -0,0 +1,111 @@
+package lineage
+
+import (
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store        types.LotStore
+	seedStore    types.SeedStore
+	usersStore   types.UserStore
+	sessionStore *auth.AuthStore
+}
+
+func NewHandler(s types.LotStore, seedStore types.SeedStore, us types.UserStore, sessionStore *auth.AuthStore) *Handler {
+	return &Handler{s, seedStore, us, sessionStore}
+}
+
+func (h *Handler) RegisterRouter(router *mux.Router) {
+	router.HandleFunc("/seeds/{seedID:[0-9]+}/lineage/{userID:[0-9]+}", auth.WithOptionalJWTAuth(h.handleLineage, h.usersStore, h.sessionStore)).Methods("GET")
+	router.HandleFunc("/seeds/{seedID:[0-9]+}/grow-outs", auth.WithJWTAuth(h.handleGrowOut, h.usersStore, h.sessionStore)).Methods("POST")
+}
+
+func (h *Handler) handleLineage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	seedID, _ := strconv.Atoi(vars["seedID"])
+	userID, _ := strconv.Atoi(vars["userID"])
+
+	seed, err := h.seedStore.GetSeedByID(seedID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	viewerID, err := auth.GetUserIDFromContext(r.Context())
+	member := err == nil
+
+	lots, err := h.store.GetSeedLots(seed.ID, viewerID, member)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// i coltivatori che chi guarda non può vedere restano anonimi: se è nascosto proprio
+	// l'utente richiesto l'albero resta vuoto e la risposta è la stessa di chi non ha partite
+	Redact(lots)
+	tree := BuildTree(lots, userID)
+	if len(tree) == 0 {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("this user has no lots of this seed"))
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", "tree":
+		utils.WriteJSON(w, http.StatusOK, tree)
+	case "geojson":
+		w.Header().Set("Content-Type", "application/geo+json")
+		utils.WriteJSON(w, http.StatusOK, BuildGeoJSON(tree))
+	default:
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("format must be tree or geojson"))
+	}
+}
+
+func (h *Handler) handleGrowOut(w http.ResponseWriter, r *http.Request) {
+	seedID, _ := strconv.Atoi(mux.Vars(r)["seedID"])
+
+	payload, err := utils.DecodePayload[types.GrowOutPayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	parent, err := h.store.GetLotByID(payload.ParentLotID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	//si può coltivare solo una propria partita dello stesso seme
+	if parent.UserID != userID || parent.SeedID != seedID {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("you can only grow out your own lots of this seed"))
+		return
+	}
+
+	lot := &types.SeedLot{
+		SeedID:      seedID,
+		UserID:      userID,
+		ParentID:    parent.ID,
+		Source:      types.LotGrowOut,
+		Quantity:    payload.Quantity,
+		HarvestYear: payload.HarvestYear,
+	}
+	if err := h.store.CreateGrowOut(lot); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, lot)
+}
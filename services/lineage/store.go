@@ -0,0 +1,143 @@
+package lineage
+
+import (
+	"backend/seed-savers/types"
+	"database/sql"
+	"fmt"
+)
+
+const lotColumns = `l.lot_id, l.seed_id, l.user_id, u.name, l.parent_lot_id, l.source, l.order_id,
+	l.quantity, l.harvest_year, l.province, l.created_at`
+
+// Store gestisce l'accesso al database per le partite di semi
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore crea e restituisce un nuovo oggetto Store con il database passato come parametro
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// growerVisible dice se chi guarda può vedere il coltivatore di una partita, con le stesse regole
+// dei possessori: account attivo, seme non privato, inventario visibile e nessun blocco tra i due.
+// Le proprie partite sono sempre visibili
+const growerVisible = `(l.user_id = ? OR (u.status = 'active' AND COALESCE(us.private, FALSE) = FALSE
+	AND u.owner_visibility IN (?, ?) AND NOT EXISTS (SELECT 1 FROM user_block b
+		WHERE (b.blocker_user_id = ? AND b.blocked_user_id = l.user_id) OR (b.blocker_user_id = l.user_id AND b.blocked_user_id = ?))))`
+
+// GetSeedLots restituisce tutte le partite di un seme, dalla più vecchia, segnando come nascoste
+// quelle dei coltivatori che viewerID non può vedere. Con member falso restano visibili solo
+// i coltivatori che mostrano l'inventario a tutti
+func (s *Store) GetSeedLots(seedID, viewerID int, member bool) ([]types.SeedLot, error) {
+	visibleTo := types.VisibilityPublic
+	if member {
+		visibleTo = types.VisibilityMembers
+	}
+
+	rows, err := s.db.Query("SELECT "+lotColumns+", "+growerVisible+` FROM seed_lot l
+		LEFT JOIN users u ON l.user_id = u.user_id
+		LEFT JOIN users_seed us ON us.user_id = l.user_id AND us.seed_id = l.seed_id
+		WHERE l.seed_id = ? ORDER BY l.created_at, l.lot_id`,
+		viewerID, types.VisibilityPublic, visibleTo, viewerID, viewerID, seedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lots := make([]types.SeedLot, 0)
+	for rows.Next() {
+		var visible bool
+		lot, err := ScanRowIntoLot(rows, &visible)
+		if err != nil {
+			return nil, err
+		}
+		lot.Hidden = !visible
+		lots = append(lots, *lot)
+	}
+
+	return lots, rows.Err()
+}
+
+// GetLotByID restituisce una partita dato il suo ID
+func (s *Store) GetLotByID(id int) (*types.SeedLot, error) {
+	rows, err := s.db.Query("SELECT "+lotColumns+" FROM seed_lot l LEFT JOIN users u ON l.user_id = u.user_id WHERE l.lot_id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, fmt.Errorf("lot not found")
+	}
+
+	return ScanRowIntoLot(rows)
+}
+
+// CreateGrowOut registra i semi ottenuti coltivando una partita e li aggiunge all'inventario
+func (s *Store) CreateGrowOut(lot *types.SeedLot) error {
+	// Inizia una transazione
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Esegui rollback in caso di errore
+
+	res, err := tx.Exec(`INSERT INTO seed_lot (seed_id, user_id, parent_lot_id, source, quantity, harvest_year, province)
+		SELECT ?, ?, ?, 'grow_out', ?, ?, (SELECT province FROM adress WHERE user_id = ? AND is_default)`,
+		lot.SeedID, lot.UserID, lot.ParentID, lot.Quantity, sql.NullInt64{Int64: int64(lot.HarvestYear), Valid: lot.HarvestYear > 0}, lot.UserID)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	lot.ID = int(id)
+
+	_, err = tx.Exec(`INSERT INTO users_seed (user_id, seed_id, quantity, restocked_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON DUPLICATE KEY UPDATE quantity = quantity + VALUES(quantity), restocked_at = CURRENT_TIMESTAMP`, lot.UserID, lot.SeedID, lot.Quantity)
+	if err != nil {
+		return err
+	}
+
+	// Conferma la transazione
+	return tx.Commit()
+}
+
+// ScanRowIntoLot esegue il binding dei dati di una riga su un oggetto SeedLot; extra riceve
+// le colonne selezionate dopo lotColumns
+func ScanRowIntoLot(rows *sql.Rows, extra ...any) (*types.SeedLot, error) {
+	lot := new(types.SeedLot)
+	var userID, parentID, orderID, harvestYear sql.NullInt64
+	var userName, province sql.NullString
+
+	dest := []any{
+		&lot.ID,
+		&lot.SeedID,
+		&userID,
+		&userName,
+		&parentID,
+		&lot.Source,
+		&orderID,
+		&lot.Quantity,
+		&harvestYear,
+		&province,
+		&lot.CreatedAt,
+	}
+
+	err := rows.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	lot.UserID = int(userID.Int64)
+	lot.UserName = userName.String
+	lot.ParentID = int(parentID.Int64)
+	lot.OrderID = int(orderID.Int64)
+	lot.HarvestYear = int(harvestYear.Int64)
+	lot.Province = province.String
+
+	return lot, nil
+}
@@ -0,0 +1,295 @@
+package moderation
+
+import (
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/services/email"
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+type Handler struct {
+	store        types.ModerationStore
+	usersStore   types.UserStore
+	sessionStore *auth.AuthStore
+	send         func(to string, msg []byte) error
+}
+
+func NewHandler(s types.ModerationStore, us types.UserStore, sessionStore *auth.AuthStore) *Handler {
+	return &Handler{store: s, usersStore: us, sessionStore: sessionStore, send: email.SendMail}
+}
+
+func (h *Handler) RegisterRouter(router *mux.Router) {
+	router.HandleFunc("/user/blocks", auth.WithJWTAuth(h.handleGetBlocks, h.usersStore, h.sessionStore)).Methods("GET")
+	router.HandleFunc("/users/{userID:[0-9]+}/block", auth.WithJWTAuth(h.handleBlock, h.usersStore, h.sessionStore)).Methods("POST")
+	router.HandleFunc("/users/{userID:[0-9]+}/block", auth.WithJWTAuth(h.handleUnblock, h.usersStore, h.sessionStore)).Methods("DELETE")
+	router.HandleFunc("/reports", auth.WithJWTAuth(h.handleCreateReport, h.usersStore, h.sessionStore)).Methods("POST")
+	router.HandleFunc("/admin/reports", auth.WithRole(h.handleGetReports, h.usersStore, h.sessionStore, types.RoleCurator, types.RoleAdmin)).Methods("GET")
+	router.HandleFunc("/admin/reports/{reportID:[0-9]+}", auth.WithRole(h.handleGetReport, h.usersStore, h.sessionStore, types.RoleCurator, types.RoleAdmin)).Methods("GET")
+	router.HandleFunc("/admin/reports/{reportID:[0-9]+}/resolve", auth.WithRole(h.handleResolveReport, h.usersStore, h.sessionStore, types.RoleCurator, types.RoleAdmin)).Methods("POST")
+}
+
+func (h *Handler) handleGetBlocks(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	blocked, err := h.store.GetBlockedUsers(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, blocked)
+}
+
+// handleBlock blocca un utente: da quel momento nessuno dei due vede l'altro tra i possessori dei semi
+// né può ordinargli dei semi
+func (h *Handler) handleBlock(w http.ResponseWriter, r *http.Request) {
+	blockedID, _ := strconv.Atoi(mux.Vars(r)["userID"])
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+	if blockedID == userID {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you cannot block yourself"))
+		return
+	}
+
+	u, err := h.usersStore.GetUserByID(blockedID)
+	if err != nil || u.Status == types.UserDeleted {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("user not found"))
+		return
+	}
+
+	if err := h.store.BlockUser(userID, u.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]bool{"blocked": true})
+}
+
+func (h *Handler) handleUnblock(w http.ResponseWriter, r *http.Request) {
+	blockedID, _ := strconv.Atoi(mux.Vars(r)["userID"])
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	if err := h.store.UnblockUser(userID, blockedID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]bool{"blocked": false})
+}
+
+// handleCreateReport segnala un utente, un seme o un resoconto ai moderatori. Non si segnalano
+// sé stessi o i propri contenuti, e una segnalazione aperta sullo stesso contenuto basta
+func (h *Handler) handleCreateReport(w http.ResponseWriter, r *http.Request) {
+	payload, err := utils.DecodePayload[types.AbuseReportPayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	owner, err := h.store.ReportTargetOwner(payload.TargetType, payload.TargetID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if owner == userID {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you cannot report yourself or your own content"))
+		return
+	}
+
+	reported, err := h.store.HasOpenReport(userID, payload.TargetType, payload.TargetID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if reported {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("you have already reported this %s", payload.TargetType))
+		return
+	}
+
+	report := &types.AbuseReport{
+		ReporterID:   userID,
+		TargetType:   payload.TargetType,
+		TargetID:     payload.TargetID,
+		TargetUserID: owner,
+		Category:     payload.Category,
+		Reason:       payload.Reason,
+	}
+	if err := h.store.CreateReport(report); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, report)
+}
+
+// handleGetReports è la coda di moderazione: status (open, resolved o all) filtra, di default restano
+// le segnalazioni aperte, dalla più vecchia
+func (h *Handler) handleGetReports(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := utils.ParsePage(r, DefaultPageSize, MaxPageSize)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = types.ReportOpen
+	case "all":
+		status = ""
+	case types.ReportOpen, types.ReportResolved:
+	default:
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("status must be open, resolved or all"))
+		return
+	}
+
+	reports, total, err := h.store.GetReports(status, limit, (page-1)*limit)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.AbuseReportPage{Results: reports, Total: total, Page: page, Limit: limit})
+}
+
+func (h *Handler) handleGetReport(w http.ResponseWriter, r *http.Request) {
+	reportID, _ := strconv.Atoi(mux.Vars(r)["reportID"])
+
+	report, err := h.store.GetReport(reportID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, report)
+}
+
+// handleResolveReport chiude una segnalazione: dismiss la archivia, warn manda la nota all'utente
+// come avvertimento, suspend sospende l'account (solo gli amministratori) e remove toglie il contenuto
+func (h *Handler) handleResolveReport(w http.ResponseWriter, r *http.Request) {
+	reportID, _ := strconv.Atoi(mux.Vars(r)["reportID"])
+
+	payload, err := utils.DecodePayload[types.ModerationPayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	moderatorID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	report, err := h.store.GetReport(reportID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if report.Status != types.ReportOpen {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("the report has already been resolved"))
+		return
+	}
+
+	if status, err := checkAction(r, report, payload, moderatorID); err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	report.Resolution, report.ResolutionNote, report.ResolvedBy = payload.Action, payload.Note, moderatorID
+	err = h.store.ResolveReport(report)
+	if errors.Is(err, ErrSeedOrdered) {
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if payload.Action == types.ModerationWarn {
+		h.sendWarning(report)
+	}
+
+	resolved, err := h.store.GetReport(report.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resolved)
+}
+
+// checkAction verifica che la decisione si possa applicare alla segnalazione e restituisce lo stato
+// HTTP da usare se non si può
+func checkAction(r *http.Request, report *types.AbuseReport, payload *types.ModerationPayload, moderatorID int) (int, error) {
+	switch payload.Action {
+	case types.ModerationWarn, types.ModerationSuspend:
+		if report.TargetUserID == 0 {
+			return http.StatusConflict, fmt.Errorf("the author of the reported %s no longer exists", report.TargetType)
+		}
+		if report.TargetUserID == moderatorID {
+			return http.StatusBadRequest, fmt.Errorf("you cannot %s yourself", payload.Action)
+		}
+	case types.ModerationRemove:
+		if report.TargetType == types.ReportTargetUser {
+			return http.StatusBadRequest, fmt.Errorf("a user cannot be removed, suspend the account instead")
+		}
+	}
+
+	if payload.Action == types.ModerationWarn && payload.Note == "" {
+		return http.StatusBadRequest, fmt.Errorf("a warning needs a note for the user")
+	}
+	if payload.Action == types.ModerationSuspend && !auth.HasRole(r.Context(), types.RoleAdmin) {
+		return http.StatusForbidden, fmt.Errorf("only admins can suspend accounts")
+	}
+
+	return 0, nil
+}
+
+func (h *Handler) sendWarning(report *types.AbuseReport) {
+	u, err := h.usersStore.GetUserByID(report.TargetUserID)
+	if err != nil {
+		log.Printf("failed to load user %d to send a warning: %v", report.TargetUserID, err)
+		return
+	}
+
+	body := fmt.Sprintf("<html><body><h1>Ciao %s, hai ricevuto un avvertimento dai moderatori</h1><p>%s</p>"+
+		"<p>Se il comportamento si ripete l'account potrà essere sospeso.</p></body></html>",
+		html.EscapeString(u.Name), html.EscapeString(report.ResolutionNote))
+	if err := h.send(u.Email, email.Message("Avvertimento dai moderatori", body)); err != nil {
+		log.Printf("failed to send the warning of report %d to user %d: %v", report.ID, u.ID, err)
+	}
+}
@@ -0,0 +1,228 @@
+package moderation
+
+import (
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func request(method, path string, payload any, userID int, role string) *http.Request {
+	marshalled, _ := json.Marshal(payload)
+	req := httptest.NewRequest(method, path, bytes.NewBuffer(marshalled))
+	ctx := context.WithValue(req.Context(), auth.UserKey, userID)
+	return req.WithContext(context.WithValue(ctx, auth.RoleKey, role))
+}
+
+func serve(route string, h http.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc(route, h)
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestBlock(t *testing.T) {
+	store := &mockStore{blocks: map[[2]int]bool{}}
+	users := &mockUserStore{users: map[int]*types.User{
+		2: {ID: 2, Name: "Marco", Status: types.UserActive},
+		3: {ID: 3, Status: types.UserDeleted},
+	}}
+	handler := NewHandler(store, users, nil)
+
+	block := func(blockedID int) int {
+		req := request(http.MethodPost, fmt.Sprintf("/users/%d/block", blockedID), nil, 1, types.RoleUser)
+		return serve("/users/{userID}/block", handler.handleBlock, req).Code
+	}
+
+	if code := block(1); code != http.StatusBadRequest {
+		t.Errorf("expected blocking yourself to fail with %d, got %d", http.StatusBadRequest, code)
+	}
+	if code := block(3); code != http.StatusNotFound {
+		t.Errorf("expected blocking a deleted account to fail with %d, got %d", http.StatusNotFound, code)
+	}
+	if code := block(2); code != http.StatusOK || !store.blocks[[2]int{1, 2}] {
+		t.Errorf("expected user 2 to be blocked, got %d %v", code, store.blocks)
+	}
+
+	req := request(http.MethodDelete, "/users/2/block", nil, 1, types.RoleUser)
+	if rr := serve("/users/{userID}/block", handler.handleUnblock, req); rr.Code != http.StatusOK || store.blocks[[2]int{1, 2}] {
+		t.Errorf("expected user 2 to be unblocked, got %d %v", rr.Code, store.blocks)
+	}
+}
+
+func TestCreateReport(t *testing.T) {
+	store := &mockStore{owners: map[string]int{"seed/4": 2, "grow_report/9": 1}}
+	handler := NewHandler(store, &mockUserStore{}, nil)
+
+	report := func(payload types.AbuseReportPayload) *httptest.ResponseRecorder {
+		return serve("/reports", handler.handleCreateReport, request(http.MethodPost, "/reports", payload, 1, types.RoleUser))
+	}
+	payload := types.AbuseReportPayload{TargetType: types.ReportTargetSeed, TargetID: 4, Category: "spam", Reason: "pubblicità di un negozio"}
+
+	t.Run("should validate the category", func(t *testing.T) {
+		invalid := payload
+		invalid.Category = "boring"
+		if rr := report(invalid); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should not report missing or own content", func(t *testing.T) {
+		missing := payload
+		missing.TargetID = 5
+		if rr := report(missing); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+
+		own := payload
+		own.TargetType, own.TargetID = types.ReportTargetGrowReport, 9
+		if rr := report(own); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should report once", func(t *testing.T) {
+		rr := report(payload)
+		if rr.Code != http.StatusCreated || store.created == nil || store.created.TargetUserID != 2 || store.created.ReporterID != 1 {
+			t.Fatalf("expected a report against user 2, got %d %+v", rr.Code, store.created)
+		}
+
+		store.open = true
+		if rr := report(payload); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+}
+
+func TestResolveReport(t *testing.T) {
+	var sent []string
+	store := &mockStore{}
+	users := &mockUserStore{users: map[int]*types.User{2: {ID: 2, Name: "Marco", Email: "marco@example.com"}}}
+	handler := NewHandler(store, users, nil)
+	handler.send = func(to string, msg []byte) error {
+		sent = append(sent, to+" "+string(msg))
+		return nil
+	}
+
+	resolve := func(payload types.ModerationPayload, role string) *httptest.ResponseRecorder {
+		store.report = &types.AbuseReport{ID: 7, TargetType: types.ReportTargetUser, TargetID: 2, TargetUserID: 2, Status: types.ReportOpen}
+		store.resolved = nil
+		req := request(http.MethodPost, "/admin/reports/7/resolve", payload, 1, role)
+		return serve("/admin/reports/{reportID}/resolve", handler.handleResolveReport, req)
+	}
+
+	cases := []struct {
+		name    string
+		payload types.ModerationPayload
+		role    string
+		status  int
+	}{
+		{"only admins suspend", types.ModerationPayload{Action: types.ModerationSuspend}, types.RoleCurator, http.StatusForbidden},
+		{"warnings need a note", types.ModerationPayload{Action: types.ModerationWarn}, types.RoleCurator, http.StatusBadRequest},
+		{"users cannot be removed", types.ModerationPayload{Action: types.ModerationRemove}, types.RoleAdmin, http.StatusBadRequest},
+		{"unknown actions", types.ModerationPayload{Action: "ban"}, types.RoleAdmin, http.StatusBadRequest},
+		{"admins suspend", types.ModerationPayload{Action: types.ModerationSuspend}, types.RoleAdmin, http.StatusOK},
+		{"curators dismiss", types.ModerationPayload{Action: types.ModerationDismiss}, types.RoleCurator, http.StatusOK},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rr := resolve(c.payload, c.role)
+			if rr.Code != c.status {
+				t.Fatalf("expected status code %d but got %d: %s", c.status, rr.Code, rr.Body)
+			}
+			if (store.resolved != nil) != (c.status == http.StatusOK) {
+				t.Errorf("expected the report to be resolved only on success, got %+v", store.resolved)
+			}
+		})
+	}
+
+	t.Run("should email the warning", func(t *testing.T) {
+		rr := resolve(types.ModerationPayload{Action: types.ModerationWarn, Note: "niente <b>insulti</b>"}, types.RoleCurator)
+		if rr.Code != http.StatusOK || store.resolved.Resolution != types.ModerationWarn || store.resolved.ResolvedBy != 1 {
+			t.Fatalf("expected a warning from moderator 1, got %d %+v", rr.Code, store.resolved)
+		}
+		if len(sent) != 1 || !strings.HasPrefix(sent[0], "marco@example.com") || !strings.Contains(sent[0], "niente &lt;b&gt;insulti&lt;/b&gt;") {
+			t.Errorf("expected the escaped note to be sent to marco, got %q", sent)
+		}
+	})
+
+	t.Run("should not resolve twice", func(t *testing.T) {
+		store.report = &types.AbuseReport{ID: 7, Status: types.ReportResolved}
+		req := request(http.MethodPost, "/admin/reports/7/resolve", types.ModerationPayload{Action: types.ModerationDismiss}, 1, types.RoleAdmin)
+		if rr := serve("/admin/reports/{reportID}/resolve", handler.handleResolveReport, req); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+}
+
+type mockStore struct {
+	types.ModerationStore
+	blocks   map[[2]int]bool
+	owners   map[string]int
+	open     bool
+	created  *types.AbuseReport
+	report   *types.AbuseReport
+	resolved *types.AbuseReport
+}
+
+func (m *mockStore) BlockUser(userID, blockedID int) error {
+	m.blocks[[2]int{userID, blockedID}] = true
+	return nil
+}
+
+func (m *mockStore) UnblockUser(userID, blockedID int) error {
+	delete(m.blocks, [2]int{userID, blockedID})
+	return nil
+}
+
+func (m *mockStore) ReportTargetOwner(targetType string, targetID int) (int, error) {
+	owner, ok := m.owners[fmt.Sprintf("%s/%d", targetType, targetID)]
+	if !ok {
+		return 0, fmt.Errorf("%s not found", targetType)
+	}
+	return owner, nil
+}
+
+func (m *mockStore) HasOpenReport(reporterID int, targetType string, targetID int) (bool, error) {
+	return m.open, nil
+}
+
+func (m *mockStore) CreateReport(report *types.AbuseReport) error {
+	report.ID, report.Status = 1, types.ReportOpen
+	m.created = report
+	return nil
+}
+
+func (m *mockStore) GetReport(id int) (*types.AbuseReport, error) {
+	if m.report == nil || m.report.ID != id {
+		return nil, fmt.Errorf("report not found")
+	}
+	return m.report, nil
+}
+
+func (m *mockStore) ResolveReport(report *types.AbuseReport) error {
+	m.resolved = report
+	return nil
+}
+
+type mockUserStore struct {
+	types.UserStore
+	users map[int]*types.User
+}
+
+func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
+	u, ok := m.users[id]
+	if !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+	return u, nil
+}
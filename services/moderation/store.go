@@ -0,0 +1,228 @@
+package moderation
+
+import (
+	"backend/seed-savers/types"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrSeedOrdered indica che il seme da rimuovere compare in qualche ordine: cancellarlo cancellerebbe
+// anche lo storico degli scambi, meglio unirlo alla varietà giusta
+var ErrSeedOrdered = errors.New("the seed appears in some orders, merge it into the right variety instead")
+
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore crea e restituisce un nuovo oggetto Store con il database passato come parametro
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// BlockUser blocca blockedID per conto di userID; bloccare due volte non è un errore
+func (s *Store) BlockUser(userID, blockedID int) error {
+	_, err := s.db.Exec("INSERT IGNORE INTO user_block (blocker_user_id, blocked_user_id) VALUES (?, ?)", userID, blockedID)
+	return err
+}
+
+func (s *Store) UnblockUser(userID, blockedID int) error {
+	_, err := s.db.Exec("DELETE FROM user_block WHERE blocker_user_id = ? AND blocked_user_id = ?", userID, blockedID)
+	return err
+}
+
+// GetBlockedUsers restituisce gli utenti bloccati da userID, dal blocco più recente
+func (s *Store) GetBlockedUsers(userID int) ([]types.BlockedUser, error) {
+	rows, err := s.db.Query(`SELECT b.blocked_user_id, COALESCE(u.name, ''), b.created_at
+		FROM user_block b
+		INNER JOIN users u ON u.user_id = b.blocked_user_id
+		WHERE b.blocker_user_id = ?
+		ORDER BY b.created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocked := make([]types.BlockedUser, 0)
+	for rows.Next() {
+		var b types.BlockedUser
+		if err := rows.Scan(&b.UserID, &b.Name, &b.BlockedAt); err != nil {
+			return nil, err
+		}
+		blocked = append(blocked, b)
+	}
+
+	return blocked, rows.Err()
+}
+
+// ReportTargetOwner restituisce l'utente segnalato, chi ha creato il seme o l'autore del resoconto.
+// I semi più vecchi non hanno un autore e restituiscono zero
+func (s *Store) ReportTargetOwner(targetType string, targetID int) (int, error) {
+	var query string
+	switch targetType {
+	case types.ReportTargetUser:
+		query = "SELECT user_id FROM users WHERE user_id = ? AND status <> 'deleted'"
+	case types.ReportTargetSeed:
+		query = "SELECT created_by FROM seed WHERE seed_id = ?"
+	case types.ReportTargetGrowReport:
+		query = "SELECT user_id FROM grow_report WHERE report_id = ?"
+	default:
+		return 0, fmt.Errorf("unknown report target %q", targetType)
+	}
+
+	var owner sql.NullInt64
+	err := s.db.QueryRow(query, targetID).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%s not found", targetType)
+	}
+
+	return int(owner.Int64), err
+}
+
+// HasOpenReport indica se l'utente ha già una segnalazione aperta su quel contenuto
+func (s *Store) HasOpenReport(reporterID int, targetType string, targetID int) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM abuse_report
+		WHERE reporter_user_id = ? AND target_type = ? AND target_id = ? AND status = 'open')`,
+		reporterID, targetType, targetID).Scan(&exists)
+	return exists, err
+}
+
+// CreateReport salva la segnalazione e imposta ID e CreatedAt
+func (s *Store) CreateReport(report *types.AbuseReport) error {
+	res, err := s.db.Exec(`INSERT INTO abuse_report (reporter_user_id, target_type, target_id, target_user_id, category, reason)
+		VALUES (?, ?, ?, NULLIF(?, 0), ?, ?)`,
+		report.ReporterID, report.TargetType, report.TargetID, report.TargetUserID, report.Category, report.Reason)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	created, err := s.GetReport(int(id))
+	if err != nil {
+		return err
+	}
+	*report = *created
+	return nil
+}
+
+const reportColumns = `r.report_id, COALESCE(r.reporter_user_id, 0), COALESCE(reporter.name, ''), r.target_type, r.target_id,
+	COALESCE(r.target_user_id, 0), COALESCE(target.name, ''), r.category, r.reason, r.status, COALESCE(r.resolution, ''),
+	COALESCE(r.resolution_note, ''), COALESCE(r.resolved_by, 0), r.resolved_at, r.created_at`
+
+const reportJoins = ` FROM abuse_report r
+	LEFT JOIN users reporter ON reporter.user_id = r.reporter_user_id
+	LEFT JOIN users target ON target.user_id = r.target_user_id`
+
+// GetReports restituisce una pagina della coda di moderazione, dalle segnalazioni più vecchie, e il totale.
+// Con status vuoto restituisce tutte le segnalazioni
+func (s *Store) GetReports(status string, limit, offset int) ([]types.AbuseReport, int, error) {
+	where := " WHERE (? = '' OR r.status = ?)"
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM abuse_report r"+where, status, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.Query("SELECT "+reportColumns+reportJoins+where+" ORDER BY r.created_at, r.report_id LIMIT ? OFFSET ?",
+		status, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	reports := make([]types.AbuseReport, 0)
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		reports = append(reports, *report)
+	}
+
+	return reports, total, rows.Err()
+}
+
+func (s *Store) GetReport(id int) (*types.AbuseReport, error) {
+	report, err := scanReport(s.db.QueryRow("SELECT "+reportColumns+reportJoins+" WHERE r.report_id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("report not found")
+	}
+	return report, err
+}
+
+func scanReport(row interface{ Scan(...any) error }) (*types.AbuseReport, error) {
+	r := new(types.AbuseReport)
+	var resolvedAt sql.NullTime
+	err := row.Scan(&r.ID, &r.ReporterID, &r.ReporterName, &r.TargetType, &r.TargetID, &r.TargetUserID, &r.TargetUserName,
+		&r.Category, &r.Reason, &r.Status, &r.Resolution, &r.ResolutionNote, &r.ResolvedBy, &resolvedAt, &r.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if resolvedAt.Valid {
+		r.ResolvedAt = &resolvedAt.Time
+	}
+	return r, nil
+}
+
+// ResolveReport applica la decisione report.Resolution presa da report.ResolvedBy e chiude, con la stessa
+// decisione, tutte le segnalazioni aperte sullo stesso contenuto
+func (s *Store) ResolveReport(report *types.AbuseReport) error {
+	// Inizia una transazione
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Assicura che il rollback venga eseguito in caso di errore
+
+	switch report.Resolution {
+	case types.ModerationWarn:
+		_, err = tx.Exec("INSERT INTO user_warning (user_id, report_id, message) VALUES (?, ?, ?)",
+			report.TargetUserID, report.ID, report.ResolutionNote)
+	case types.ModerationSuspend:
+		// come la sospensione dall'API di amministrazione: chiude anche tutte le sessioni aperte
+		_, err = tx.Exec("UPDATE users SET token_version = token_version + 1, status = 'blocked' WHERE user_id = ? AND status = 'active'",
+			report.TargetUserID)
+	case types.ModerationRemove:
+		err = removeContent(tx, report.TargetType, report.TargetID)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE abuse_report SET status = 'resolved', resolution = ?, resolution_note = NULLIF(?, ''),
+			resolved_by = ?, resolved_at = CURRENT_TIMESTAMP
+		WHERE status = 'open' AND (report_id = ? OR (target_type = ? AND target_id = ?))`,
+		report.Resolution, report.ResolutionNote, report.ResolvedBy, report.ID, report.TargetType, report.TargetID)
+	if err != nil {
+		return err
+	}
+
+	// Conferma la transazione
+	return tx.Commit()
+}
+
+// removeContent nasconde il resoconto segnalato o cancella il seme dal catalogo, se nessuno l'ha mai ordinato
+func removeContent(tx *sql.Tx, targetType string, targetID int) error {
+	switch targetType {
+	case types.ReportTargetGrowReport:
+		_, err := tx.Exec("UPDATE grow_report SET status = ? WHERE report_id = ?", types.GrowReportHidden, targetID)
+		return err
+	case types.ReportTargetSeed:
+		var ordered bool
+		if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM order_detail WHERE seed_id = ?)", targetID).Scan(&ordered); err != nil {
+			return err
+		}
+		if ordered {
+			return ErrSeedOrdered
+		}
+		_, err := tx.Exec("DELETE FROM seed WHERE seed_id = ?", targetID)
+		return err
+	default:
+		return fmt.Errorf("a %s cannot be removed, suspend the account instead", targetType)
+	}
+}
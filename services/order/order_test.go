@@ -0,0 +1,117 @@
+package order
+
+import (
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckChange(t *testing.T) {
+	const sender, reciver, stranger = 1, 2, 3
+
+	cases := []struct {
+		name     string
+		previous string
+		state    string
+		quantity int
+		userID   int
+		want     error
+	}{
+		{"the sender moves the order forward", OrderPending, OrderPreparing, 0, sender, nil},
+		{"the sender can skip a step", OrderPending, OrderShipping, 0, sender, nil},
+		{"the reciver marks the arrival", OrderShipping, OrderArrived, 0, reciver, nil},
+		{"the sender reduces the quantity while pending", OrderPending, "", 3, sender, nil},
+		{"strangers cannot touch the order", OrderPending, OrderPreparing, 0, stranger, ErrNotParty},
+		{"the sender cannot mark the arrival", OrderShipping, OrderArrived, 0, sender, ErrNotParty},
+		{"a pending order cannot arrive", OrderPending, OrderArrived, 0, reciver, ErrOrderState},
+		{"an order in preparation cannot arrive", OrderPreparing, OrderArrived, 0, reciver, ErrOrderState},
+		{"the reciver cannot ship", OrderPending, OrderShipping, 0, reciver, ErrNotParty},
+		{"the reciver cannot change the quantity", OrderPending, "", 3, reciver, ErrNotParty},
+		{"an arrived order cannot go back", OrderArrived, OrderPending, 0, sender, ErrOrderState},
+		{"an arrived order cannot arrive twice", OrderArrived, OrderArrived, 0, reciver, nil},
+		{"the quantity is fixed once accepted", OrderPreparing, "", 3, sender, ErrOrderState},
+		{"the quantity cannot grow", OrderPending, "", 9, sender, ErrOrderState},
+		{"cancelled orders are closed", OrderCancelled, OrderArrived, 0, reciver, ErrOrderState},
+		{"users cannot cancel", OrderPending, OrderCancelled, 0, sender, ErrOrderState},
+	}
+
+	for _, c := range cases {
+		order := &types.Order{State: c.state, Seed: types.Seed{Quantity: c.quantity}}
+		err := checkChange(order, c.previous, 5, c.userID, sender, reciver)
+		if (c.want == nil && err != nil) || (c.want != nil && !errors.Is(err, c.want)) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, err)
+		}
+	}
+}
+
+func TestUpdateOrderState(t *testing.T) {
+	store := &mockOrderStore{}
+	handler := NewHandler(store, nil, nil, nil)
+
+	update := func(state string) int {
+		marshalled, _ := json.Marshal(types.UpdateOrderPayload{OrderId: 3, State: state})
+		req := httptest.NewRequest(http.MethodPut, "/update-order", bytes.NewBuffer(marshalled))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, 1))
+		rr := httptest.NewRecorder()
+		handler.handleUpdateOrder(rr, req)
+		return rr.Code
+	}
+
+	for _, state := range []string{OrderCancelled, "Smarrito"} {
+		if code := update(state); code != http.StatusBadRequest || store.modified != nil {
+			t.Errorf("expected %q to be refused, got %d", state, code)
+		}
+	}
+	if code := update(OrderShipping); code != http.StatusOK || store.modified == nil || store.modified.State != OrderShipping {
+		t.Errorf("expected the order to be shipped, got %d %+v", code, store.modified)
+	}
+}
+
+type mockOrderStore struct {
+	types.OrderStore
+	modified *types.Order
+}
+
+func (m *mockOrderStore) ModifyOrder(order *types.Order, userID int) error {
+	m.modified = order
+	return nil
+}
+
+func TestSenderAdressDisclosure(t *testing.T) {
+	a := types.Adress{Label: "casa", Street: "via Roma", Number: "12", Apartment_number: "3", City: "Saluggia", Cap: "13040", Province: "VC"}
+
+	// l'indirizzo è di chi spedisce, quindi decide la sua impostazione
+	cases := []struct {
+		disclosure string
+		state      string
+		visible    bool
+	}{
+		{types.DiscloseOnOrder, OrderPending, true},
+		{types.DiscloseOnOrder, OrderPreparing, true},
+		{types.DiscloseOnOrder, OrderCancelled, true},
+		{types.DiscloseOnAccept, OrderPending, false},
+		{types.DiscloseOnAccept, OrderPreparing, true},
+		{types.DiscloseOnAccept, OrderShipping, true},
+		{types.DiscloseOnAccept, OrderArrived, true},
+		{types.DiscloseOnAccept, OrderCancelled, false},
+	}
+
+	for _, c := range cases {
+		got := senderAdress(a, c.state, c.disclosure)
+		if c.visible && *got != a {
+			t.Errorf("%s, %s: expected the full address, got %+v", c.disclosure, c.state, got)
+		}
+		if !c.visible && (got.Label != "" || got.Street != "" || got.Number != "" || got.Apartment_number != "") {
+			t.Errorf("%s, %s: street and number must be withheld, got %+v", c.disclosure, c.state, got)
+		}
+		if got.City != "Saluggia" || got.Cap != "13040" || got.Province != "VC" {
+			t.Errorf("%s, %s: the locality must stay visible, got %+v", c.disclosure, c.state, got)
+		}
+	}
+}
@@ -4,10 +4,10 @@ import (
 	"backend/seed-savers/services/auth"
 	"backend/seed-savers/types"
 	"backend/seed-savers/utils"
+	"errors"
 	"fmt"
 	"strconv"
 
-	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -25,7 +25,7 @@ func NewHandler(s types.OrderStore, us types.UserStore, seedStore types.SeedStor
 }
 
 func (h *Handler) RegisterRouter(router *mux.Router) {
-	router.HandleFunc("/create-order", auth.WithJWTAuth(h.handleCreateOrder, h.usersStore, h.sessionStore)).Methods("POST")
+	router.HandleFunc("/create-order", auth.WithVerifiedEmail(h.handleCreateOrder, h.usersStore, h.sessionStore)).Methods("POST")
 	router.HandleFunc("/update-order", auth.WithJWTAuth(h.handleUpdateOrder, h.usersStore, h.sessionStore)).Methods("PUT")
 	router.HandleFunc("/orders-to-ship", auth.WithJWTAuth(h.handleOrdersToShip, h.usersStore, h.sessionStore)).Methods("GET")
 	router.HandleFunc("/orders-to-recive", auth.WithJWTAuth(h.handleOrdersToRecive, h.usersStore, h.sessionStore)).Methods("GET")
@@ -80,23 +80,31 @@ func (h *Handler) handleOrdersToRecive(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) handleUpdateOrder(w http.ResponseWriter, r *http.Request) {
-	var order types.Order
-
 	payload, err := utils.DecodePayload[types.UpdateOrderPayload](w, r)
 	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
 
+	userID, err := auth.GetUserIDFromContext(r.Context())
 	if err != nil {
 		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
-	order = types.Order{ID: payload.OrderId, State: payload.State, Seed: types.Seed{Quantity: payload.SeedQuantity}}
 
-	err = h.store.ModifyOrder(&order)
-	if err != nil {
-		log.Println(err)
+	order := types.Order{ID: payload.OrderId, State: payload.State, Seed: types.Seed{Quantity: payload.SeedQuantity}}
+	err = h.store.ModifyOrder(&order, userID)
+	switch {
+	case errors.Is(err, ErrOrderNotFound):
+		utils.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrNotParty):
+		utils.WriteError(w, http.StatusForbidden, err)
+	case errors.Is(err, ErrOrderState):
+		utils.WriteError(w, http.StatusConflict, err)
+	case err != nil:
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	default:
+		utils.WriteJSON(w, http.StatusOK, nil)
 	}
 }
 
@@ -118,7 +126,15 @@ func (h *Handler) handleCreateOrder(w http.ResponseWriter, r *http.Request) {
 
 	//verifico che l'utente che dovra spedire semi ha più semi ddi quanti richiesti TO-DO: gestire i crediti
 	if quantity >= payload.SeedQuantity {
-		err = h.store.MakeOrder(payload.SenderID, reciver, payload.SeedID, payload.SeedQuantity)
+		err = h.store.MakeOrder(payload.SenderID, reciver, payload.SeedID, payload.SeedQuantity, payload.AdressID)
+		if errors.Is(err, ErrNoAdress) {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		if errors.Is(err, ErrBlocked) {
+			utils.WriteError(w, http.StatusForbidden, err)
+			return
+		}
 		if err != nil {
 			utils.WriteError(w, http.StatusInternalServerError, err)
 			return
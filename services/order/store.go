@@ -3,10 +3,54 @@ package order
 import (
 	"backend/seed-savers/types"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 )
 
+// Gli stati di un ordine, nell'ordine in cui si susseguono
+const (
+	OrderPending   = "In attesa"
+	OrderPreparing = "In preparazione"
+	OrderShipping  = "In spedizione"
+)
+
+// OrderArrived è lo stato di un ordine consegnato al destinatario
+const OrderArrived = "Arrivato"
+
+// orderFlow è la sequenza degli stati: un ordine può solo andare avanti
+var orderFlow = []string{OrderPending, OrderPreparing, OrderShipping, OrderArrived}
+
+// OrderCancelled è lo stato di un ordine annullato, ad esempio perché una delle parti ha cancellato l'account
+const OrderCancelled = "Annullato"
+
+// ErrNoAdress indica che il destinatario non ha l'indirizzo di spedizione scelto, o non ne ha nessuno
+var ErrNoAdress = errors.New("no shipping address found, add one to your address book")
+
+// ErrOrderNotFound indica che l'ordine non esiste
+var ErrOrderNotFound = errors.New("order not found")
+
+// ErrNotParty indica che chi modifica l'ordine non ne è né il mittente né il destinatario, o che
+// la modifica spetta all'altra parte
+var ErrNotParty = errors.New("you are not allowed to change this order")
+
+// ErrOrderState indica una modifica che lo stato dell'ordine non permette
+var ErrOrderState = errors.New("the order cannot be changed")
+
+// ErrBlocked indica che uno dei due utenti ha bloccato l'altro
+var ErrBlocked = errors.New("you cannot order from this user")
+
+// shippingColumns legge l'indirizzo copiato nell'ordine al momento dell'acquisto, nell'ordine atteso
+// da ScanRowIntoOrder. Gli ordini più vecchi della rubrica possono non averlo
+const shippingColumns = `COALESCE(o.shipping_label, ''), COALESCE(o.shipping_state, ''), COALESCE(o.shipping_city, ''),
+			  COALESCE(o.shipping_street, ''), COALESCE(o.shipping_cap, ''), COALESCE(o.shipping_province, ''),
+			  COALESCE(o.shipping_number, ''), COALESCE(o.shipping_apartment_number, '')`
+
+// senderAdressColumns leggono l'indirizzo predefinito di chi spedisce e quando ha scelto di mostrarlo
+const senderAdressColumns = `sa.adress_id IS NOT NULL, COALESCE(sa.label, ''), COALESCE(sa.state, ''), COALESCE(sa.city, ''),
+			  COALESCE(sa.street, ''), COALESCE(sa.cap, ''), COALESCE(sa.province, ''), COALESCE(sa.number, ''),
+			  COALESCE(sa.apartment_number, ''), sender.address_disclosure`
+
 // Store rappresenta una struttura che gestisce l'accesso al database per gli ordini
 type Store struct {
 	db *sql.DB
@@ -45,14 +89,16 @@ func (s *Store) GetOrdersById(ID int) (*types.Order, error) {
 	return order, nil
 }
 
-// GetOrdersByReciver restituisce una lista di ordini ricevuti da un utente dato l'ID
+// GetOrdersByReciver restituisce una lista di ordini ricevuti da un utente dato l'ID, con l'indirizzo
+// di chi spedisce secondo quando ha scelto di mostrarlo
 func (s *Store) GetIncomingOrders(reciverUserID int) ([]types.Order, error) {
 
-	query := `SELECT o.*, reciver.name, a.state, a.city, a.street, a.cap, 
-			  a.province, a.number, a.apartment_number, s.img, s.variety_name,  od.quantity, s.seed_id
+	query := `SELECT o.order_id, o.sender_user_id, o.reciver_user_id, o.order_date, o.state, reciver.name, ` + shippingColumns + `,
+			  s.img, s.variety_name, od.quantity, s.seed_id, ` + senderAdressColumns + `
 			  FROM orders o
  			  JOIN users reciver ON o.reciver_user_id = reciver.user_id
-			  LEFT JOIN adress a ON reciver.user_id = a.id
+			  JOIN users sender ON o.sender_user_id = sender.user_id
+			  LEFT JOIN adress sa ON sa.user_id = o.sender_user_id AND sa.is_default
 			  JOIN order_detail od ON o.order_id = od.order_id
 			  JOIN seed s ON od.seed_id = s.seed_id
 			  WHERE o.reciver_user_id = ?;`
@@ -68,10 +114,17 @@ func (s *Store) GetIncomingOrders(reciverUserID int) ([]types.Order, error) {
 
 	// Iteriamo sulle righe restituite dalla query
 	for rows.Next() {
-		order, err := ScanRowIntoOrder(rows)
+		var found bool
+		var a types.Adress
+		var disclosure string
+		order, err := ScanRowIntoOrder(rows, &found, &a.Label, &a.Country, &a.City, &a.Street, &a.Cap, &a.Province,
+			&a.Number, &a.Apartment_number, &disclosure)
 		if err != nil {
 			return nil, err
 		}
+		if found {
+			order.SenderAdress = senderAdress(a, order.State, disclosure)
+		}
 		orders = append(orders, *order)
 	}
 
@@ -83,14 +136,23 @@ func (s *Store) GetIncomingOrders(reciverUserID int) ([]types.Order, error) {
 	return orders, nil
 }
 
+// senderAdress restituisce l'indirizzo di chi spedisce come lo vede chi riceve l'ordine. Con
+// DiscloseOnAccept nome, via e civico restano nascosti finché l'ordine non è accettato, o se è
+// stato annullato; restano sempre località, CAP e provincia
+func senderAdress(a types.Adress, state, disclosure string) *types.Adress {
+	if disclosure != types.DiscloseOnOrder && (state == OrderPending || state == OrderCancelled) {
+		a.Label, a.Street, a.Number, a.Apartment_number = "", "", "", ""
+	}
+	return &a
+}
+
 // GetOrdersBySender restituisce una lista di ordini inviati da un utente dato l'ID
 func (s *Store) GetOrdersToBeSent(senderUserID int) ([]types.Order, error) {
 
-	query := `SELECT o.*, sender.name, a.state, a.city, a.street, a.cap, 
-			  a.province, a.number, a.apartment_number, s.img, s.variety_name,  od.quantity, s.seed_id
+	query := `SELECT o.order_id, o.sender_user_id, o.reciver_user_id, o.order_date, o.state, sender.name, ` + shippingColumns + `,
+			  s.img, s.variety_name, od.quantity, s.seed_id
 			  FROM orders o
 	 		  JOIN users sender ON o.sender_user_id = sender.user_id
-			  LEFT JOIN adress a ON sender.user_id = a.id
 			  JOIN order_detail od ON o.order_id = od.order_id
 			  JOIN seed s ON od.seed_id = s.seed_id
 			  WHERE o.sender_user_id = ?;`
@@ -120,8 +182,9 @@ func (s *Store) GetOrdersToBeSent(senderUserID int) ([]types.Order, error) {
 	return orders, nil
 }
 
-// MakeOrder crea un nuovo ordine e i dettagli associati (come quantità e seme) con una transazione
-func (s *Store) MakeOrder(senderUserID, reciverUserID, seedId, quantity int) error {
+// MakeOrder crea un nuovo ordine e i dettagli associati (come quantità e seme) con una transazione.
+// L'indirizzo di spedizione, quello scelto o altrimenti il predefinito, viene copiato nell'ordine
+func (s *Store) MakeOrder(senderUserID, reciverUserID, seedId, quantity, adressID int) error {
 	// Inizio della transazione
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -131,11 +194,33 @@ func (s *Store) MakeOrder(senderUserID, reciverUserID, seedId, quantity int) err
 	// Rollback automatico se qualcosa va storto
 	defer tx.Rollback()
 
-	// Inseriamo l'ordine nella tabella orders
-	res, err := tx.Exec("INSERT INTO orders (sender_user_id, reciver_user_id) VALUES (?, ?)", senderUserID, reciverUserID)
+	// Nessun ordine tra due utenti se uno dei due ha bloccato l'altro
+	var blocked bool
+	err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM user_block
+		WHERE (blocker_user_id = ? AND blocked_user_id = ?) OR (blocker_user_id = ? AND blocked_user_id = ?))`,
+		senderUserID, reciverUserID, reciverUserID, senderUserID).Scan(&blocked)
 	if err != nil {
 		return err
 	}
+	if blocked {
+		return ErrBlocked
+	}
+
+	// Inseriamo l'ordine nella tabella orders insieme alla copia dell'indirizzo
+	res, err := tx.Exec(`INSERT INTO orders (sender_user_id, reciver_user_id, shipping_label, shipping_state, shipping_city,
+			shipping_street, shipping_cap, shipping_province, shipping_number, shipping_apartment_number)
+		SELECT ?, ?, label, state, city, street, cap, province, number, apartment_number
+		FROM adress
+		WHERE user_id = ? AND (adress_id = ? OR (? = 0 AND is_default))`,
+		senderUserID, reciverUserID, reciverUserID, adressID, adressID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNoAdress
+	}
 
 	// Otteniamo l'ID dell'ordine appena creato
 	orderID, err := res.LastInsertId()
@@ -190,8 +275,11 @@ func (s *Store) DeleteOrder(ID int) error {
 }
 
 
-// ModifyOrder modifica un ordine esistente, come lo stato e la quantità di semi
-func (s *Store) ModifyOrder(order *types.Order) error {
+// ModifyOrder modifica un ordine esistente per conto di userID. Solo il mittente fa avanzare lo stato
+// e può ridurre la quantità finché l'ordine è in attesa, restituendo a sé stesso i semi tolti; solo
+// il destinatario segna l'arrivo. Gli stati non tornano mai indietro, così i semi entrano una sola
+// volta nell'inventario del destinatario, come partita discendente da quella del mittente
+func (s *Store) ModifyOrder(order *types.Order, userID int) error {
 	// Inizio della transazione
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -201,29 +289,126 @@ func (s *Store) ModifyOrder(order *types.Order) error {
 	// Rollback automatico se qualcosa va storto
 	defer tx.Rollback()
 
-	// Modifica lo stato dell'ordine nella tabella orders
-	_, err = tx.Exec("UPDATE orders SET state = ? WHERE order_id = ?", order.State, order.ID)
+	// Legge l'ordine bloccandolo, così due modifiche contemporanee non si sovrappongono
+	var previousState string
+	var senderID, reciverID, seedID, quantity int
+	err = tx.QueryRow(`SELECT o.state, o.sender_user_id, o.reciver_user_id, od.seed_id, od.quantity
+		FROM orders o JOIN order_detail od ON o.order_id = od.order_id
+		WHERE o.order_id = ? FOR UPDATE`, order.ID).Scan(&previousState, &senderID, &reciverID, &seedID, &quantity)
+	if err == sql.ErrNoRows {
+		return ErrOrderNotFound
+	}
 	if err != nil {
 		return err
 	}
 
-	// Modifica la quantità dei semi nei dettagli dell'ordine
-	_, err = tx.Exec("UPDATE order_detail SET quantity = ? WHERE order_id = ?", order.Seed.Quantity, order.ID)
-	if err != nil {
+	if err := checkChange(order, previousState, quantity, userID, senderID, reciverID); err != nil {
 		return err
 	}
 
+	// Modifica la quantità dei semi nei dettagli dell'ordine, restituendo al mittente quelli tolti
+	if order.Seed.Quantity > 0 && order.Seed.Quantity != quantity {
+		_, err = tx.Exec("UPDATE order_detail SET quantity = ? WHERE order_id = ?", order.Seed.Quantity, order.ID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE users_seed SET quantity = quantity + ? WHERE user_id = ? AND seed_id = ?",
+			quantity-order.Seed.Quantity, senderID, seedID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Modifica lo stato dell'ordine nella tabella orders
+	if order.State != "" && order.State != previousState {
+		_, err = tx.Exec("UPDATE orders SET state = ? WHERE order_id = ?", order.State, order.ID)
+		if err != nil {
+			return err
+		}
+
+		if order.State == OrderArrived {
+			if err := receiveOrder(tx, order.ID); err != nil {
+				return err
+			}
+		}
+	}
+
 	// Confermiamo la transazione
-	err = tx.Commit()
+	return tx.Commit()
+}
+
+// checkChange verifica che userID possa applicare la modifica order a un ordine nello stato previous
+// con quantity semi
+func checkChange(order *types.Order, previous string, quantity, userID, senderID, reciverID int) error {
+	if userID != senderID && userID != reciverID {
+		return ErrNotParty
+	}
+	if previous == OrderCancelled {
+		return fmt.Errorf("%w: it was cancelled", ErrOrderState)
+	}
+
+	if order.Seed.Quantity > 0 && order.Seed.Quantity != quantity {
+		if userID != senderID {
+			return ErrNotParty
+		}
+		if previous != OrderPending {
+			return fmt.Errorf("%w: the quantity can only change while the order is %s", ErrOrderState, OrderPending)
+		}
+		if order.Seed.Quantity > quantity {
+			return fmt.Errorf("%w: the quantity can only be reduced", ErrOrderState)
+		}
+	}
+
+	if order.State != "" && order.State != previous {
+		if stateIndex(order.State) <= stateIndex(previous) ||
+			(order.State == OrderArrived && previous != OrderShipping) {
+			return fmt.Errorf("%w: it cannot go from %s to %s", ErrOrderState, previous, order.State)
+		}
+		if (order.State == OrderArrived) != (userID == reciverID) {
+			return ErrNotParty
+		}
+	}
+
+	return nil
+}
+
+// stateIndex restituisce la posizione dello stato in orderFlow, -1 se non ne fa parte
+func stateIndex(state string) int {
+	for i, s := range orderFlow {
+		if s == state {
+			return i
+		}
+	}
+	return -1
+}
+
+// receiveOrder aggiunge i semi all'inventario del destinatario e registra la partita ricevuta
+func receiveOrder(tx *sql.Tx, orderID int) error {
+	var senderID, reciverID, seedID, quantity int
+	err := tx.QueryRow(`SELECT o.sender_user_id, o.reciver_user_id, od.seed_id, od.quantity
+		FROM orders o JOIN order_detail od ON o.order_id = od.order_id
+		WHERE o.order_id = ?`, orderID).Scan(&senderID, &reciverID, &seedID, &quantity)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	_, err = tx.Exec(`INSERT INTO users_seed (user_id, seed_id, quantity, restocked_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON DUPLICATE KEY UPDATE quantity = quantity + VALUES(quantity), restocked_at = CURRENT_TIMESTAMP`, reciverID, seedID, quantity)
+	if err != nil {
+		return err
+	}
+
+	// La partita genitore è la più recente del mittente per quel seme
+	_, err = tx.Exec(`INSERT INTO seed_lot (seed_id, user_id, parent_lot_id, source, order_id, quantity, province)
+		SELECT ?, ?, (SELECT lot_id FROM seed_lot WHERE seed_id = ? AND user_id = ? ORDER BY created_at DESC, lot_id DESC LIMIT 1),
+		       'exchange', ?, ?, (SELECT shipping_province FROM orders WHERE order_id = ?)`,
+		seedID, reciverID, seedID, senderID, orderID, quantity, orderID)
+	return err
 }
 
-// ScanRowIntoOrder esegue il binding dei dati di una riga su un oggetto Order
-func ScanRowIntoOrder(rows *sql.Rows) (*types.Order, error) {
+// ScanRowIntoOrder esegue il binding dei dati di una riga su un oggetto Order; extra riceve le
+// colonne che seguono quelle dell'ordine
+func ScanRowIntoOrder(rows *sql.Rows, extra ...any) (*types.Order, error) {
 	// Create a new Order object to fill with row data
 	order := new(types.Order)
 
@@ -235,13 +420,14 @@ func ScanRowIntoOrder(rows *sql.Rows) (*types.Order, error) {
 		orderDate     time.Time
 		orderState    string
 		senderName    string
+		label         string
 		country       string
 		city          string
 		street        string
 		cap           string
 		province      string
 		aptNumber     string
-		number        uint16
+		number        string
 		img           string
 		varietyName   string
 		quantity      int
@@ -249,13 +435,14 @@ func ScanRowIntoOrder(rows *sql.Rows) (*types.Order, error) {
 	)
 
 	// Scan the row into our variables - matched with the column order provided
-	err := rows.Scan(
+	dest := []any{
 		&orderID,       // order_id
 		&senderUserID,  // sender_user_id
 		&reciverUserID, // reciver_user_id
 		&orderDate,     // order_date
 		&orderState,    // state
 		&senderName,    // sender_name
+		&label,         // shipping_label
 		&country,       // state
 		&city,          // city
 		&street,        // street
@@ -267,7 +454,8 @@ func ScanRowIntoOrder(rows *sql.Rows) (*types.Order, error) {
 		&varietyName,   // variety_name
 		&quantity,      // quantity
 		&seedId,
-	)
+	}
+	err := rows.Scan(append(dest, extra...)...)
 
 	if err != nil {
 		return nil, fmt.Errorf("error scanning order row: %w", err)
@@ -283,6 +471,7 @@ func ScanRowIntoOrder(rows *sql.Rows) (*types.Order, error) {
 
 	// Construct the Address
 	order.ReciverAdress = types.Adress{
+		Label:            label,
 		Street:           street,
 		City:             city,
 		Cap:              cap,
@@ -0,0 +1,120 @@
+package profile
+
+import (
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store        types.ProfileStore
+	usersStore   types.UserStore
+	sessionStore *auth.AuthStore
+}
+
+func NewHandler(s types.ProfileStore, us types.UserStore, sessionStore *auth.AuthStore) *Handler {
+	return &Handler{s, us, sessionStore}
+}
+
+func (h *Handler) RegisterRouter(router *mux.Router) {
+	router.HandleFunc("/users/{userID:[0-9]+}", auth.WithOptionalJWTAuth(h.handleProfile, h.usersStore, h.sessionStore)).Methods("GET")
+	router.HandleFunc("/user/seeds/{seedID:[0-9]+}/privacy", auth.WithJWTAuth(h.handleSetPrivacy, h.usersStore, h.sessionStore)).Methods("PUT")
+	router.HandleFunc("/user/privacy", auth.WithJWTAuth(h.handleGetPrivacySettings, h.usersStore, h.sessionStore)).Methods("GET")
+	router.HandleFunc("/user/privacy", auth.WithJWTAuth(h.handleSetPrivacySettings, h.usersStore, h.sessionStore)).Methods("PUT")
+}
+
+// handleProfile restituisce il profilo pubblico, se la visibilità scelta dall'utente lo permette;
+// l'inventario rispetta la visibilità scelta per i semi. Gli account bloccati o cancellati non
+// esistono per gli altri, e l'utente vede sempre il proprio profilo completo
+func (h *Handler) handleProfile(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(mux.Vars(r)["userID"])
+
+	viewerID, err := auth.GetUserIDFromContext(r.Context())
+	member := err == nil
+	if !member {
+		viewerID = 0
+	}
+
+	profile, err := h.store.GetPublicProfile(userID, viewerID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	owner := member && viewerID == profile.ID
+	if owner || profile.Visibility == types.VisibilityPublic || (profile.Visibility == types.VisibilityMembers && member) {
+		profile.Inventory, err = h.store.GetPublicInventory(profile.ID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, profile)
+}
+
+func (h *Handler) handleSetPrivacy(w http.ResponseWriter, r *http.Request) {
+	seedID, _ := strconv.Atoi(mux.Vars(r)["seedID"])
+
+	payload, err := utils.DecodePayload[types.SeedPrivacyPayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	if err := h.store.SetSeedPrivacy(userID, seedID, payload.Private); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, payload)
+}
+
+func (h *Handler) handleGetPrivacySettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	settings, err := h.store.GetPrivacySettings(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, settings)
+}
+
+// handleSetPrivacySettings salva chi vede profilo e inventario e quando chi ordina vede l'indirizzo di chi
+// spedisce: con accepted via e civico compaiono solo dopo che chi spedisce ha accettato l'ordine
+func (h *Handler) handleSetPrivacySettings(w http.ResponseWriter, r *http.Request) {
+	payload, err := utils.DecodePayload[types.PrivacySettings](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	if err := h.store.SetPrivacySettings(userID, payload); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, payload)
+}
@@ -0,0 +1,102 @@
+package profile
+
+import (
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+type mockStore struct {
+	types.ProfileStore
+}
+
+func (m *mockStore) GetPublicProfile(userID, viewerID int) (*types.PublicProfile, error) {
+	visibility := map[int]string{1: types.VisibilityPublic, 2: types.VisibilityMembers, 3: types.VisibilityHidden, 4: types.VisibilityHidden}[userID]
+	if visibility == "" {
+		return nil, fmt.Errorf("user not found")
+	}
+	// il profilo 4 è visibile solo ai membri
+	if userID == 4 && viewerID == 0 {
+		return nil, fmt.Errorf("user not found")
+	}
+	return &types.PublicProfile{ID: userID, Name: "Anna", Province: "Torino", Inventory: []types.PublicSeed{}, Visibility: visibility}, nil
+}
+
+func (m *mockStore) GetPublicInventory(userID int) ([]types.PublicSeed, error) {
+	return []types.PublicSeed{{SeedID: 4, Variety_name: "cuore di bue", Vegetable: "pomodoro", Quantity: 3}}, nil
+}
+
+func TestProfile(t *testing.T) {
+	handler := NewHandler(&mockStore{}, nil, nil)
+
+	get := func(userID, viewer int) (*httptest.ResponseRecorder, map[string]any) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d", userID), nil)
+		if viewer != 0 {
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, viewer))
+		}
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/users/{userID}", handler.handleProfile)
+		router.ServeHTTP(rr, req)
+
+		body := map[string]any{}
+		json.Unmarshal(rr.Body.Bytes(), &body)
+		return rr, body
+	}
+	inventory := func(body map[string]any) int {
+		return len(body["inventory"].([]any))
+	}
+
+	t.Run("should show the public projection only", func(t *testing.T) {
+		rr, body := get(1, 0)
+		if rr.Code != http.StatusOK || inventory(body) != 1 {
+			t.Fatalf("unexpected response %d %s", rr.Code, rr.Body.String())
+		}
+		for _, key := range []string{"email", "adress", "Visibility"} {
+			if _, ok := body[key]; ok {
+				t.Errorf("the profile leaks %q", key)
+			}
+		}
+	})
+
+	t.Run("should show members-only inventories to logged-in users", func(t *testing.T) {
+		if _, body := get(2, 0); inventory(body) != 0 {
+			t.Errorf("anonymous visitors must not see the inventory")
+		}
+		if _, body := get(2, 9); inventory(body) != 1 {
+			t.Errorf("members must see the inventory")
+		}
+		if _, body := get(3, 9); inventory(body) != 0 {
+			t.Errorf("hidden owners must not show their inventory")
+		}
+	})
+
+	t.Run("should show the whole inventory to its owner", func(t *testing.T) {
+		if _, body := get(3, 3); inventory(body) != 1 {
+			t.Errorf("owners must always see their own inventory")
+		}
+	})
+
+	t.Run("should hide members-only profiles from anonymous visitors", func(t *testing.T) {
+		if rr, _ := get(4, 0); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+		if rr, _ := get(4, 9); rr.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %d", http.StatusOK, rr.Code)
+		}
+	})
+
+	t.Run("should return 404 for deleted or blocked users", func(t *testing.T) {
+		if rr, _ := get(5, 9); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package profile
+
+import (
+	"backend/seed-savers/geo"
+	"backend/seed-savers/types"
+	"database/sql"
+	"fmt"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore crea e restituisce un nuovo oggetto Store con il database passato come parametro
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// GetPublicProfile restituisce il profilo pubblico di un utente attivo, senza inventario, se viewerID
+// può vederlo: un profilo per soli membri non esiste per chi non è autenticato (viewerID zero), uno
+// nascosto solo per il suo proprietario. CAP e provincia dell'indirizzo servono solo a ricavare il nome
+// della provincia
+func (s *Store) GetPublicProfile(userID, viewerID int) (*types.PublicProfile, error) {
+	p := &types.PublicProfile{Inventory: make([]types.PublicSeed, 0)}
+	var name, postalCode, province sql.NullString
+	var createdAt sql.NullTime
+
+	err := s.db.QueryRow(`SELECT u.user_id, u.name, u.created_at, u.owner_visibility, a.cap, a.province,
+		(SELECT COUNT(*) FROM orders o WHERE o.sender_user_id = u.user_id AND o.state = 'Arrivato'),
+		(SELECT COUNT(*) FROM orders o WHERE (o.sender_user_id = u.user_id OR o.reciver_user_id = u.user_id) AND o.state = 'Arrivato')
+		FROM users u
+		LEFT JOIN adress a ON a.user_id = u.user_id AND a.is_default
+		WHERE u.user_id = ? AND u.status = 'active'
+		AND (u.profile_visibility = 'public' OR (u.profile_visibility = 'members' AND ? <> 0) OR u.user_id = ?)`,
+		userID, viewerID, viewerID).Scan(
+		&p.ID, &name, &createdAt, &p.Visibility, &postalCode, &province, &p.Reputation, &p.CompletedExchanges)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.Name = name.String
+	if createdAt.Valid {
+		p.MemberSince = &createdAt.Time
+	}
+	if located, ok := geo.Locate(postalCode.String, province.String); ok {
+		p.Province = located.Name
+	}
+	return p, nil
+}
+
+// GetPublicInventory restituisce i semi disponibili dell'utente che non ha segnato come privati
+func (s *Store) GetPublicInventory(userID int) ([]types.PublicSeed, error) {
+	rows, err := s.db.Query(`SELECT s.seed_id, s.variety_name, s.vegetable, us.quantity
+		FROM users_seed us
+		INNER JOIN seed s ON us.seed_id = s.seed_id
+		WHERE us.user_id = ? AND us.quantity > 0 AND us.private = FALSE
+		ORDER BY s.vegetable, s.variety_name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inventory := make([]types.PublicSeed, 0)
+	for rows.Next() {
+		var seed types.PublicSeed
+		if err := rows.Scan(&seed.SeedID, &seed.Variety_name, &seed.Vegetable, &seed.Quantity); err != nil {
+			return nil, err
+		}
+		inventory = append(inventory, seed)
+	}
+
+	return inventory, rows.Err()
+}
+
+// SetSeedPrivacy nasconde o mostra un seme dell'inventario nel profilo pubblico e tra i possessori
+func (s *Store) SetSeedPrivacy(userID, seedID int, private bool) error {
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users_seed WHERE user_id = ? AND seed_id = ?)", userID, seedID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("seed not in your inventory")
+	}
+
+	_, err = s.db.Exec("UPDATE users_seed SET private = ? WHERE user_id = ? AND seed_id = ?", private, userID, seedID)
+	return err
+}
+
+// GetPrivacySettings restituisce le impostazioni sulla privacy dell'utente
+func (s *Store) GetPrivacySettings(userID int) (*types.PrivacySettings, error) {
+	settings := &types.PrivacySettings{}
+	err := s.db.QueryRow("SELECT profile_visibility, owner_visibility, address_disclosure FROM users WHERE user_id = ?", userID).Scan(
+		&settings.ProfileVisibility, &settings.InventoryVisibility, &settings.AddressDisclosure)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user not found")
+	}
+	return settings, err
+}
+
+// SetPrivacySettings salva le impostazioni sulla privacy; la visibilità dell'inventario vale anche tra i possessori
+func (s *Store) SetPrivacySettings(userID int, settings *types.PrivacySettings) error {
+	_, err := s.db.Exec("UPDATE users SET profile_visibility = ?, owner_visibility = ?, address_disclosure = ? WHERE user_id = ?",
+		settings.ProfileVisibility, settings.InventoryVisibility, settings.AddressDisclosure, userID)
+	return err
+}
@@ -0,0 +1,130 @@
+package seed
+
+import (
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RareMaxOwners è il numero massimo di possessori per cui un seme disponibile è considerato raro
+const RareMaxOwners = 3
+
+var pollinationLabels = map[string]string{
+	"self":   "Autoimpollinante",
+	"cross":  "Impollinazione incrociata",
+	"hybrid": "Ibrido F1",
+}
+
+// ParseCatalogFilter legge i filtri dalla query string. Ogni faccetta accetta più valori, ripetendo
+// il parametro (?tag=nano&tag=precoce) oppure separandoli con una virgola. available=true lascia solo
+// i semi che qualcuno può scambiare, rare=true quelli con al massimo RareMaxOwners possessori
+func ParseCatalogFilter(query url.Values) types.CatalogFilter {
+	values := func(key string, normalize func(string) string) []string {
+		out := make([]string, 0)
+		for _, v := range query[key] {
+			for _, part := range strings.Split(v, ",") {
+				if part = normalize(part); part != "" {
+					out = append(out, part)
+				}
+			}
+		}
+		return out
+	}
+
+	return types.CatalogFilter{
+		Vegetables:  values("vegetable", utils.NormalizeName),
+		Tags:        values("tag", utils.Slug),
+		Pollination: values("pollination", func(s string) string { return strings.ToLower(strings.TrimSpace(s)) }),
+		Available:   flag(query.Get("available")),
+		Rare:        flag(query.Get("rare")),
+	}
+}
+
+const (
+	facetNone = iota - 1
+	facetVegetable
+	facetTag
+	facetPollination
+)
+
+// catalogWhere traduce i filtri nella clausola WHERE del catalogo, da usare dopo availabilityJoin.
+// skip è la faccetta di cui ignorare i valori selezionati: i conteggi di una faccetta tengono conto dei
+// filtri sulle altre ma non dei suoi, così si vede quanti risultati darebbe aggiungere un valore alla
+// selezione. extra sono altre condizioni da mettere in AND
+func catalogWhere(filter types.CatalogFilter, skip int, extra ...string) (string, []any) {
+	conditions := append(make([]string, 0, 6+len(extra)), extra...)
+	args := make([]any, 0)
+	in := func(column string, values []string) string {
+		for _, v := range values {
+			args = append(args, v)
+		}
+		return column + " IN (" + strings.TrimSuffix(strings.Repeat("?,", len(values)), ",") + ")"
+	}
+
+	if filter.SeedIDs != nil {
+		if len(filter.SeedIDs) == 0 {
+			conditions = append(conditions, "FALSE")
+		} else {
+			conditions = append(conditions, "seed.seed_id IN ("+strings.TrimSuffix(strings.Repeat("?,", len(filter.SeedIDs)), ",")+")")
+			for _, id := range filter.SeedIDs {
+				args = append(args, id)
+			}
+		}
+	}
+	if filter.Available {
+		conditions = append(conditions, "availability.available_quantity > 0")
+	}
+	if filter.Rare {
+		conditions = append(conditions, "availability.owners BETWEEN 1 AND ?")
+		args = append(args, RareMaxOwners)
+	}
+	if len(filter.Vegetables) > 0 && skip != facetVegetable {
+		//la collation del database ignora maiuscole e accenti, come NormalizeName
+		conditions = append(conditions, in("seed.vegetable", filter.Vegetables))
+	}
+	if len(filter.Tags) > 0 && skip != facetTag {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM seed_tag ft INNER JOIN tag ftt ON ftt.tag_id = ft.tag_id "+
+			"WHERE ft.seed_id = seed.seed_id AND ftt.status = 'curated' AND "+in("ftt.slug", filter.Tags)+")")
+	}
+	if len(filter.Pollination) > 0 && skip != facetPollination {
+		conditions = append(conditions, in("seed.pollination", filter.Pollination))
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// addCount somma count al valore della faccetta, così le varianti di scrittura che il database tiene
+// separate ("Fagiolo" e "fagiolo.") finiscono nello stesso conteggio
+func addCount(counts map[string]*types.FacetCount, value, label string, count int) {
+	if c, ok := counts[value]; ok {
+		c.Count += count
+		return
+	}
+	counts[value] = &types.FacetCount{Value: value, Label: label, Count: count}
+}
+
+// flag legge un parametro booleano; un valore non valido vale come assente
+func flag(v string) bool {
+	b, err := strconv.ParseBool(v)
+	return err == nil && b
+}
+
+func sortedCounts(counts map[string]*types.FacetCount) []types.FacetCount {
+	out := make([]types.FacetCount, 0, len(counts))
+	for _, c := range counts {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out
+}
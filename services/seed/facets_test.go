@@ -0,0 +1,69 @@
+package seed
+
+import (
+	"backend/seed-savers/types"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCatalogWhere(t *testing.T) {
+	filter := ParseCatalogFilter(url.Values{"vegetable": {"fagiolo,ZUCCA"}, "tag": {"Rampicante"}, "pollination": {" Self"}})
+
+	where, args := catalogWhere(filter, facetNone)
+	if !strings.Contains(where, "seed.vegetable IN (?,?)") || !strings.Contains(where, "ftt.slug IN (?)") || !strings.Contains(where, "seed.pollination IN (?)") {
+		t.Errorf("expected every facet to be filtered, got %s", where)
+	}
+	if fmt.Sprint(args) != "[fagiolo zucca rampicante self]" {
+		t.Errorf("unexpected arguments %v", args)
+	}
+
+	// la faccetta ortaggio ignora il proprio filtro ma rispetta quello sui tag
+	where, args = catalogWhere(filter, facetVegetable)
+	if strings.Contains(where, "seed.vegetable") || !strings.Contains(where, "ftt.slug") || fmt.Sprint(args) != "[rampicante self]" {
+		t.Errorf("the vegetable facet must skip only its own filter, got %s %v", where, args)
+	}
+
+	where, args = catalogWhere(filter, facetTag, "seed.pollination <> ''")
+	if strings.Contains(where, "ftt.slug") || !strings.HasPrefix(where, " WHERE seed.pollination <> '' AND ") || len(args) != 3 {
+		t.Errorf("the tag facet must skip only its own filter, got %s %v", where, args)
+	}
+}
+
+func TestCatalogWhereWithoutFilters(t *testing.T) {
+	if where, args := catalogWhere(ParseCatalogFilter(url.Values{}), facetNone); where != "" || len(args) != 0 {
+		t.Errorf("expected no conditions, got %q %v", where, args)
+	}
+}
+
+func TestCatalogWhereAvailability(t *testing.T) {
+	where, args := catalogWhere(ParseCatalogFilter(url.Values{"available": {"true"}, "rare": {"1"}}), facetVegetable)
+	if !strings.Contains(where, "availability.available_quantity > 0") || !strings.Contains(where, "availability.owners BETWEEN 1 AND ?") {
+		t.Errorf("availability must restrict every facet, got %s", where)
+	}
+	if len(args) != 1 || args[0] != RareMaxOwners {
+		t.Errorf("unexpected arguments %v", args)
+	}
+
+	if where, _ := catalogWhere(ParseCatalogFilter(url.Values{"rare": {"forse"}}), facetNone); where != "" {
+		t.Errorf("an invalid flag must be ignored, got %s", where)
+	}
+
+	// nessun seme entro il raggio: il catalogo resta vuoto invece di tornare completo
+	if where, _ := catalogWhere(types.CatalogFilter{SeedIDs: []int{}}, facetNone); where != " WHERE FALSE" {
+		t.Errorf("an empty selection must match nothing, got %s", where)
+	}
+}
+
+func TestAddCount(t *testing.T) {
+	counts := map[string]*types.FacetCount{}
+	addCount(counts, "fagiolo", "Fagiolo", 2)
+	addCount(counts, "fagiolo", "fagiolo.", 1)
+	addCount(counts, "zucca", "Zucca", 4)
+
+	sorted := sortedCounts(counts)
+	if len(sorted) != 2 || sorted[0].Value != "zucca" || sorted[1].Count != 3 || sorted[1].Label != "Fagiolo" {
+		t.Errorf("expected the spellings of fagiolo to be merged, got %+v", sorted)
+	}
+}
@@ -0,0 +1,120 @@
+package seed
+
+import (
+	"backend/seed-savers/geo"
+	"backend/seed-savers/types"
+	"sort"
+)
+
+const (
+	DefaultRadiusKm = 50
+	MaxRadiusKm     = 1500
+
+	DefaultOwnersPageSize = 20
+	MaxOwnersPageSize     = 100
+
+	DefaultCatalogPageSize = 50
+	MaxCatalogPageSize     = 200
+)
+
+// VisibleHolders toglie i possessori che si mostrano solo agli utenti autenticati quando la
+// richiesta è anonima
+func VisibleHolders(holders []types.SeedHolder, member bool) []types.SeedHolder {
+	if member {
+		return holders
+	}
+
+	visible := make([]types.SeedHolder, 0, len(holders))
+	for _, h := range holders {
+		if !h.MembersOnly {
+			visible = append(visible, h)
+		}
+	}
+	return visible
+}
+
+// NearbyOwners trasforma i possessori di un seme nella lista pubblica: solo nome, provincia e una
+// distanza arrotondata dal capoluogo della provincia di origin. Con origin nil la distanza non viene
+// calcolata; con radius > 0 restano solo i possessori collocabili entro il raggio, dal più vicino
+func NearbyOwners(holders []types.SeedHolder, origin *geo.Province, radius, excludeUserID int) []types.NearbyOwner {
+	owners := make([]types.NearbyOwner, 0, len(holders))
+	for _, h := range holders {
+		if h.UserID == excludeUserID {
+			continue
+		}
+
+		owner := types.NearbyOwner{UserID: h.UserID, Name: h.Name, Quantity: h.Quantity}
+		p, located := geo.Locate(h.Cap, h.Province)
+		if located {
+			owner.Province = p.Name
+		}
+
+		if origin != nil {
+			if !located {
+				if radius > 0 {
+					continue
+				}
+			} else {
+				d := geo.ProvinceDistance(origin, p)
+				if radius > 0 && d > radius {
+					continue
+				}
+				owner.DistanceKm = &d
+			}
+		}
+
+		owners = append(owners, owner)
+	}
+
+	if origin != nil {
+		sort.SliceStable(owners, func(i, j int) bool {
+			a, b := owners[i].DistanceKm, owners[j].DistanceKm
+			if a == nil || b == nil {
+				return b == nil && a != nil
+			}
+			return *a < *b
+		})
+	}
+
+	return owners
+}
+
+// SeedDistances restituisce, per ogni seme disponibile entro radius km da origin, la distanza del
+// possessore più vicino (escluso excludeUserID)
+func SeedDistances(holders []types.SeedHolder, origin *geo.Province, radius, excludeUserID int) map[int]int {
+	distances := make(map[int]int)
+	for _, h := range holders {
+		if h.UserID == excludeUserID {
+			continue
+		}
+		p, ok := geo.Locate(h.Cap, h.Province)
+		if !ok {
+			continue
+		}
+
+		d := geo.ProvinceDistance(origin, p)
+		if d > radius {
+			continue
+		}
+		if current, ok := distances[h.SeedID]; !ok || d < current {
+			distances[h.SeedID] = d
+		}
+	}
+	return distances
+}
+
+// NearestFirst ordina gli id dei semi di SeedDistances dal più vicino; a parità di distanza
+// viene prima il seme più vecchio
+func NearestFirst(distances map[int]int) []int {
+	ids := make([]int, 0, len(distances))
+	for id := range distances {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if distances[ids[i]] != distances[ids[j]] {
+			return distances[ids[i]] < distances[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
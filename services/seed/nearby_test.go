@@ -0,0 +1,13 @@
+package seed
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNearestFirst(t *testing.T) {
+	ids := NearestFirst(map[int]int{4: 120, 9: 30, 2: 120, 7: 0})
+	if fmt.Sprint(ids) != "[7 9 2 4]" {
+		t.Errorf("expected the seeds from the nearest, got %v", ids)
+	}
+}
@@ -1,13 +1,17 @@
 package seed
 
 import (
+	"backend/seed-savers/geo"
 	"backend/seed-savers/services/auth"
+	"backend/seed-savers/services/conservation"
 	"backend/seed-savers/types"
 	"backend/seed-savers/utils"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
+	"time"
 
 	"net/http"
 
@@ -18,37 +22,100 @@ type Handler struct {
 	store        types.SeedStore
 	usersStore   types.UserStore
 	sessionStore *auth.AuthStore
+	notifier     types.AvailabilityNotifier
 }
 
-func NewHandler(s types.SeedStore, us types.UserStore, sessionStore *auth.AuthStore) *Handler {
-	return &Handler{s, us, sessionStore}
+func NewHandler(s types.SeedStore, us types.UserStore, sessionStore *auth.AuthStore, notifier types.AvailabilityNotifier) *Handler {
+	return &Handler{s, us, sessionStore, notifier}
 }
 
 func (h *Handler) RegisterRouter(router *mux.Router) {
 	//ogni seme non ha una quantità
-	router.HandleFunc("/seeds", h.handleSeeds).Methods("GET")
-	router.HandleFunc("/create-seed", auth.WithJWTAuth(h.handleCreateSeed, h.usersStore, h.sessionStore)).Methods("POST")
+	router.HandleFunc("/seeds", auth.WithOptionalJWTAuth(h.handleSeeds, h.usersStore, h.sessionStore)).Methods("GET")
+	router.HandleFunc("/create-seed", auth.WithVerifiedEmail(h.handleCreateSeed, h.usersStore, h.sessionStore)).Methods("POST")
 	router.HandleFunc("/update-seed", auth.WithJWTAuth(h.handleUpdateSeed, h.usersStore, h.sessionStore)).Methods("PUT")
 	router.HandleFunc("/seeds/{vegetable}", h.handleGetSeedByVegetable).Methods("GET")
 	router.HandleFunc("/seeds/search/{name}", h.handleSearchSeed).Methods("GET")
-	router.HandleFunc("/seeds-owners/{seedID}", h.handleSeedOwners).Methods("GET")
+	router.HandleFunc("/seeds-owners/{seedID}", auth.WithOptionalJWTAuth(h.handleSeedOwners, h.usersStore, h.sessionStore)).Methods("GET")
+	router.HandleFunc("/user/sharing", auth.WithJWTAuth(h.handleGetOwnerSharing, h.usersStore, h.sessionStore)).Methods("GET")
+	router.HandleFunc("/user/sharing", auth.WithJWTAuth(h.handleSetOwnerSharing, h.usersStore, h.sessionStore)).Methods("PUT")
+	router.HandleFunc("/seeds/{seedID:[0-9]+}/owners", auth.WithOptionalJWTAuth(h.handleNearbyOwners, h.usersStore, h.sessionStore)).Methods("GET")
+	router.HandleFunc("/seeds/{seedID:[0-9]+}", auth.WithJWTAuth(h.handleEditSeed, h.usersStore, h.sessionStore)).Methods("PATCH")
+	router.HandleFunc("/seeds/{seedID:[0-9]+}/revisions", h.handleSeedRevisions).Methods("GET")
+	router.HandleFunc("/seeds/{seedID:[0-9]+}/revisions/{revisionID:[0-9]+}/revert", auth.WithRole(h.handleRevertSeed, h.usersStore, h.sessionStore, types.RoleCurator, types.RoleAdmin)).Methods("POST")
+	router.HandleFunc("/admin/seeds/duplicates", auth.WithRole(h.handleDuplicateSeeds, h.usersStore, h.sessionStore, types.RoleCurator, types.RoleAdmin)).Methods("GET")
+	router.HandleFunc("/admin/seeds/merge", auth.WithRole(h.handleMergeSeeds, h.usersStore, h.sessionStore, types.RoleAdmin)).Methods("POST")
 }
 
-func (h *Handler) handleSeedOwners(w http.ResponseWriter, r *http.Request){
+// handleSeedOwners elenca a pagine (page e limit) chi può scambiare il seme, escluso chi chiede
+// e chi è in vacanza o non si mostra
+func (h *Handler) handleSeedOwners(w http.ResponseWriter, r *http.Request) {
 	seedID, err := strconv.Atoi(mux.Vars(r)["seedID"])
-	if err != nil{
+	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
-	seeds, err := h.store.GetSeedOwnersByID(seedID)
-	if err != nil{
+
+	page, limit, err := utils.ParsePage(r, DefaultOwnersPageSize, MaxOwnersPageSize)
+	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
-	utils.WriteJSON(w, http.StatusOK, seeds)
-	
+
+	if _, err := h.store.GetSeedByID(seedID); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	member := err == nil
+
+	owners, total, err := h.store.GetSeedOwners(seedID, userID, member, limit, (page-1)*limit)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.SeedOwnerPage{Results: owners, Total: total, Page: page, Limit: limit})
+}
+
+func (h *Handler) handleGetOwnerSharing(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	sharing, err := h.store.GetOwnerSharing(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, sharing)
 }
 
+//vacation nasconde l'utente da tutte le liste dei possessori finché non la disattiva
+func (h *Handler) handleSetOwnerSharing(w http.ResponseWriter, r *http.Request) {
+	payload, err := utils.DecodePayload[types.OwnerSharing](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	if err := h.store.SetOwnerSharing(userID, payload); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, payload)
+}
 
 func (h *Handler) handleGetSeedByVegetable(w http.ResponseWriter, r *http.Request) {
 	vegetable := mux.Vars(r)["vegetable"]
@@ -58,29 +125,199 @@ func (h *Handler) handleGetSeedByVegetable(w http.ResponseWriter, r *http.Reques
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
+	if err := h.addBadges(seeds); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
 
 	utils.WriteJSON(w, http.StatusOK, seeds)
 }
 
+// handleSearchSeed cerca una varietà per nome; con near e radius la restituisce solo se è
+// disponibile entro il raggio, con la distanza del possessore più vicino
 func (h *Handler) handleSearchSeed(w http.ResponseWriter, r *http.Request) {
 	variety := mux.Vars(r)["name"]
 
-	seeds, err := h.store.GetSeedByVarieties(variety)
+	origin, radius, status, err := h.nearOrigin(r)
+	if err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	seed, err := h.store.GetSeedByVarieties(variety)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, seeds)
+	if origin != nil && seed != nil {
+		distances, err := h.seedDistances(r, []int{seed.ID}, origin, radius)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		d, ok := distances[seed.ID]
+		if !ok {
+			seed = nil
+		} else {
+			seed.DistanceKm = &d
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, seed)
 }
 
+// handleSeeds restituisce una pagina (page e limit) del catalogo con i filtri a faccette di
+// ParseCatalogFilter; con near restano solo i semi disponibili entro il raggio, dal più vicino
 func (h *Handler) handleSeeds(w http.ResponseWriter, r *http.Request) {
-	seeds, err := h.store.GetSeeds()
+	origin, radius, status, err := h.nearOrigin(r)
+	if err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	page, limit, err := utils.ParsePage(r, DefaultCatalogPageSize, MaxCatalogPageSize)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	filter := ParseCatalogFilter(r.URL.Query())
+	var distances map[int]int
+	if origin != nil {
+		available := filter
+		available.Available = true
+		ids, err := h.store.GetCatalogSeedIDs(available)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		distances, err = h.seedDistances(r, ids, origin, radius)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		filter.SeedIDs = NearestFirst(distances)
+	}
+
+	catalog, err := h.store.BrowseCatalog(filter, limit, (page-1)*limit)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
-	utils.WriteJSON(w, http.StatusOK, seeds)
+	if err := h.addBadges(catalog.Results); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if distances != nil {
+		for i := range catalog.Results {
+			d := distances[catalog.Results[i].ID]
+			catalog.Results[i].DistanceKm = &d
+		}
+	}
+
+	catalog.Page, catalog.Limit = page, limit
+	utils.WriteJSON(w, http.StatusOK, catalog)
+}
+
+// handleNearbyOwners elenca chi ha disponibile il seme; con near=me (o un CAP o una provincia)
+// e radius=km mostra solo chi è entro il raggio, ordinato per distanza
+func (h *Handler) handleNearbyOwners(w http.ResponseWriter, r *http.Request) {
+	seedID, _ := strconv.Atoi(mux.Vars(r)["seedID"])
+
+	origin, radius, status, err := h.nearOrigin(r)
+	if err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	seed, err := h.store.GetSeedByID(seedID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	member := err == nil
+
+	holders, err := h.store.GetSeedHolders(seed.ID, userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, NearbyOwners(VisibleHolders(holders, member), origin, radius, userID))
+}
+
+// seedDistances restituisce, per i semi indicati disponibili entro il raggio, la distanza del possessore
+// più vicino tra quelli che chi chiama può vedere
+func (h *Handler) seedDistances(r *http.Request, seedIDs []int, origin *geo.Province, radius int) (map[int]int, error) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	member := err == nil
+
+	holders, err := h.store.GetAvailableHolders(seedIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return SeedDistances(VisibleHolders(holders, member), origin, radius, userID), nil
+}
+
+// addBadges segna le varietà a rischio o con uno stato di conservazione ufficiale, leggendo solo
+// le scorte dei semi indicati
+func (h *Handler) addBadges(seeds []types.Seed) error {
+	ids := make([]int, 0, len(seeds))
+	for _, seed := range seeds {
+		ids = append(ids, seed.ID)
+	}
+
+	holders, err := h.store.GetHoldings(ids)
+	if err != nil {
+		return err
+	}
+
+	rarity := conservation.Assess(holders, time.Now().Year())
+	for i := range seeds {
+		seeds[i].Badges = conservation.Badges(seeds[i], rarity[seeds[i].ID])
+	}
+	return nil
+}
+
+// nearOrigin legge i parametri near e radius. near=me usa l'indirizzo dell'utente autenticato,
+// altrimenti near può essere un CAP o una provincia
+func (h *Handler) nearOrigin(r *http.Request) (*geo.Province, int, int, error) {
+	near := r.URL.Query().Get("near")
+	if near == "" {
+		return nil, 0, 0, nil
+	}
+
+	radius := DefaultRadiusKm
+	if v := r.URL.Query().Get("radius"); v != "" {
+		km, err := strconv.Atoi(v)
+		if err != nil || km < 1 || km > MaxRadiusKm {
+			return nil, 0, http.StatusBadRequest, fmt.Errorf("radius must be between 1 and %d km", MaxRadiusKm)
+		}
+		radius = km
+	}
+
+	postalCode, province := near, near
+	if near == "me" {
+		userID, err := auth.GetUserIDFromContext(r.Context())
+		if err != nil {
+			return nil, 0, http.StatusUnauthorized, fmt.Errorf("log in to search near you")
+		}
+		postalCode, province, err = h.store.GetUserArea(userID)
+		if err != nil {
+			return nil, 0, http.StatusBadRequest, err
+		}
+	}
+
+	origin, ok := geo.Locate(postalCode, province)
+	if !ok {
+		return nil, 0, http.StatusBadRequest, fmt.Errorf("cannot locate %q, use a CAP or an Italian province", near)
+	}
+
+	return origin, radius, 0, nil
 }
 
 func (h *Handler) handleCreateSeed(w http.ResponseWriter, r *http.Request) {
@@ -100,24 +337,38 @@ func (h *Handler) handleCreateSeed(w http.ResponseWriter, r *http.Request) {
 	}
 
 	//check if seeds already exist in db
-	seed, _ := h.store.GetSeedByVarieties(strings.ToLower(payload.Variety_name))
+	seed, err := h.findSeed(payload.Variety_name, payload.Vegetable)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
 	if seed != nil {
 		seed.Quantity = payload.Quantity
 		err = h.usersStore.RegisterSeed(seed, userID)
 		if err != nil {
 			utils.WriteError(w, http.StatusConflict, fmt.Errorf("you have already registered this seed"))
+			return
 		}
+		h.seedAvailable(seed.ID, userID, payload.Quantity)
 		utils.WriteJSON(w, http.StatusOK, nil)
 		return
 	}
 
-	err = h.store.CreateSeed(payload)
+	err = h.store.CreateSeed(payload, userID)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	seed, _ = h.store.GetSeedByVarieties(strings.ToLower(payload.Variety_name))
+	seed, err = h.findSeed(payload.Variety_name, payload.Vegetable)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if seed == nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("seed not found after creation"))
+		return
+	}
 
 	seed.Quantity = payload.Quantity
 	err = h.usersStore.RegisterSeed(seed, userID)
@@ -125,6 +376,7 @@ func (h *Handler) handleCreateSeed(w http.ResponseWriter, r *http.Request) {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
+	h.seedAvailable(seed.ID, userID, payload.Quantity)
 
 	log.Print(userID, payload)
 	utils.WriteJSON(w, http.StatusOK, nil)
@@ -147,7 +399,11 @@ func (h *Handler) handleUpdateSeed(w http.ResponseWriter, r *http.Request) {
 	}
 
 	//check if seeds already exist in db
-	seed, _ := h.store.GetSeedByVarieties(strings.ToLower(payload.Variety_name))
+	seed, err := h.findSeed(payload.Variety_name, payload.Vegetable)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
 	if seed == nil {
 		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("seed not exist, create one before udate it"))
 		return
@@ -155,6 +411,195 @@ func (h *Handler) handleUpdateSeed(w http.ResponseWriter, r *http.Request) {
 
 	seed.Quantity = payload.Quantity
 
-	h.usersStore.ModifySeedQuantity(seed, userID)
+	//si avvisa solo quando il seme torna disponibile, non a ogni modifica della scorta
+	before := h.store.UserSeedQuantity(userID, seed.ID)
+	if err := h.usersStore.ModifySeedQuantity(seed, userID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if before <= 0 {
+		h.seedAvailable(seed.ID, userID, payload.Quantity)
+	}
 	utils.WriteJSON(w, http.StatusOK, nil)
 }
+
+func (h *Handler) seedAvailable(seedID, userID, quantity int) {
+	if h.notifier != nil && quantity > 0 {
+		h.notifier.SeedAvailable(seedID, userID)
+	}
+}
+
+func (h *Handler) handleEditSeed(w http.ResponseWriter, r *http.Request) {
+	seedID, err := strconv.Atoi(mux.Vars(r)["seedID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	payload, err := utils.DecodePayload[types.UpdateSeedPayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	seed, err := h.store.GetSeedByID(seedID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	//solo chi ha creato il seme, i curatori e gli admin possono modificare il catalogo
+	if seed.CreatedBy != userID && !auth.HasRole(r.Context(), types.RoleCurator, types.RoleAdmin) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only the creator, curators and admins can edit this seed"))
+		return
+	}
+
+	if payload.Description != nil {
+		seed.Description = *payload.Description
+	}
+	if payload.Variety_name != nil {
+		seed.Variety_name = strings.ToLower(*payload.Variety_name)
+	}
+	if payload.Vegetable != nil {
+		seed.Vegetable = *payload.Vegetable
+	}
+	if payload.Image != nil {
+		seed.Image = *payload.Image
+	}
+	if payload.Pollination != nil {
+		seed.Pollination = *payload.Pollination
+	}
+	if payload.Sowing != nil {
+		seed.Sowing = *payload.Sowing
+	}
+
+	if err := h.store.UpdateSeed(seed, userID, 0); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, seed)
+}
+
+func (h *Handler) handleSeedRevisions(w http.ResponseWriter, r *http.Request) {
+	seedID, err := strconv.Atoi(mux.Vars(r)["seedID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	revisions, err := h.store.GetSeedRevisions(seedID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, revisions)
+}
+
+func (h *Handler) handleRevertSeed(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	seedID, _ := strconv.Atoi(vars["seedID"])
+	revisionID, _ := strconv.Atoi(vars["revisionID"])
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	revision, err := h.store.GetSeedRevision(seedID, revisionID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	seed, err := h.store.GetSeedByID(seedID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	seed.Description = revision.Snapshot.Description
+	seed.Variety_name = revision.Snapshot.Variety_name
+	seed.Vegetable = revision.Snapshot.Vegetable
+	seed.Image = revision.Snapshot.Image
+	seed.Sowing = revision.Snapshot.Sowing
+	seed.Pollination = revision.Snapshot.Pollination
+
+	//il ripristino è a sua volta una revisione, così la cronologia non perde nulla
+	if err := h.store.UpdateSeed(seed, userID, revision.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, seed)
+}
+
+// findSeed cerca la stessa varietà dello stesso ortaggio confrontando i nomi normalizzati,
+// così "San Marzano" non viene confuso con "San Marzano Redorta". Restituisce nil se non esiste
+func (h *Handler) findSeed(variety, vegetable string) (*types.Seed, error) {
+	seeds, err := h.store.GetVarietyCandidates(vegetable)
+	if err != nil {
+		return nil, err
+	}
+
+	return findSameVariety(seeds, variety, vegetable), nil
+}
+
+func (h *Handler) handleDuplicateSeeds(w http.ResponseWriter, r *http.Request) {
+	threshold := DefaultDuplicateThreshold
+	if t := r.URL.Query().Get("threshold"); t != "" {
+		parsed, err := strconv.ParseFloat(t, 64)
+		if err != nil || parsed <= 0 || parsed > 1 {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("threshold must be a number between 0 and 1"))
+			return
+		}
+		threshold = parsed
+	}
+
+	var seeds []types.Seed
+	var err error
+	if vegetable := r.URL.Query().Get("vegetable"); vegetable != "" {
+		seeds, err = h.store.GetSeedsByVegetable(vegetable)
+	} else {
+		seeds, err = h.store.GetSeeds()
+	}
+	if errors.Is(err, ErrSeedsNotFound) {
+		utils.WriteJSON(w, http.StatusOK, []types.DuplicateCandidate{})
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, FindDuplicateCandidates(seeds, threshold))
+}
+
+func (h *Handler) handleMergeSeeds(w http.ResponseWriter, r *http.Request) {
+	payload, err := utils.DecodePayload[types.MergeSeedsPayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.store.MergeSeeds(payload.SurvivorID, payload.DuplicateID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	seed, err := h.store.GetSeedByID(payload.SurvivorID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, seed)
+}
@@ -5,6 +5,7 @@ import (
 	"backend/seed-savers/types"
 
 	"bytes"
+	"context"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -12,6 +13,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"slices"
 	"testing"
 
 	"github.com/gorilla/mux"
@@ -23,7 +25,7 @@ func TestUserServiceHandlers(t *testing.T) {
 	mockStore := &mockUserStore{}
 	autMockStore := &auth.AuthStore{Store: sessions.NewCookieStore([]byte{5})}
 	sessionsMock := auth.NewCookieStore(auth.SessionOptions{})
-	handler := NewHandler(mockStore, autMockStore, sessionsMock)
+	handler := NewHandler(mockStore, autMockStore, sessionsMock, nil)
 
 	t.Run("should return seeds list successfully", func(t *testing.T) {
 		req, err := http.NewRequest(http.MethodGet, "/seeds", nil)
@@ -87,28 +89,392 @@ func TestUserServiceHandlers(t *testing.T) {
 			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
 		}
 	})
+
+	t.Run("should not create a duplicate when the catalog cannot be read", func(t *testing.T) {
+		mockStore.candidatesErr = fmt.Errorf("connection reset")
+		defer func() { mockStore.candidatesErr = nil }()
+
+		payload := types.CreateSeedPayload{Description: "rosso", Variety_name: "Tomato", Vegetable: "Tomato", Quantity: 2}
+		marshalled, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/create-seed", bytes.NewBuffer(marshalled))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, 1))
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/create-seed", handler.handleCreateSeed)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("expected status code %d but got %d", http.StatusInternalServerError, rr.Code)
+		}
+	})
+
+	t.Run("should forbid catalog edits from users who did not create the seed", func(t *testing.T) {
+		req := editSeedRequest(t, 3, types.RoleUser, `{"description": "nuova descrizione"}`)
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/seeds/{seedID}", handler.handleEditSeed)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+
+	t.Run("should let curators edit the catalog", func(t *testing.T) {
+		req := editSeedRequest(t, 3, types.RoleCurator, `{"variety_name": "Cuore di Bue Albenga"}`)
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/seeds/{seedID}", handler.handleEditSeed)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %d", http.StatusOK, rr.Code)
+		}
+		if mockStore.updated == nil || mockStore.updated.Variety_name != "cuore di bue albenga" || mockStore.updated.Vegetable != "pomodoro" {
+			t.Errorf("unexpected updated seed %+v", mockStore.updated)
+		}
+	})
+
+	t.Run("should list owners near the caller without the caller", func(t *testing.T) {
+		req := editSeedRequest(t, 2, types.RoleUser, "")
+		req.Method = http.MethodGet
+		req.URL.Path = "/seeds/1/owners"
+		req.URL.RawQuery = "near=me&radius=100"
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/seeds/{seedID}/owners", handler.handleNearbyOwners)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, rr.Code)
+		}
+		var owners []types.NearbyOwner
+		if err := json.Unmarshal(rr.Body.Bytes(), &owners); err != nil {
+			t.Fatal(err)
+		}
+		if len(owners) != 1 || owners[0].UserID != 4 || owners[0].Province != "Cuneo" || owners[0].DistanceKm == nil || *owners[0].DistanceKm%10 != 0 {
+			t.Errorf("expected only the grower from Cuneo with a rounded distance, got %+v", owners)
+		}
+	})
+
+	t.Run("should page the catalog near the caller", func(t *testing.T) {
+		req := editSeedRequest(t, 2, types.RoleUser, "")
+		req.Method = http.MethodGet
+		req.URL.Path = "/seeds"
+		req.URL.RawQuery = "near=me&radius=100&tag=rampicante&page=3&limit=10"
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/seeds", handler.handleSeeds)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, rr.Code)
+		}
+		var page types.CatalogPage
+		if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Results) != 1 || page.Results[0].DistanceKm == nil || page.Page != 3 || page.Limit != 10 {
+			t.Errorf("expected the seed of the grower from Cuneo with its distance, got %+v", page)
+		}
+		if len(mockStore.holdingsQuery) != 1 || mockStore.holdingsQuery[0] != 1 {
+			t.Errorf("expected the rarity of the page's seeds only, got %v", mockStore.holdingsQuery)
+		}
+		if fmt.Sprint(mockStore.catalogQuery) != "[{[] [rampicante] [] false false [1]} 10 20]" {
+			t.Errorf("expected the filters, the nearby seeds and the page to reach the store, got %v", mockStore.catalogQuery)
+		}
+	})
+
+	t.Run("should search a variety within the radius", func(t *testing.T) {
+		search := func(query string) *types.Seed {
+			req := editSeedRequest(t, 2, types.RoleUser, "")
+			req.Method = http.MethodGet
+			req.URL.Path = "/seeds/search/cuore"
+			req.URL.RawQuery = query
+
+			rr := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/seeds/search/{name}", handler.handleSearchSeed)
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected status code %d but got %d", http.StatusOK, rr.Code)
+			}
+			var seed *types.Seed
+			if err := json.Unmarshal(rr.Body.Bytes(), &seed); err != nil {
+				t.Fatal(err)
+			}
+			return seed
+		}
+
+		if seed := search("near=me&radius=100"); seed == nil || seed.DistanceKm == nil {
+			t.Errorf("expected the seed with the distance of the grower from Cuneo, got %+v", seed)
+		}
+		if seed := search("near=73100&radius=50"); seed != nil {
+			t.Errorf("expected no seed within 50 km of Lecce, got %+v", seed)
+		}
+	})
+
+	t.Run("should hide members-only owners from anonymous visitors", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/seeds/1/owners", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/seeds/{seedID}/owners", handler.handleNearbyOwners)
+		router.ServeHTTP(rr, req)
+
+		var owners []types.NearbyOwner
+		if err := json.Unmarshal(rr.Body.Bytes(), &owners); err != nil {
+			t.Fatal(err)
+		}
+		for _, o := range owners {
+			if o.UserID == 3 {
+				t.Errorf("members-only owner listed to an anonymous visitor: %+v", owners)
+			}
+		}
+		if len(owners) != 2 {
+			t.Errorf("expected 2 public owners, got %+v", owners)
+		}
+	})
+
+	t.Run("should page the seed owners by user id", func(t *testing.T) {
+		req := editSeedRequest(t, 7, types.RoleUser, "")
+		req.Method = http.MethodGet
+		req.URL.Path = "/seeds-owners/1"
+		req.URL.RawQuery = "page=3&limit=10"
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/seeds-owners/{seedID}", handler.handleSeedOwners)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, rr.Code)
+		}
+		var page types.SeedOwnerPage
+		if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		// due utenti con lo stesso nome restano distinti
+		if len(page.Results) != 2 || page.Results[0].UserID == page.Results[1].UserID || page.Total != 42 || page.Page != 3 {
+			t.Errorf("unexpected page %+v", page)
+		}
+		if !reflect.DeepEqual(mockStore.ownersQuery, []any{7, true, 10, 20}) {
+			t.Errorf("expected the caller excluded and offset 20, got %v", mockStore.ownersQuery)
+		}
+	})
+
+	t.Run("should reject an oversized owners page", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/seeds-owners/1?limit=1000", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/seeds-owners/{seedID}", handler.handleSeedOwners)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should require login to search near me", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/seeds?near=me", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/seeds", handler.handleSeeds)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %d but got %d", http.StatusUnauthorized, rr.Code)
+		}
+	})
+
+	t.Run("should list no duplicates when no seed matches", func(t *testing.T) {
+		mockStore.vegetableErr = ErrSeedsNotFound
+		defer func() { mockStore.vegetableErr = nil }()
+
+		req, err := http.NewRequest(http.MethodGet, "/admin/seeds/duplicates?vegetable=carota", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/admin/seeds/duplicates", handler.handleDuplicateSeeds)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK || rr.Body.String() != "[]\n" {
+			t.Errorf("expected an empty list but got %d %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("should fail when the duplicates cannot be loaded", func(t *testing.T) {
+		mockStore.vegetableErr = fmt.Errorf("connection refused")
+		defer func() { mockStore.vegetableErr = nil }()
+
+		req, err := http.NewRequest(http.MethodGet, "/admin/seeds/duplicates?vegetable=carota", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/admin/seeds/duplicates", handler.handleDuplicateSeeds)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("expected status code %d but got %d", http.StatusInternalServerError, rr.Code)
+		}
+	})
 }
 
-type mockUserStore struct{}
+func editSeedRequest(t *testing.T, userID int, role string, body string) *http.Request {
+	req, err := http.NewRequest(http.MethodPatch, "/seeds/1", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.WithValue(req.Context(), auth.UserKey, userID)
+	ctx = context.WithValue(ctx, auth.RoleKey, role)
+	return req.WithContext(ctx)
+}
+
+type mockUserStore struct {
+	updated       *types.Seed
+	ownersQuery   []any
+	catalogQuery  []any
+	holdingsQuery []int
+	candidatesErr error
+	vegetableErr  error
+}
 
 // CreateSeed implements types.SeedStore.
-func (m *mockUserStore) CreateSeed(*types.CreateSeedPayload) error {
+func (m *mockUserStore) CreateSeed(seed *types.CreateSeedPayload, creatorID int) error {
+	panic("unimplemented")
+}
+
+// UpdateSeed implements types.SeedStore.
+func (m *mockUserStore) UpdateSeed(seed *types.Seed, editorID, revertedFrom int) error {
+	m.updated = seed
+	return nil
+}
+
+// MergeSeeds implements types.SeedStore.
+func (m *mockUserStore) MergeSeeds(survivorID, duplicateID int) error {
+	panic("unimplemented")
+}
+
+// GetSeedRevisions implements types.SeedStore.
+func (m *mockUserStore) GetSeedRevisions(seedID int) ([]types.SeedRevision, error) {
+	panic("unimplemented")
+}
+
+// GetSeedRevision implements types.SeedStore.
+func (m *mockUserStore) GetSeedRevision(seedID, revisionID int) (*types.SeedRevision, error) {
 	panic("unimplemented")
 }
 
 // GetSeedByID implements types.SeedStore.
 func (m *mockUserStore) GetSeedByID(id int) (*types.Seed, error) {
+	return &types.Seed{ID: id, Variety_name: "cuore di bue", Vegetable: "pomodoro", CreatedBy: 7}, nil
+}
+
+// GetSeedsByIDs implements types.SeedStore.
+func (m *mockUserStore) GetSeedsByIDs(ids []int) (map[int]types.Seed, error) {
 	panic("unimplemented")
 }
 
 // GetSeedByVarieties implements types.SeedStore.
 func (m *mockUserStore) GetSeedByVarieties(varieties string) (*types.Seed, error) {
-	panic("unimplemented")
+	return &types.Seed{ID: 1, Variety_name: varieties, Vegetable: "pomodoro", Quantity: 14}, nil
 }
 
-// GetSeedOwnersByID implements types.SeedStore.
-func (m *mockUserStore) GetSeedOwnersByID(id int) (map[string]int, error) {
-	panic("unimplemented")
+// GetSeedOwners implements types.SeedStore.
+func (m *mockUserStore) GetSeedOwners(seedID, excludeUserID int, member bool, limit, offset int) ([]types.SeedOwner, int, error) {
+	m.ownersQuery = []any{excludeUserID, member, limit, offset}
+	return []types.SeedOwner{
+		{UserID: 2, Name: "Marco", Region: "Piemonte", Reputation: 4, Quantity: 5, Lots: []types.OwnerLot{{ID: 10, Source: types.LotRegistered, Quantity: 5}}},
+		{UserID: 4, Name: "Marco", Region: "Piemonte", Quantity: 1, Lots: []types.OwnerLot{}},
+	}, 42, nil
+}
+
+// GetOwnerSharing implements types.SeedStore.
+func (m *mockUserStore) GetOwnerSharing(userID int) (*types.OwnerSharing, error) {
+	return &types.OwnerSharing{Visibility: types.VisibilityPublic}, nil
+}
+
+// SetOwnerSharing implements types.SeedStore.
+func (m *mockUserStore) SetOwnerSharing(userID int, sharing *types.OwnerSharing) error {
+	return nil
+}
+
+// GetSeedHolders implements types.SeedStore.
+func (m *mockUserStore) GetSeedHolders(seedID, viewerID int) ([]types.SeedHolder, error) {
+	return []types.SeedHolder{
+		{SeedID: seedID, UserID: 2, Name: "Marco", Quantity: 5, Cap: "10121"},
+		{SeedID: seedID, UserID: 3, Name: "Giulia", Quantity: 8, Province: "Palermo", MembersOnly: true},
+		{SeedID: seedID, UserID: 4, Name: "Marco", Quantity: 1, Cap: "12051"},
+	}, nil
+}
+
+// GetAvailableHolders implements types.SeedStore.
+func (m *mockUserStore) GetAvailableHolders(seedIDs []int, viewerID int) ([]types.SeedHolder, error) {
+	holders := make([]types.SeedHolder, 0)
+	for _, id := range seedIDs {
+		h, _ := m.GetSeedHolders(id, viewerID)
+		holders = append(holders, h...)
+	}
+	return holders, nil
+}
+
+// GetCuratedSeedTags implements types.SeedStore.
+func (m *mockUserStore) GetCuratedSeedTags(seedIDs []int) (map[int][]types.Tag, error) {
+	return map[int][]types.Tag{1: {{ID: 1, Slug: "rampicante", Label: "Rampicante"}}}, nil
+}
+
+// BrowseCatalog implements types.SeedStore.
+func (m *mockUserStore) BrowseCatalog(filter types.CatalogFilter, limit, offset int) (*types.CatalogPage, error) {
+	m.catalogQuery = []any{filter, limit, offset}
+	seeds, _ := m.GetSeeds()
+	page := &types.CatalogPage{Results: make([]types.Seed, 0)}
+	for _, seed := range seeds {
+		if filter.SeedIDs == nil || slices.Contains(filter.SeedIDs, seed.ID) {
+			page.Results = append(page.Results, seed)
+		}
+	}
+	page.Total = len(page.Results)
+	return page, nil
+}
+
+// GetCatalogSeedIDs implements types.SeedStore.
+func (m *mockUserStore) GetCatalogSeedIDs(filter types.CatalogFilter) ([]int, error) {
+	return []int{1}, nil
+}
+
+// GetHoldings implements types.SeedStore.
+func (m *mockUserStore) GetHoldings(seedIDs []int) ([]types.SeedHolder, error) {
+	m.holdingsQuery = seedIDs
+	return []types.SeedHolder{{SeedID: 1, UserID: 2, Cap: "10121", LastHarvest: 2019}}, nil
+}
+
+// GetUserArea implements types.SeedStore.
+func (m *mockUserStore) GetUserArea(userID int) (string, string, error) {
+	return "10100", "TO", nil
 }
 
 
@@ -130,9 +496,20 @@ func (m *mockUserStore) GetSeeds() ([]types.Seed, error) {
 	}, nil
 }
 
+// GetVarietyCandidates implements types.SeedStore.
+func (m *mockUserStore) GetVarietyCandidates(vegetable string) ([]types.Seed, error) {
+	if m.candidatesErr != nil {
+		return nil, m.candidatesErr
+	}
+	return m.GetSeeds()
+}
+
 // GetSeedsByVegetable implements types.SeedStore.
 func (m *mockUserStore) GetSeedsByVegetable(vegetable string) ([]types.Seed, error) {
-	panic("unimplemented")
+	if m.vegetableErr != nil {
+		return nil, m.vegetableErr
+	}
+	return m.GetSeeds()
 }
 
 // GetCompleteUserByEmail implements types.UserStore.
@@ -0,0 +1,132 @@
+package seed
+
+import (
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"sort"
+	"strings"
+)
+
+// DefaultDuplicateThreshold è il punteggio minimo perché due varietà siano proposte come doppioni
+const DefaultDuplicateThreshold = 0.85
+
+// Similarity restituisce un punteggio tra 0 e 1 tra due nomi di varietà.
+// Usa il migliore tra la distanza di edit (errori di battitura) e la sovrapposizione delle parole
+// (parole in ordine diverso), calcolati sui nomi normalizzati
+func Similarity(a, b string) float64 {
+	a, b = utils.NormalizeName(a), utils.NormalizeName(b)
+	if a == b {
+		return 1
+	}
+	if a == "" || b == "" {
+		return 0
+	}
+
+	longest := max(len([]rune(a)), len([]rune(b)))
+	editScore := 1 - float64(levenshtein(a, b))/float64(longest)
+
+	// con una sola parola per parte la sovrapposizione non aggiunge informazione
+	tokensA, tokensB := strings.Fields(a), strings.Fields(b)
+	if len(tokensA) < 2 || len(tokensB) < 2 {
+		return editScore
+	}
+
+	return max(editScore, jaccard(tokensA, tokensB))
+}
+
+// FindDuplicateCandidates confronta le varietà dello stesso ortaggio e restituisce
+// le coppie con punteggio almeno pari a threshold, dalla più simile
+func FindDuplicateCandidates(seeds []types.Seed, threshold float64) []types.DuplicateCandidate {
+	byVegetable := make(map[string][]types.Seed)
+	for _, s := range seeds {
+		key := utils.NormalizeName(s.Vegetable)
+		byVegetable[key] = append(byVegetable[key], s)
+	}
+
+	candidates := make([]types.DuplicateCandidate, 0)
+	for _, group := range byVegetable {
+		for i := 0; i < len(group); i++ {
+			for j := i + 1; j < len(group); j++ {
+				score := Similarity(group[i].Variety_name, group[j].Variety_name)
+				if score < threshold {
+					continue
+				}
+
+				// il seme più vecchio è proposto come quello da tenere
+				keep, dup := group[i], group[j]
+				if dup.ID < keep.ID {
+					keep, dup = dup, keep
+				}
+				candidates = append(candidates, types.DuplicateCandidate{Seed: keep, Duplicate: dup, Score: score})
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].Seed.ID < candidates[j].Seed.ID
+	})
+
+	return candidates
+}
+
+// findSameVariety cerca nel catalogo la stessa varietà dello stesso ortaggio, ignorando
+// maiuscole, accenti, punteggiatura e spazi
+func findSameVariety(seeds []types.Seed, variety, vegetable string) *types.Seed {
+	variety, vegetable = utils.NormalizeName(variety), utils.NormalizeName(vegetable)
+	for i := range seeds {
+		if utils.NormalizeName(seeds[i].Variety_name) == variety && utils.NormalizeName(seeds[i].Vegetable) == vegetable {
+			return &seeds[i]
+		}
+	}
+	return nil
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+func jaccard(a, b []string) float64 {
+	set := make(map[string]bool, len(a))
+	for _, t := range a {
+		set[t] = true
+	}
+
+	union := len(set)
+	shared := 0
+	seen := make(map[string]bool, len(b))
+	for _, t := range b {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		if set[t] {
+			shared++
+		} else {
+			union++
+		}
+	}
+
+	return float64(shared) / float64(union)
+}
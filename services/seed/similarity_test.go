@@ -0,0 +1,58 @@
+package seed
+
+import (
+	"backend/seed-savers/types"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	cases := []struct {
+		a, b      string
+		duplicate bool
+	}{
+		{"San Marzano", "san  marzano", true},
+		{"Cuore di Bue", "Cuore di bùe", true},
+		{"Cuor di bue", "Cuore di bue", true},
+		{"Marzano San", "San Marzano", true},
+		{"San Marzano", "San Marzano Redorta", false},
+		{"Borlotto", "Cannellino", false},
+	}
+
+	for _, c := range cases {
+		score := Similarity(c.a, c.b)
+		if (score >= DefaultDuplicateThreshold) != c.duplicate {
+			t.Errorf("Similarity(%q, %q) = %.2f, expected duplicate=%v", c.a, c.b, score, c.duplicate)
+		}
+	}
+}
+
+func TestFindDuplicateCandidates(t *testing.T) {
+	seeds := []types.Seed{
+		{ID: 1, Variety_name: "san marzano", Vegetable: "Pomodoro"},
+		{ID: 2, Variety_name: "san marzano redorta", Vegetable: "pomodoro"},
+		{ID: 3, Variety_name: "San  Marzano", Vegetable: "pomodòro"},
+		{ID: 4, Variety_name: "san marzano", Vegetable: "peperone"},
+	}
+
+	candidates := FindDuplicateCandidates(seeds, DefaultDuplicateThreshold)
+	if len(candidates) != 1 {
+		t.Fatalf("expected 1 candidate but got %d: %+v", len(candidates), candidates)
+	}
+	if candidates[0].Seed.ID != 1 || candidates[0].Duplicate.ID != 3 {
+		t.Errorf("expected seed 3 proposed as duplicate of seed 1, got %+v", candidates[0])
+	}
+}
+
+func TestFindSameVariety(t *testing.T) {
+	seeds := []types.Seed{
+		{ID: 1, Variety_name: "san marzano redorta", Vegetable: "pomodoro"},
+		{ID: 2, Variety_name: "san marzano", Vegetable: "pomodoro"},
+	}
+
+	if seed := findSameVariety(seeds, "San Marzano", "Pomodoro"); seed == nil || seed.ID != 2 {
+		t.Errorf("expected the exact variety to be found, got %+v", seed)
+	}
+	if seed := findSameVariety(seeds, "Marzano", "pomodoro"); seed != nil {
+		t.Errorf("a substring must not match an existing variety, got %+v", seed)
+	}
+}
@@ -1,12 +1,44 @@
 package seed
 
 import (
+	"backend/seed-savers/geo"
 	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 )
 
+// ErrSeedsNotFound indica che nessun seme corrisponde alla ricerca
+var ErrSeedsNotFound = errors.New("seeds not found")
+
+// seedColumns elenca le colonne lette da ScanRowIntoSeed, nello stesso ordine
+const seedColumns = "seed_id, description, img, variety_name, vegetable, created_by, pollination, " +
+	"conservation_status, conservation_source, " + sowingColumns
+
+// sowingColumns sono le colonne dei periodi di semina, nell'ordine usato da scanSowing e sowingValues
+const sowingColumns = "sow_indoor_start, sow_indoor_end, sow_outdoor_start, sow_outdoor_end, " +
+	"transplant_start, transplant_end, harvest_start, harvest_end"
+
+// availableStock seleziona le scorte che contano come disponibili: non private, di account attivi non
+// in vacanza. Vuole users_seed come us e users come u
+const availableStock = "us.quantity > 0 AND us.private = FALSE AND u.status = 'active' AND u.vacation_mode = FALSE"
+
+// PublicStock restringe availableStock a chi mostra l'inventario a tutti, per i dati che escono dal sito
+const PublicStock = availableStock + " AND u.owner_visibility = 'public'"
+
+// availabilityJoin aggiunge a ogni seme la disponibilità aggregata in un'unica query: quantità totale,
+// numero di possessori e ultimo rifornimento, contando solo availableStock. Va letta con availabilityColumns
+const availabilityJoin = ` LEFT JOIN (SELECT us.seed_id AS available_seed_id, SUM(us.quantity) AS available_quantity,
+		COUNT(*) AS owners, MAX(us.restocked_at) AS restocked_at
+		FROM users_seed us INNER JOIN users u ON us.user_id = u.user_id
+		WHERE ` + availableStock + `
+		GROUP BY us.seed_id) availability ON availability.available_seed_id = seed.seed_id `
+
+const availabilityColumns = ", COALESCE(availability.available_quantity, 0), COALESCE(availability.owners, 0), availability.restocked_at"
+
 // Store rappresenta una struttura che gestisce l'accesso al database per i semi
 type Store struct {
 	db *sql.DB
@@ -19,7 +51,7 @@ func NewStore(db *sql.DB) *Store {
 
 // GetSeeds restituisce una lista di tutti i semi nel database
 func (s *Store) GetSeeds() ([]types.Seed, error) {
-	rows, err := s.db.Query("SELECT * FROM seed")
+	rows, err := s.db.Query("SELECT " + seedColumns + availabilityColumns + " FROM seed" + availabilityJoin)
 	if err != nil {
 		return nil, err
 	}
@@ -29,7 +61,7 @@ func (s *Store) GetSeeds() ([]types.Seed, error) {
 
 	// Itera sulle righe restituite dalla query e popola il slice di semi
 	for rows.Next() {
-		seed, err := ScanRowIntoSeed(rows)
+		seed, err := scanListedSeed(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -37,14 +69,15 @@ func (s *Store) GetSeeds() ([]types.Seed, error) {
 	}
 
 	if len(seeds) == 0 {
-		return nil, fmt.Errorf("seeds not found")
+		return nil, ErrSeedsNotFound
 	}
 	return seeds, nil
 }
 
-// GetSeedByID restituisce un seme specifico dato il suo ID
+// GetSeedByID restituisce un seme specifico dato il suo ID. Se il seme è stato unito a un altro
+// restituisce quello sopravvissuto
 func (s *Store) GetSeedByID(id int) (*types.Seed, error) {
-	rows, err := s.db.Query("SELECT * FROM seed WHERE seed_id=?", id)
+	rows, err := s.db.Query("SELECT "+seedColumns+" FROM seed WHERE seed_id = COALESCE((SELECT new_seed_id FROM seed_redirect WHERE old_seed_id = ?), ?)", id, id)
 	if err != nil {
 		return nil, err
 	}
@@ -66,52 +99,412 @@ func (s *Store) GetSeedByID(id int) (*types.Seed, error) {
 	return seed, nil
 }
 
-// GetTotalSeedQuantity restituisce la quantità totale di un seme, sommando le quantità per tutti gli utenti
-func (s *Store) GetTotalSeedQuantity(id int) (int, error) {
-	rows, err := s.db.Query("SELECT SUM(quantity) AS total_quantity FROM users_seed WHERE seed_id = ?", id)
+// GetSeedsByIDs carica con una sola query i semi indicati, seguendo i reindirizzamenti dei semi uniti.
+// La mappa è indicizzata con gli id richiesti; quelli che non esistono mancano
+func (s *Store) GetSeedsByIDs(ids []int) (map[int]types.Seed, error) {
+	seeds := make(map[int]types.Seed, len(ids))
+	if len(ids) == 0 {
+		return seeds, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]any, 0, len(ids)*2)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, args...)
+
+	rows, err := s.db.Query(fmt.Sprintf(`SELECT %s, wanted.requested_id FROM (
+			SELECT seed_id AS requested_id, seed_id AS target_id FROM seed WHERE seed_id IN (%s)
+			UNION ALL
+			SELECT old_seed_id, new_seed_id FROM seed_redirect WHERE old_seed_id IN (%s)
+		) wanted INNER JOIN seed ON seed.seed_id = wanted.target_id`, seedColumns, placeholders, placeholders), args...)
 	if err != nil {
-		return -1, err
+		return nil, err
 	}
 	defer rows.Close()
 
-	var quantity int
-
 	for rows.Next() {
-		err := rows.Scan(&quantity)
+		var requested int
+		seed, err := ScanRowIntoSeed(rows, &requested)
 		if err != nil {
-			return -1, err
+			return nil, err
 		}
+		seeds[requested] = *seed
 	}
-	return quantity, nil
+
+	return seeds, rows.Err()
 }
 
-// GetSeedOwnersByID restituisce una mappa con i nomi degli utenti e le quantità di semi che possiedono
-func (s *Store) GetSeedOwnersByID(id int) (map[string]int, error) {
-	rows, err := s.db.Query("SELECT us.seed_id, u.name, us.quantity FROM users_seed us INNER JOIN users u ON us.user_id = u.user_id WHERE us.seed_id = ?", id)
+// notBlocked esclude i possessori che hanno bloccato chi guarda o che chi guarda ha bloccato;
+// vuole due volte l'id di chi guarda
+const notBlocked = `AND NOT EXISTS (SELECT 1 FROM user_block b
+	WHERE (b.blocker_user_id = ? AND b.blocked_user_id = us.user_id) OR (b.blocker_user_id = us.user_id AND b.blocked_user_id = ?))`
+
+// ownerFilter seleziona chi può comparire tra i possessori: account attivo, quantità disponibile e
+// non privata, non in vacanza, inventario visibile a chi guarda e senza blocchi tra i due
+const ownerFilter = `WHERE us.seed_id = ? AND us.quantity > 0 AND us.private = FALSE AND us.user_id <> ?
+	AND u.status = 'active' AND u.vacation_mode = FALSE AND u.owner_visibility IN (?, ?) ` + notBlocked
+
+// GetSeedOwners restituisce una pagina dei possessori di un seme, dal più affidabile, escluso
+// excludeUserID, e il totale. Con member falso restano solo i possessori visibili a tutti
+func (s *Store) GetSeedOwners(seedID, excludeUserID int, member bool, limit, offset int) ([]types.SeedOwner, int, error) {
+	visibleTo := types.VisibilityPublic
+	if member {
+		visibleTo = types.VisibilityMembers
+	}
+	args := []any{seedID, excludeUserID, types.VisibilityPublic, visibleTo, excludeUserID, excludeUserID}
+
+	var total int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM users_seed us INNER JOIN users u ON us.user_id = u.user_id "+ownerFilter, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.Query(`SELECT us.user_id, u.name, us.quantity, a.cap, a.province,
+		(SELECT COUNT(*) FROM orders o WHERE o.sender_user_id = us.user_id AND o.state = 'Arrivato') AS reputation
+		FROM users_seed us
+		INNER JOIN users u ON us.user_id = u.user_id
+		LEFT JOIN adress a ON a.user_id = us.user_id AND a.is_default `+ownerFilter+`
+		ORDER BY reputation DESC, us.quantity DESC, us.user_id
+		LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	owners := make([]types.SeedOwner, 0)
+	index := make(map[int]int)
+	for rows.Next() {
+		var o types.SeedOwner
+		var postalCode, province sql.NullString
+		if err := rows.Scan(&o.UserID, &o.Name, &o.Quantity, &postalCode, &province, &o.Reputation); err != nil {
+			return nil, 0, err
+		}
+		// solo la regione: CAP e provincia restano privati
+		if p, ok := geo.Locate(postalCode.String, province.String); ok {
+			o.Region = p.Region
+		}
+		o.Lots = []types.OwnerLot{}
+		index[o.UserID] = len(owners)
+		owners = append(owners, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(owners) == 0 {
+		return owners, total, nil
+	}
+
+	// Carica le partite dei possessori della pagina con un'unica query
+	lotArgs := []any{seedID}
+	for _, o := range owners {
+		lotArgs = append(lotArgs, o.UserID)
+	}
+	lots, err := s.db.Query(`SELECT lot_id, user_id, source, quantity, harvest_year FROM seed_lot
+		WHERE seed_id = ? AND quantity > 0 AND user_id IN (`+strings.TrimSuffix(strings.Repeat("?,", len(owners)), ",")+`)
+		ORDER BY created_at DESC, lot_id DESC`, lotArgs...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer lots.Close()
+
+	for lots.Next() {
+		var lot types.OwnerLot
+		var userID int
+		var year sql.NullInt64
+		if err := lots.Scan(&lot.ID, &userID, &lot.Source, &lot.Quantity, &year); err != nil {
+			return nil, 0, err
+		}
+		lot.HarvestYear = int(year.Int64)
+		o := &owners[index[userID]]
+		o.Lots = append(o.Lots, lot)
+	}
+
+	return owners, total, lots.Err()
+}
+
+// GetOwnerSharing restituisce le impostazioni con cui l'utente compare tra i possessori
+func (s *Store) GetOwnerSharing(userID int) (*types.OwnerSharing, error) {
+	sharing := &types.OwnerSharing{}
+	err := s.db.QueryRow("SELECT vacation_mode, owner_visibility FROM users WHERE user_id = ?", userID).Scan(&sharing.Vacation, &sharing.Visibility)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user not found")
+	}
+	return sharing, err
+}
+
+// SetOwnerSharing salva la modalità vacanza e la visibilità dell'utente tra i possessori
+func (s *Store) SetOwnerSharing(userID int, sharing *types.OwnerSharing) error {
+	_, err := s.db.Exec("UPDATE users SET vacation_mode = ?, owner_visibility = ? WHERE user_id = ?", sharing.Vacation, sharing.Visibility, userID)
+	return err
+}
+
+// GetSeedHolders restituisce chi ha disponibile un seme, con CAP e provincia dell'indirizzo (vuoti se manca),
+// tranne chi ha un blocco con viewerID
+func (s *Store) GetSeedHolders(seedID, viewerID int) ([]types.SeedHolder, error) {
+	return s.queryHolders("WHERE us.seed_id = ? AND us.quantity > 0 "+notBlocked, seedID, viewerID, viewerID)
+}
+
+// GetAvailableHolders restituisce le disponibilità dei semi indicati, per la ricerca per distanza,
+// tranne chi ha un blocco con viewerID
+func (s *Store) GetAvailableHolders(seedIDs []int, viewerID int) ([]types.SeedHolder, error) {
+	if len(seedIDs) == 0 {
+		return []types.SeedHolder{}, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(seedIDs)), ",")
+	args := make([]any, 0, len(seedIDs)+2)
+	for _, id := range seedIDs {
+		args = append(args, id)
+	}
+	args = append(args, viewerID, viewerID)
+
+	return s.queryHolders("WHERE us.seed_id IN ("+placeholders+") AND us.quantity > 0 "+notBlocked, args...)
+}
+
+func (s *Store) queryHolders(where string, args ...any) ([]types.SeedHolder, error) {
+	// chi è in vacanza o nascosto, gli account non attivi e le scorte private non compaiono mai
+	rows, err := s.db.Query(`SELECT us.seed_id, us.user_id, u.name, us.quantity, a.cap, a.province, u.owner_visibility = 'members'
+		FROM users_seed us
+		INNER JOIN users u ON us.user_id = u.user_id
+		LEFT JOIN adress a ON a.user_id = us.user_id AND a.is_default `+where+` AND us.private = FALSE AND u.status = 'active'
+		AND u.vacation_mode = FALSE AND u.owner_visibility <> 'hidden'`, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	owners := make(map[string]int)
+	holders := make([]types.SeedHolder, 0)
+	for rows.Next() {
+		var h types.SeedHolder
+		var postalCode, province sql.NullString
+		if err := rows.Scan(&h.SeedID, &h.UserID, &h.Name, &h.Quantity, &postalCode, &province, &h.MembersOnly); err != nil {
+			return nil, err
+		}
+		h.Cap, h.Province = postalCode.String, province.String
+		holders = append(holders, h)
+	}
 
-	var username string
-	var quantity int
+	return holders, rows.Err()
+}
 
-	// Popola la mappa con i dati di ogni proprietario di semi
+// GetHoldings restituisce le scorte disponibili dei semi indicati (di tutti con seedIDs nil), compresi i
+// possessori in vacanza o nascosti, con l'anno del raccolto più recente di ciascuno. Serve a valutare la
+// rarità delle varietà, che per ogni seme dipende solo dalle sue scorte
+func (s *Store) GetHoldings(seedIDs []int) ([]types.SeedHolder, error) {
+	holdings, lots := "", ""
+	args := make([]any, 0, len(seedIDs)*2)
+	if seedIDs != nil {
+		if len(seedIDs) == 0 {
+			return []types.SeedHolder{}, nil
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(seedIDs)), ",")
+		lots, holdings = " AND seed_id IN ("+placeholders+")", " AND us.seed_id IN ("+placeholders+")"
+		for _, id := range seedIDs {
+			args = append(args, id)
+		}
+		args = append(args, args...)
+	}
+
+	rows, err := s.db.Query(`SELECT us.seed_id, us.user_id, u.name, us.quantity, a.cap, a.province, COALESCE(l.last_harvest, 0)
+		FROM users_seed us
+		INNER JOIN users u ON us.user_id = u.user_id
+		LEFT JOIN adress a ON a.user_id = us.user_id AND a.is_default
+		LEFT JOIN (SELECT seed_id, user_id, MAX(COALESCE(harvest_year, YEAR(created_at))) AS last_harvest
+			FROM seed_lot WHERE quantity > 0`+lots+` GROUP BY seed_id, user_id) l ON l.seed_id = us.seed_id AND l.user_id = us.user_id
+		WHERE us.quantity > 0`+holdings, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holders := make([]types.SeedHolder, 0)
 	for rows.Next() {
-		err := rows.Scan(new(int), &username, &quantity)
+		var h types.SeedHolder
+		var postalCode, province sql.NullString
+		if err := rows.Scan(&h.SeedID, &h.UserID, &h.Name, &h.Quantity, &postalCode, &province, &h.LastHarvest); err != nil {
+			return nil, err
+		}
+		h.Cap, h.Province = postalCode.String, province.String
+		holders = append(holders, h)
+	}
+
+	return holders, rows.Err()
+}
+
+// GetUserArea restituisce CAP e provincia dell'indirizzo dell'utente
+func (s *Store) GetUserArea(userID int) (string, string, error) {
+	var postalCode, province string
+	err := s.db.QueryRow("SELECT cap, province FROM adress WHERE user_id = ? AND is_default", userID).Scan(&postalCode, &province)
+	if err == sql.ErrNoRows {
+		return "", "", fmt.Errorf("the user has no address")
+	}
+	return postalCode, province, err
+}
+
+// GetCuratedSeedTags restituisce i tag approvati dei semi indicati, con un'unica query
+func (s *Store) GetCuratedSeedTags(seedIDs []int) (map[int][]types.Tag, error) {
+	tags := make(map[int][]types.Tag)
+	if len(seedIDs) == 0 {
+		return tags, nil
+	}
+
+	args := make([]any, 0, len(seedIDs))
+	for _, id := range seedIDs {
+		args = append(args, id)
+	}
+
+	rows, err := s.db.Query(`SELECT st.seed_id, t.tag_id, t.slug, t.label FROM seed_tag st
+		JOIN tag t ON t.tag_id = st.tag_id WHERE t.status = 'curated' AND st.seed_id IN (`+
+		strings.TrimSuffix(strings.Repeat("?,", len(seedIDs)), ",")+`) ORDER BY t.label`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var seedID int
+		t := types.Tag{Status: types.TagCurated}
+		if err := rows.Scan(&seedID, &t.ID, &t.Slug, &t.Label); err != nil {
+			return nil, err
+		}
+		tags[seedID] = append(tags[seedID], t)
+	}
+
+	return tags, rows.Err()
+}
+
+// BrowseCatalog restituisce una pagina del catalogo filtrato, dal seme più vecchio o nell'ordine di
+// filter.SeedIDs, con i tag approvati dei risultati, il totale e i conteggi delle faccette
+func (s *Store) BrowseCatalog(filter types.CatalogFilter, limit, offset int) (*types.CatalogPage, error) {
+	page := &types.CatalogPage{Results: make([]types.Seed, 0)}
+
+	where, args := catalogWhere(filter, facetNone)
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM seed"+availabilityJoin+where, args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	order := " ORDER BY seed.seed_id"
+	if len(filter.SeedIDs) > 0 {
+		order = " ORDER BY FIELD(seed.seed_id, " + strings.TrimSuffix(strings.Repeat("?,", len(filter.SeedIDs)), ",") + ")"
+		for _, id := range filter.SeedIDs {
+			args = append(args, id)
+		}
+	}
+	args = append(args, limit, offset)
+
+	rows, err := s.db.Query("SELECT "+seedColumns+availabilityColumns+" FROM seed"+availabilityJoin+where+order+" LIMIT ? OFFSET ?", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0, limit)
+	for rows.Next() {
+		seed, err := scanListedSeed(rows)
 		if err != nil {
 			return nil, err
 		}
-		owners[username] = quantity
+		page.Results = append(page.Results, *seed)
+		ids = append(ids, seed.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tags, err := s.GetCuratedSeedTags(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range page.Results {
+		page.Results[i].Tags = make([]string, 0, len(tags[page.Results[i].ID]))
+		for _, t := range tags[page.Results[i].ID] {
+			page.Results[i].Tags = append(page.Results[i].Tags, t.Slug)
+		}
+	}
+
+	where, args = catalogWhere(filter, facetVegetable)
+	page.Facets.Vegetable, err = s.countFacet("SELECT MIN(seed.vegetable), MIN(seed.vegetable), COUNT(*) FROM seed"+
+		availabilityJoin+where+" GROUP BY seed.vegetable", args, func(value, label string) (string, string) {
+		return utils.NormalizeName(value), label
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	where, args = catalogWhere(filter, facetTag)
+	page.Facets.Tag, err = s.countFacet("SELECT t.slug, t.label, COUNT(*) FROM seed"+availabilityJoin+
+		"INNER JOIN seed_tag st ON st.seed_id = seed.seed_id INNER JOIN tag t ON t.tag_id = st.tag_id AND t.status = 'curated'"+
+		where+" GROUP BY t.tag_id, t.slug, t.label", args, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	where, args = catalogWhere(filter, facetPollination, "seed.pollination <> ''")
+	page.Facets.Pollination, err = s.countFacet("SELECT seed.pollination, seed.pollination, COUNT(*) FROM seed"+
+		availabilityJoin+where+" GROUP BY seed.pollination", args, func(value, _ string) (string, string) {
+		return value, pollinationLabels[value]
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// countFacet legge le righe valore, etichetta e conteggio di una faccetta; label, se c'è, trasforma
+// valore ed etichetta prima di sommare i conteggi
+func (s *Store) countFacet(query string, args []any, label func(value, label string) (string, string)) ([]types.FacetCount, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]*types.FacetCount)
+	for rows.Next() {
+		var value, name string
+		var count int
+		if err := rows.Scan(&value, &name, &count); err != nil {
+			return nil, err
+		}
+		if label != nil {
+			value, name = label(value, name)
+		}
+		addCount(counts, value, name, count)
 	}
-	return owners, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sortedCounts(counts), nil
+}
+
+// GetCatalogSeedIDs restituisce gli id di tutti i semi che soddisfano i filtri, per la ricerca per distanza
+func (s *Store) GetCatalogSeedIDs(filter types.CatalogFilter) ([]int, error) {
+	where, args := catalogWhere(filter, facetNone)
+	rows, err := s.db.Query("SELECT seed.seed_id FROM seed"+availabilityJoin+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
 }
 
 // GetSeedByVarieties restituisce un seme che corrisponde al nome della varietà
 func (s *Store) GetSeedByVarieties(varieties string) (*types.Seed, error) {
-	rows, err := s.db.Query("SELECT * FROM seed WHERE seed.variety_name LIKE ?", "%"+varieties+"%")
+	rows, err := s.db.Query("SELECT "+seedColumns+availabilityColumns+" FROM seed"+availabilityJoin+"WHERE seed.variety_name LIKE ?", "%"+varieties+"%")
 	if err != nil {
 		return nil, err
 	}
@@ -120,7 +513,7 @@ func (s *Store) GetSeedByVarieties(varieties string) (*types.Seed, error) {
 	var seed *types.Seed
 
 	for rows.Next() {
-		seed, err = ScanRowIntoSeed(rows)
+		seed, err = scanListedSeed(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -131,7 +524,7 @@ func (s *Store) GetSeedByVarieties(varieties string) (*types.Seed, error) {
 
 // GetSeedsByVegetable restituisce una lista di semi che corrispondono a un determinato tipo di ortaggio
 func (s *Store) GetSeedsByVegetable(vegetable string) ([]types.Seed, error) {
-	rows, err := s.db.Query("SELECT * FROM seed WHERE seed.vegetable LIKE ?", "%"+vegetable+"%")
+	rows, err := s.db.Query("SELECT "+seedColumns+availabilityColumns+" FROM seed"+availabilityJoin+"WHERE seed.vegetable LIKE ?", "%"+vegetable+"%")
 	if err != nil {
 		return nil, err
 	}
@@ -141,7 +534,7 @@ func (s *Store) GetSeedsByVegetable(vegetable string) ([]types.Seed, error) {
 
 	// Aggiunge i semi alla lista
 	for rows.Next() {
-		seed, err := ScanRowIntoSeed(rows)
+		seed, err := scanListedSeed(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -149,13 +542,40 @@ func (s *Store) GetSeedsByVegetable(vegetable string) ([]types.Seed, error) {
 	}
 
 	if len(seeds) == 0 {
-		return nil, fmt.Errorf("seeds not found")
+		return nil, ErrSeedsNotFound
 	}
 	return seeds, nil
 }
 
-// CreateSeed crea un nuovo seme nel database
-func (s *Store) CreateSeed(seedPayload *types.CreateSeedPayload) error {
+// GetVarietyCandidates restituisce i semi il cui ortaggio contiene, in ordine, le parole del nome
+// normalizzato: sono i candidati tra cui cercare la stessa varietà. La collation ignora maiuscole e
+// accenti; senza candidati restituisce una lista vuota, non un errore
+func (s *Store) GetVarietyCandidates(vegetable string) ([]types.Seed, error) {
+	words := strings.Fields(utils.NormalizeName(vegetable))
+	if len(words) == 0 {
+		return []types.Seed{}, nil
+	}
+
+	rows, err := s.db.Query("SELECT "+seedColumns+" FROM seed WHERE vegetable LIKE ?", "%"+strings.Join(words, "%")+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seeds := make([]types.Seed, 0)
+	for rows.Next() {
+		seed, err := ScanRowIntoSeed(rows)
+		if err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, *seed)
+	}
+
+	return seeds, rows.Err()
+}
+
+// CreateSeed crea un nuovo seme nel database registrando chi lo ha inserito nel catalogo
+func (s *Store) CreateSeed(seedPayload *types.CreateSeedPayload, creatorID int) error {
 	// Inizia una transazione
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -164,7 +584,13 @@ func (s *Store) CreateSeed(seedPayload *types.CreateSeedPayload) error {
 	defer tx.Rollback() // Assicura che il rollback venga eseguito in caso di errore
 
 	// Inserisce il seme nel database
-	_, err = tx.Exec("INSERT INTO seed (description, variety_name, vegetable, img) VALUES (?, ?, ?, ?)", seedPayload.Description, strings.ToLower(seedPayload.Variety_name), seedPayload.Vegetable, seedPayload.Image)
+	var sowing types.SowingWindows
+	if seedPayload.Sowing != nil {
+		sowing = *seedPayload.Sowing
+	}
+
+	args := append([]any{seedPayload.Description, strings.ToLower(seedPayload.Variety_name), seedPayload.Vegetable, seedPayload.Image, creatorID, nullString(seedPayload.Pollination)}, sowingValues(sowing)...)
+	_, err = tx.Exec("INSERT INTO seed (description, variety_name, vegetable, img, created_by, pollination, "+sowingColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", args...)
 	if err != nil {
 		return err
 	}
@@ -173,8 +599,9 @@ func (s *Store) CreateSeed(seedPayload *types.CreateSeedPayload) error {
 	return tx.Commit()
 }
 
-// UpdateSeed aggiorna le informazioni di un seme esistente
-func (s *Store) UpdateSeed(seed *types.Seed) error {
+// UpdateSeed aggiorna le informazioni di un seme esistente e salva la revisione con le differenze.
+// revertedFrom indica la revisione ripristinata, 0 se si tratta di una modifica normale
+func (s *Store) UpdateSeed(seed *types.Seed, editorID, revertedFrom int) error {
 	// Inizia una transazione
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -182,16 +609,270 @@ func (s *Store) UpdateSeed(seed *types.Seed) error {
 	}
 	defer tx.Rollback() // Assicura che il rollback venga eseguito in caso di errore
 
+	// Legge lo stato attuale bloccando la riga, così le differenze sono calcolate sui dati veri
+	rows, err := tx.Query("SELECT "+seedColumns+" FROM seed WHERE seed_id=? FOR UPDATE", seed.ID)
+	if err != nil {
+		return err
+	}
+	var current *types.Seed
+	if rows.Next() {
+		current, err = ScanRowIntoSeed(rows)
+	}
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return fmt.Errorf("seed not found")
+	}
+
+	changes := diffCatalogFields(catalogFields(current), catalogFields(seed))
+	if len(changes) == 0 {
+		return nil
+	}
+
+	// Alla prima modifica salva anche la versione originale, così si può sempre tornare indietro
+	var revisions int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM seed_revision WHERE seed_id=?", seed.ID).Scan(&revisions); err != nil {
+		return err
+	}
+	if revisions == 0 {
+		if err := insertRevision(tx, current.ID, current.CreatedBy, catalogFields(current), map[string]types.FieldChange{}, 0); err != nil {
+			return err
+		}
+	}
+
 	// Modifica i dettagli del seme
-	_, err = tx.Exec("UPDATE seed SET description=?, variety_name=?, vegetable=?, img=? WHERE seed_id=?", seed.Description, seed.Variety_name, seed.Vegetable, seed.Image, seed.ID)
+	args := append([]any{seed.Description, seed.Variety_name, seed.Vegetable, seed.Image, nullString(seed.Pollination)}, sowingValues(seed.Sowing)...)
+	_, err = tx.Exec(`UPDATE seed SET description=?, variety_name=?, vegetable=?, img=?, pollination=?,
+		sow_indoor_start=?, sow_indoor_end=?, sow_outdoor_start=?, sow_outdoor_end=?,
+		transplant_start=?, transplant_end=?, harvest_start=?, harvest_end=? WHERE seed_id=?`, append(args, seed.ID)...)
+	if err != nil {
+		return err
+	}
+
+	if err := insertRevision(tx, seed.ID, editorID, catalogFields(seed), changes, revertedFrom); err != nil {
+		return err
+	}
+
+	// Conferma la transazione
+	return tx.Commit()
+}
+
+// GetSeedRevisions restituisce la cronologia delle modifiche di un seme, dalla più recente
+func (s *Store) GetSeedRevisions(seedID int) ([]types.SeedRevision, error) {
+	rows, err := s.db.Query(`SELECT r.revision_id, r.seed_id, r.user_id, u.name, r.created_at, r.changes, r.snapshot, r.reverted_from
+		FROM seed_revision r LEFT JOIN users u ON r.user_id = u.user_id
+		WHERE r.seed_id = ? ORDER BY r.revision_id DESC`, seedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := make([]types.SeedRevision, 0)
+	for rows.Next() {
+		revision, err := ScanRowIntoRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+// GetSeedRevision restituisce una singola revisione di un seme
+func (s *Store) GetSeedRevision(seedID, revisionID int) (*types.SeedRevision, error) {
+	rows, err := s.db.Query(`SELECT r.revision_id, r.seed_id, r.user_id, u.name, r.created_at, r.changes, r.snapshot, r.reverted_from
+		FROM seed_revision r LEFT JOIN users u ON r.user_id = u.user_id
+		WHERE r.seed_id = ? AND r.revision_id = ?`, seedID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, fmt.Errorf("revision not found")
+	}
+
+	return ScanRowIntoRevision(rows)
+}
+
+// MergeSeeds unisce un doppione nel seme sopravvissuto in un'unica transazione: sposta inventari,
+// partite, dettagli degli ordini, foto e revisioni, elimina il doppione e lascia un redirect dal suo vecchio ID
+func (s *Store) MergeSeeds(survivorID, duplicateID int) error {
+	// Inizia una transazione
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Assicura che il rollback venga eseguito in caso di errore
+
+	// Blocca entrambi i semi per tutta la durata dell'unione
+	var found int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM seed WHERE seed_id IN (?, ?) FOR UPDATE", survivorID, duplicateID).Scan(&found); err != nil {
+		return err
+	}
+	if survivorID == duplicateID || found != 2 {
+		return fmt.Errorf("both seeds must exist and be different")
+	}
+
+	// Gli utenti che avevano entrambi i semi sommano le quantità; la scorta resta privata se
+	// almeno una delle due lo era, così l'unione non mostra semi che l'utente aveva nascosto
+	_, err = tx.Exec(`INSERT INTO users_seed (user_id, seed_id, quantity, restocked_at, private)
+		SELECT user_id, ?, quantity, restocked_at, private FROM users_seed WHERE seed_id = ?
+		ON DUPLICATE KEY UPDATE quantity = users_seed.quantity + VALUES(quantity),
+			restocked_at = GREATEST(COALESCE(users_seed.restocked_at, VALUES(restocked_at)), COALESCE(VALUES(restocked_at), users_seed.restocked_at)),
+			private = users_seed.private OR VALUES(private)`, survivorID, duplicateID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM users_seed WHERE seed_id = ?", duplicateID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE order_detail SET seed_id = ? WHERE seed_id = ?", survivorID, duplicateID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE seed_lot SET seed_id = ? WHERE seed_id = ?", survivorID, duplicateID); err != nil {
+		return err
+	}
+	// La cronologia del doppione passa al sopravvissuto invece di sparire con lui
+	if _, err := tx.Exec("UPDATE seed_revision SET seed_id = ? WHERE seed_id = ?", survivorID, duplicateID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT IGNORE INTO wishlist (user_id, seed_id, created_at) SELECT user_id, ?, created_at FROM wishlist WHERE seed_id = ?", survivorID, duplicateID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE alert SET seed_id = ? WHERE seed_id = ?", survivorID, duplicateID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE grow_report SET seed_id = ? WHERE seed_id = ?", survivorID, duplicateID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT IGNORE INTO seed_tag (seed_id, tag_id, added_by, created_at) SELECT ?, tag_id, added_by, created_at FROM seed_tag WHERE seed_id = ?", survivorID, duplicateID); err != nil {
+		return err
+	}
+
+	// Lo stato di conservazione del sopravvissuto prevale, altrimenti si eredita quello del doppione
+	_, err = tx.Exec(`UPDATE seed s JOIN seed d ON d.seed_id = ?
+		SET s.conservation_status = d.conservation_status, s.conservation_source = d.conservation_source, s.conservation_set_by = d.conservation_set_by
+		WHERE s.seed_id = ? AND s.conservation_status IS NULL`, duplicateID, survivorID)
 	if err != nil {
 		return err
 	}
 
+	// Le foto del doppione vengono messe dopo quelle del sopravvissuto
+	var offset int
+	if err := tx.QueryRow("SELECT COALESCE(MAX(position) + 1, 0) FROM seed_image WHERE seed_id = ?", survivorID).Scan(&offset); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE seed_image SET seed_id = ?, position = position + ? WHERE seed_id = ?", survivorID, offset, duplicateID); err != nil {
+		return err
+	}
+
+	// I redirect che puntavano al doppione ora puntano al sopravvissuto, così non si creano catene
+	if _, err := tx.Exec("UPDATE seed_redirect SET new_seed_id = ? WHERE new_seed_id = ?", survivorID, duplicateID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO seed_redirect (old_seed_id, new_seed_id) VALUES (?, ?)", duplicateID, survivorID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM seed WHERE seed_id = ?", duplicateID); err != nil {
+		return err
+	}
+
 	// Conferma la transazione
 	return tx.Commit()
 }
 
+func insertRevision(tx *sql.Tx, seedID, userID int, snapshot types.SeedCatalogFields, changes map[string]types.FieldChange, revertedFrom int) error {
+	snapshotJSON, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO seed_revision (seed_id, user_id, changes, snapshot, reverted_from) VALUES (?, ?, ?, ?, ?)",
+		seedID, nullableID(userID), changesJSON, snapshotJSON, nullableID(revertedFrom))
+	return err
+}
+
+func catalogFields(seed *types.Seed) types.SeedCatalogFields {
+	return types.SeedCatalogFields{
+		Description:  seed.Description,
+		Variety_name: seed.Variety_name,
+		Vegetable:    seed.Vegetable,
+		Image:        seed.Image,
+		Sowing:       seed.Sowing,
+		Pollination:  seed.Pollination,
+	}
+}
+
+// diffCatalogFields confronta due versioni del catalogo e restituisce solo i campi cambiati
+func diffCatalogFields(before, after types.SeedCatalogFields) map[string]types.FieldChange {
+	changes := make(map[string]types.FieldChange)
+	add := func(field, o, n string) {
+		if o != n {
+			changes[field] = types.FieldChange{Old: o, New: n}
+		}
+	}
+
+	add("description", before.Description, after.Description)
+	add("variety_name", before.Variety_name, after.Variety_name)
+	add("vegetable", before.Vegetable, after.Vegetable)
+	add("image", before.Image, after.Image)
+	add("pollination", before.Pollination, after.Pollination)
+	add("sowing.sowIndoor", before.Sowing.SowIndoor.String(), after.Sowing.SowIndoor.String())
+	add("sowing.sowOutdoor", before.Sowing.SowOutdoor.String(), after.Sowing.SowOutdoor.String())
+	add("sowing.transplant", before.Sowing.Transplant.String(), after.Sowing.Transplant.String())
+	add("sowing.harvest", before.Sowing.Harvest.String(), after.Sowing.Harvest.String())
+
+	return changes
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// sowingValues restituisce i valori per sowingColumns, NULL per i periodi non indicati
+func sowingValues(w types.SowingWindows) []any {
+	values := make([]any, 0, 8)
+	for _, r := range []*types.MonthRange{w.SowIndoor, w.SowOutdoor, w.Transplant, w.Harvest} {
+		if r == nil {
+			values = append(values, nil, nil)
+		} else {
+			values = append(values, r.Start, r.End)
+		}
+	}
+	return values
+}
+
+// ScanSowing converte le colonne lette con sowingColumns nei periodi di semina
+func ScanSowing(cols [8]sql.NullInt64) types.SowingWindows {
+	monthRange := func(start, end sql.NullInt64) *types.MonthRange {
+		if !start.Valid || !end.Valid {
+			return nil
+		}
+		return &types.MonthRange{Start: int(start.Int64), End: int(end.Int64)}
+	}
+
+	return types.SowingWindows{
+		SowIndoor:  monthRange(cols[0], cols[1]),
+		SowOutdoor: monthRange(cols[2], cols[3]),
+		Transplant: monthRange(cols[4], cols[5]),
+		Harvest:    monthRange(cols[6], cols[7]),
+	}
+}
+
+func nullableID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id > 0}
+}
+
 func (s *Store) UserSeedQuantity(id, seedId int) int{
 	rows, err := s.db.Query("SELECT quantity FROM users_seed WHERE user_id = ? AND seed_id = ?", id, seedId)
 	if err != nil {
@@ -210,17 +891,28 @@ func (s *Store) UserSeedQuantity(id, seedId int) int{
 }
 
 
-// ScanRowIntoSeed esegue il binding dei dati di una riga su un oggetto Seed
-func ScanRowIntoSeed(rows *sql.Rows) (*types.Seed, error) {
+// ScanRowIntoSeed esegue il binding dei dati di una riga su un oggetto Seed; extra riceve le colonne
+// selezionate dopo seedColumns
+func ScanRowIntoSeed(rows *sql.Rows, extra ...any) (*types.Seed, error) {
 	seed := new(types.Seed)
 	var img sql.NullString
-	err := rows.Scan(
+	var createdBy sql.NullInt64
+	var pollination, conservationStatus, conservationSource sql.NullString
+	var sowing [8]sql.NullInt64
+	dest := []any{
 		&seed.ID,
 		&seed.Description,
 		&img,
 		&seed.Variety_name,
 		&seed.Vegetable,
-	)
+		&createdBy,
+		&pollination,
+		&conservationStatus,
+		&conservationSource,
+		&sowing[0], &sowing[1], &sowing[2], &sowing[3],
+		&sowing[4], &sowing[5], &sowing[6], &sowing[7],
+	}
+	err := rows.Scan(append(dest, extra...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -229,6 +921,61 @@ func ScanRowIntoSeed(rows *sql.Rows) (*types.Seed, error) {
 	if img.Valid {
 		seed.Image = img.String
 	}
+	seed.CreatedBy = int(createdBy.Int64)
+	seed.Pollination = pollination.String
+	seed.ConservationStatus, seed.ConservationSource = conservationStatus.String, conservationSource.String
+	seed.Sowing = ScanSowing(sowing)
 
 	return seed, nil
-}
\ No newline at end of file
+}
+
+// ScanRowIntoRevision esegue il binding dei dati di una riga su un oggetto SeedRevision
+func ScanRowIntoRevision(rows *sql.Rows) (*types.SeedRevision, error) {
+	revision := new(types.SeedRevision)
+	var userID, revertedFrom sql.NullInt64
+	var author sql.NullString
+	var changes, snapshot []byte
+
+	err := rows.Scan(
+		&revision.ID,
+		&revision.SeedID,
+		&userID,
+		&author,
+		&revision.CreatedAt,
+		&changes,
+		&snapshot,
+		&revertedFrom,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	revision.UserID = int(userID.Int64)
+	revision.AuthorName = author.String
+	revision.RevertedFrom = int(revertedFrom.Int64)
+
+	if err := json.Unmarshal(changes, &revision.Changes); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(snapshot, &revision.Snapshot); err != nil {
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+// scanListedSeed legge una riga del catalogo selezionata con availabilityColumns
+func scanListedSeed(rows *sql.Rows) (*types.Seed, error) {
+	var quantity, owners int
+	var restockedAt sql.NullTime
+	seed, err := ScanRowIntoSeed(rows, &quantity, &owners, &restockedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	seed.Quantity, seed.Owners = quantity, owners
+	if restockedAt.Valid {
+		seed.RestockedAt = &restockedAt.Time
+	}
+	return seed, nil
+}
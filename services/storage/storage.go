@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"backend/seed-savers/config"
+	"backend/seed-savers/types"
+	"errors"
+	"fmt"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// New restituisce il backend di storage scelto tramite STORAGE_DRIVER
+func New(cfg config.Config) (types.BlobStorage, error) {
+	switch cfg.StorageDriver {
+	case "local":
+		return NewLocalStorage(cfg.StoragePath)
+	case "s3":
+		return NewS3Storage(S3Options{
+			Endpoint:  cfg.S3Endpoint,
+			Bucket:    cfg.S3Bucket,
+			Region:    cfg.S3Region,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.StorageDriver)
+	}
+}
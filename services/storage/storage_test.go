@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"backend/seed-savers/types"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLocalStorage(t *testing.T) {
+	store, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testBlobStorage(t, store)
+
+	if err := store.Put("../escape.txt", strings.NewReader("x"), "text/plain"); err == nil {
+		t.Errorf("expected an error for a key outside the storage root")
+	}
+}
+
+func TestS3Storage(t *testing.T) {
+	fake := newFakeS3(t)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store := NewS3Storage(S3Options{
+		Endpoint:  server.URL,
+		Bucket:    "seeds",
+		Region:    "eu-south-1",
+		AccessKey: "access",
+		SecretKey: "secret",
+	})
+	store.now = func() time.Time { return time.Date(2024, 9, 10, 14, 0, 0, 0, time.UTC) }
+
+	testBlobStorage(t, store)
+}
+
+func testBlobStorage(t *testing.T, store types.BlobStorage) {
+	t.Helper()
+
+	if err := store.Put("seeds/1/photo.jpg", strings.NewReader("jpeg bytes"), "image/jpeg"); err != nil {
+		t.Fatalf("put failed: %v", err)
+	}
+
+	r, err := store.Get("seeds/1/photo.jpg")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "jpeg bytes" {
+		t.Errorf("expected %q but got %q", "jpeg bytes", data)
+	}
+
+	if err := store.Delete("seeds/1/photo.jpg"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+
+	if _, err := store.Get("seeds/1/photo.jpg"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound after delete but got %v", err)
+	}
+}
+
+// fakeS3 is a minimal in-memory stand-in for an S3-compatible server
+type fakeS3 struct {
+	t       *testing.T
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeS3(t *testing.T) *fakeS3 {
+	return &fakeS3{t: t, objects: map[string][]byte{}}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=access/20240910/eu-south-1/s3/aws4_request") {
+		f.t.Errorf("unexpected authorization header %q", auth)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	sum := sha256.Sum256(body)
+	if r.Header.Get("x-amz-content-sha256") != hex.EncodeToString(sum[:]) {
+		f.t.Errorf("payload hash does not match the body")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[r.URL.Path] = body
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,208 @@
+package tag
+
+import (
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store        types.TagStore
+	seedStore    types.SeedStore
+	usersStore   types.UserStore
+	sessionStore *auth.AuthStore
+}
+
+func NewHandler(s types.TagStore, seedStore types.SeedStore, us types.UserStore, sessionStore *auth.AuthStore) *Handler {
+	return &Handler{s, seedStore, us, sessionStore}
+}
+
+func (h *Handler) RegisterRouter(router *mux.Router) {
+	router.HandleFunc("/tags", h.handleGetTags).Methods("GET")
+	router.HandleFunc("/tags", auth.WithRole(h.handleCreateTag, h.usersStore, h.sessionStore, types.RoleCurator, types.RoleAdmin)).Methods("POST")
+	router.HandleFunc("/seeds/{seedID:[0-9]+}/tags", h.handleGetSeedTags).Methods("GET")
+	router.HandleFunc("/seeds/{seedID:[0-9]+}/tags", auth.WithJWTAuth(h.handleTagSeed, h.usersStore, h.sessionStore)).Methods("POST")
+	router.HandleFunc("/seeds/{seedID:[0-9]+}/tags/{tagID:[0-9]+}", auth.WithJWTAuth(h.handleUntagSeed, h.usersStore, h.sessionStore)).Methods("DELETE")
+	router.HandleFunc("/admin/tags/pending", auth.WithRole(h.handlePendingTags, h.usersStore, h.sessionStore, types.RoleCurator, types.RoleAdmin)).Methods("GET")
+	router.HandleFunc("/admin/tags/{tagID:[0-9]+}/approve", auth.WithRole(h.handleModerateTag(types.TagCurated), h.usersStore, h.sessionStore, types.RoleCurator, types.RoleAdmin)).Methods("POST")
+	router.HandleFunc("/admin/tags/{tagID:[0-9]+}/reject", auth.WithRole(h.handleModerateTag(types.TagRejected), h.usersStore, h.sessionStore, types.RoleCurator, types.RoleAdmin)).Methods("POST")
+}
+
+func (h *Handler) handleGetTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.store.GetTags(types.TagCurated)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, tags)
+}
+
+func (h *Handler) handleCreateTag(w http.ResponseWriter, r *http.Request) {
+	payload, err := utils.DecodePayload[types.TagPayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tag := newTag(payload.Label, types.TagCurated, 0)
+	if tag.Slug == "" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("the label must contain letters or digits"))
+		return
+	}
+	if _, err := h.store.GetTagBySlug(tag.Slug); err == nil {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("tag %q already exists", tag.Slug))
+		return
+	}
+
+	if err := h.store.CreateTag(tag); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, tag)
+}
+
+func (h *Handler) handleGetSeedTags(w http.ResponseWriter, r *http.Request) {
+	seedID, _ := strconv.Atoi(mux.Vars(r)["seedID"])
+
+	seed, err := h.seedStore.GetSeedByID(seedID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	tags, err := h.store.GetSeedTags(seed.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	//i suggerimenti non ancora approvati non sono pubblici
+	curated := make([]types.Tag, 0, len(tags))
+	for _, t := range tags {
+		if t.Status == types.TagCurated {
+			curated = append(curated, t)
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, curated)
+}
+
+// handleTagSeed applica un tag a un seme. Un'etichetta nuova diventa un suggerimento che resta
+// invisibile finché un curatore non la approva; i tag creati dai curatori sono subito approvati
+func (h *Handler) handleTagSeed(w http.ResponseWriter, r *http.Request) {
+	payload, err := utils.DecodePayload[types.TagPayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	seedID, _ := strconv.Atoi(mux.Vars(r)["seedID"])
+	seed, err := h.seedStore.GetSeedByID(seedID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	curator := auth.HasRole(r.Context(), types.RoleCurator, types.RoleAdmin)
+	tag, err := h.store.GetTagBySlug(utils.Slug(payload.Label))
+	if err != nil {
+		status := types.TagPending
+		if curator {
+			status = types.TagCurated
+		}
+		tag = newTag(payload.Label, status, userID)
+		if tag.Slug == "" {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("the label must contain letters or digits"))
+			return
+		}
+		if err := h.store.CreateTag(tag); err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	if tag.Status == types.TagRejected {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("tag %q was rejected by the curators", tag.Slug))
+		return
+	}
+
+	if err := h.store.TagSeed(seed.ID, tag.ID, userID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, tag)
+}
+
+func (h *Handler) handleUntagSeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	seedID, _ := strconv.Atoi(vars["seedID"])
+	tagID, _ := strconv.Atoi(vars["tagID"])
+
+	author, err := h.store.GetTagAuthor(seedID, tagID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	//solo chi ha messo il tag, i curatori e gli admin possono toglierlo
+	if author != userID && !auth.HasRole(r.Context(), types.RoleCurator, types.RoleAdmin) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only who added the tag, curators and admins can remove it"))
+		return
+	}
+
+	if err := h.store.UntagSeed(seedID, tagID); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *Handler) handlePendingTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.store.GetTags(types.TagPending)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, tags)
+}
+
+func (h *Handler) handleModerateTag(status string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tagID, _ := strconv.Atoi(mux.Vars(r)["tagID"])
+
+		if err := h.store.SetTagStatus(tagID, status); err != nil {
+			utils.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, nil)
+	}
+}
+
+func newTag(label, status string, suggestedBy int) *types.Tag {
+	label = strings.Join(strings.Fields(label), " ")
+	return &types.Tag{Slug: utils.Slug(label), Label: label, Status: status, SuggestedBy: suggestedBy}
+}
@@ -0,0 +1,113 @@
+package tag
+
+import (
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+type mockTagStore struct {
+	types.TagStore
+	tags    map[string]*types.Tag
+	created *types.Tag
+	linked  [2]int
+	removed bool
+}
+
+func (m *mockTagStore) GetTagBySlug(slug string) (*types.Tag, error) {
+	if t, ok := m.tags[slug]; ok {
+		return t, nil
+	}
+	return nil, fmt.Errorf("tag not found")
+}
+
+func (m *mockTagStore) CreateTag(tag *types.Tag) error {
+	tag.ID = 99
+	m.created = tag
+	return nil
+}
+
+func (m *mockTagStore) TagSeed(seedID, tagID, userID int) error {
+	m.linked = [2]int{seedID, tagID}
+	return nil
+}
+
+func (m *mockTagStore) GetTagAuthor(seedID, tagID int) (int, error) {
+	return 5, nil
+}
+
+func (m *mockTagStore) UntagSeed(seedID, tagID int) error {
+	m.removed = true
+	return nil
+}
+
+type mockSeedStore struct {
+	types.SeedStore
+}
+
+func (m *mockSeedStore) GetSeedByID(id int) (*types.Seed, error) {
+	return &types.Seed{ID: id}, nil
+}
+
+func newRequest(method, path, body string, userID int, role string) *http.Request {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	ctx := context.WithValue(req.Context(), auth.UserKey, userID)
+	ctx = context.WithValue(ctx, auth.RoleKey, role)
+	return req.WithContext(ctx)
+}
+
+func TestTagHandlers(t *testing.T) {
+	store := &mockTagStore{tags: map[string]*types.Tag{
+		"rampicante": {ID: 1, Slug: "rampicante", Status: types.TagCurated},
+		"gigante":    {ID: 2, Slug: "gigante", Status: types.TagRejected},
+	}}
+	handler := NewHandler(store, &mockSeedStore{}, nil, nil)
+
+	serve := func(req *http.Request, path string, h http.HandlerFunc) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc(path, h)
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should apply a curated tag", func(t *testing.T) {
+		rr := serve(newRequest(http.MethodPost, "/seeds/3/tags", `{"label": "Rampicante"}`, 7, types.RoleUser), "/seeds/{seedID}/tags", handler.handleTagSeed)
+		if rr.Code != http.StatusCreated || store.linked != [2]int{3, 1} {
+			t.Errorf("expected the existing tag to be linked, got %d %v", rr.Code, store.linked)
+		}
+	})
+
+	t.Run("should keep user suggestions pending", func(t *testing.T) {
+		rr := serve(newRequest(http.MethodPost, "/seeds/3/tags", `{"label": "Resistente alla siccità"}`, 7, types.RoleUser), "/seeds/{seedID}/tags", handler.handleTagSeed)
+		if rr.Code != http.StatusCreated || store.created == nil || store.created.Status != types.TagPending || store.created.Slug != "resistente-alla-siccita" {
+			t.Errorf("expected a pending suggestion, got %d %+v", rr.Code, store.created)
+		}
+	})
+
+	t.Run("should refuse rejected tags", func(t *testing.T) {
+		rr := serve(newRequest(http.MethodPost, "/seeds/3/tags", `{"label": "gigante"}`, 7, types.RoleUser), "/seeds/{seedID}/tags", handler.handleTagSeed)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should only let the author or curators remove a tag", func(t *testing.T) {
+		rr := serve(newRequest(http.MethodDelete, "/seeds/3/tags/1", "", 7, types.RoleUser), "/seeds/{seedID}/tags/{tagID}", handler.handleUntagSeed)
+		if rr.Code != http.StatusForbidden || store.removed {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rr.Code)
+		}
+
+		rr = serve(newRequest(http.MethodDelete, "/seeds/3/tags/1", "", 7, types.RoleCurator), "/seeds/{seedID}/tags/{tagID}", handler.handleUntagSeed)
+		if rr.Code != http.StatusOK || !store.removed {
+			t.Errorf("expected curators to remove the tag, got %d", rr.Code)
+		}
+	})
+}
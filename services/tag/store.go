@@ -0,0 +1,149 @@
+package tag
+
+import (
+	"backend/seed-savers/types"
+	"database/sql"
+	"fmt"
+)
+
+// Store gestisce l'accesso al database per i tag dei semi
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore crea e restituisce un nuovo oggetto Store con il database passato come parametro
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+const tagQuery = `SELECT t.tag_id, t.slug, t.label, t.status, t.suggested_by, t.created_at,
+	(SELECT COUNT(*) FROM seed_tag st WHERE st.tag_id = t.tag_id)
+	FROM tag t `
+
+// GetTags restituisce i tag con un certo stato e il numero di semi a cui sono applicati
+func (s *Store) GetTags(status string) ([]types.Tag, error) {
+	return s.queryTags(tagQuery+"WHERE t.status = ? ORDER BY t.label", status)
+}
+
+// GetTagBySlug restituisce un tag dal suo identificatore
+func (s *Store) GetTagBySlug(slug string) (*types.Tag, error) {
+	return s.queryTag(tagQuery+"WHERE t.slug = ?", slug)
+}
+
+// GetTagByID restituisce un tag dal suo ID
+func (s *Store) GetTagByID(id int) (*types.Tag, error) {
+	return s.queryTag(tagQuery+"WHERE t.tag_id = ?", id)
+}
+
+// CreateTag inserisce un nuovo tag
+func (s *Store) CreateTag(tag *types.Tag) error {
+	res, err := s.db.Exec("INSERT INTO tag (slug, label, status, suggested_by) VALUES (?, ?, ?, ?)",
+		tag.Slug, tag.Label, tag.Status, sql.NullInt64{Int64: int64(tag.SuggestedBy), Valid: tag.SuggestedBy != 0})
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	tag.ID = int(id)
+
+	return nil
+}
+
+// SetTagStatus approva o rifiuta un tag; un tag rifiutato viene tolto da tutti i semi
+func (s *Store) SetTagStatus(id int, status string) error {
+	// Inizia una transazione
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Assicura che il rollback venga eseguito in caso di errore
+
+	res, err := tx.Exec("UPDATE tag SET status = ? WHERE tag_id = ?", status, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("tag not found or already %s", status)
+	}
+
+	if status == types.TagRejected {
+		if _, err := tx.Exec("DELETE FROM seed_tag WHERE tag_id = ?", id); err != nil {
+			return err
+		}
+	}
+
+	// Conferma la transazione
+	return tx.Commit()
+}
+
+// GetSeedTags restituisce tutti i tag di un seme, compresi quelli in attesa di moderazione
+func (s *Store) GetSeedTags(seedID int) ([]types.Tag, error) {
+	return s.queryTags(tagQuery+"JOIN seed_tag x ON x.tag_id = t.tag_id WHERE x.seed_id = ? ORDER BY t.label", seedID)
+}
+
+// TagSeed applica un tag a un seme; applicarlo di nuovo non è un errore
+func (s *Store) TagSeed(seedID, tagID, userID int) error {
+	_, err := s.db.Exec("INSERT IGNORE INTO seed_tag (seed_id, tag_id, added_by) VALUES (?, ?, ?)", seedID, tagID, userID)
+	return err
+}
+
+// UntagSeed toglie un tag da un seme
+func (s *Store) UntagSeed(seedID, tagID int) error {
+	res, err := s.db.Exec("DELETE FROM seed_tag WHERE seed_id = ? AND tag_id = ?", seedID, tagID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("the seed does not have this tag")
+	}
+	return nil
+}
+
+// GetTagAuthor restituisce chi ha applicato il tag al seme (0 se l'utente è stato eliminato)
+func (s *Store) GetTagAuthor(seedID, tagID int) (int, error) {
+	var addedBy sql.NullInt64
+	err := s.db.QueryRow("SELECT added_by FROM seed_tag WHERE seed_id = ? AND tag_id = ?", seedID, tagID).Scan(&addedBy)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("the seed does not have this tag")
+	}
+	return int(addedBy.Int64), err
+}
+
+func (s *Store) queryTag(query string, args ...any) (*types.Tag, error) {
+	tags, err := s.queryTags(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("tag not found")
+	}
+	return &tags[0], nil
+}
+
+func (s *Store) queryTags(query string, args ...any) ([]types.Tag, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]types.Tag, 0)
+	for rows.Next() {
+		var t types.Tag
+		var suggestedBy sql.NullInt64
+		if err := rows.Scan(&t.ID, &t.Slug, &t.Label, &t.Status, &suggestedBy, &t.CreatedAt, &t.Seeds); err != nil {
+			return nil, err
+		}
+		t.SuggestedBy = int(suggestedBy.Int64)
+		tags = append(tags, t)
+	}
+
+	return tags, rows.Err()
+}
@@ -0,0 +1,146 @@
+package user
+
+import (
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/sessions"
+)
+
+func TestAccountSettings(t *testing.T) {
+	hash, _ := auth.HashPassword("old-password")
+	users := &accountUserStore{user: &types.User{ID: 7, Name: "Anna", Email: "anna@mail.com", Password: hash}}
+	accounts := &mockAccountStore{}
+	handler := NewHandler(users, accounts, &auth.AuthStore{Store: sessions.NewCookieStore([]byte{5})})
+	var mails []string
+	handler.send = func(to string, msg []byte) error {
+		mails = append(mails, to)
+		return nil
+	}
+
+	serve := func(method, path string, payload any, handlerFunc http.HandlerFunc) *httptest.ResponseRecorder {
+		marshalled, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(marshalled))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, 7))
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc(path, handlerFunc)
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should update the name", func(t *testing.T) {
+		rr := serve(http.MethodPatch, "/user/me", map[string]string{"name": "  Anna Rossi "}, handler.handleUpdateMe)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, rr.Code)
+		}
+		if accounts.names[7] != "Anna Rossi" {
+			t.Errorf("expected the trimmed name to be saved, got %q", accounts.names[7])
+		}
+	})
+
+	t.Run("should refuse a blank name", func(t *testing.T) {
+		rr := serve(http.MethodPatch, "/user/me", map[string]string{"name": "   "}, handler.handleUpdateMe)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should require the current password", func(t *testing.T) {
+		payload := types.ChangePasswordPayload{CurrentPassword: "wrong", NewPassword: "new-password"}
+		rr := serve(http.MethodPost, "/user/me/password", payload, handler.handleChangePassword)
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rr.Code)
+		}
+		if accounts.version != 0 {
+			t.Errorf("the password should not change")
+		}
+	})
+
+	t.Run("should change the password and renew the session", func(t *testing.T) {
+		payload := types.ChangePasswordPayload{CurrentPassword: "old-password", NewPassword: "new-password"}
+		rr := serve(http.MethodPost, "/user/me/password", payload, handler.handleChangePassword)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, rr.Code)
+		}
+		if accounts.version != 1 || !strings.Contains(rr.Header().Get("Set-Cookie"), auth.SessionName) {
+			t.Errorf("expected a new token version and session cookie")
+		}
+	})
+
+	t.Run("should report a session that cannot be saved", func(t *testing.T) {
+		failing := NewHandler(users, accounts, &auth.AuthStore{Store: brokenSessionStore{}})
+		payload := types.ChangePasswordPayload{CurrentPassword: "old-password", NewPassword: "newer-password"}
+		rr := serve(http.MethodPost, "/user/me/password", payload, failing.handleChangePassword)
+		if rr.Code != http.StatusInternalServerError || !strings.Contains(rr.Body.String(), "session") {
+			t.Errorf("expected status code %d with the error, got %d %q", http.StatusInternalServerError, rr.Code, rr.Body)
+		}
+	})
+
+	t.Run("should confirm the new email and notify the old one", func(t *testing.T) {
+		payload := types.ChangeEmailPayload{Email: "anna.rossi@mail.com", Password: "old-password"}
+		rr := serve(http.MethodPost, "/user/me/email", payload, handler.handleChangeEmail)
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("expected status code %d but got %d", http.StatusAccepted, rr.Code)
+		}
+		if fmt.Sprint(mails) != "[anna.rossi@mail.com anna@mail.com]" {
+			t.Errorf("unexpected recipients %v", mails)
+		}
+		link := accounts.links[len(accounts.links)-1]
+		if link.Purpose != types.EmailChange || link.Email != "anna.rossi@mail.com" || link.UserID != 7 {
+			t.Errorf("unexpected link %+v", link)
+		}
+	})
+
+	t.Run("should refuse an email already in use", func(t *testing.T) {
+		payload := types.ChangeEmailPayload{Email: "taken@mail.com", Password: "old-password"}
+		rr := serve(http.MethodPost, "/user/me/email", payload, handler.handleChangeEmail)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+}
+
+type accountUserStore struct {
+	mockUserStore
+	user *types.User
+}
+
+func (m *accountUserStore) GetUserByID(id int) (*types.User, error) {
+	if id != m.user.ID {
+		return nil, fmt.Errorf("user not found")
+	}
+	return m.user, nil
+}
+
+func (m *accountUserStore) GetUserByEmail(email string) (*types.User, error) {
+	if email == "taken@mail.com" {
+		return &types.User{ID: 9, Email: email}, nil
+	}
+	return nil, fmt.Errorf("user not found")
+}
+
+// brokenSessionStore non riesce mai a salvare la sessione
+type brokenSessionStore struct{}
+
+func (brokenSessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.NewSession(brokenSessionStore{}, name), nil
+}
+
+func (brokenSessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.NewSession(brokenSessionStore{}, name), nil
+}
+
+func (brokenSessionStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	return fmt.Errorf("the session store is unavailable")
+}
@@ -9,7 +9,9 @@ import (
 
 	"context"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,22 +21,27 @@ import (
 
 type Handler struct {
 	store        types.UserStore
+	accounts     types.AccountStore
 	sessionStore *auth.AuthStore
+	send         func(to string, msg []byte) error
 }
 
-func NewHandler(store types.UserStore, session *auth.AuthStore) *Handler {
-	return &Handler{store: store, sessionStore: session}
+func NewHandler(store types.UserStore, accounts types.AccountStore, session *auth.AuthStore) *Handler {
+	return &Handler{store: store, accounts: accounts, sessionStore: session, send: email.SendMail}
 }
 
 func (h *Handler) RegisterRouter(router *mux.Router) {
 
 	router.HandleFunc("/login", h.handleLogin).Methods("POST")
 	router.HandleFunc("/register", h.handleRegister).Methods("POST")
-	router.HandleFunc("/register/adress", auth.WithJWTAuth(h.handlePOSTAdress, h.store, h.sessionStore)).Methods("POST")
-	router.HandleFunc("/register/adress", auth.WithJWTAuth(h.handlePUTAdress, h.store, h.sessionStore)).Methods("PUT")
-	router.HandleFunc("/user/delete", auth.WithJWTAuth(h.handleDeleteUser, h.store, h.sessionStore)).Methods("DELETE")
+	router.HandleFunc("/user/me", auth.WithJWTAuth(h.handleMe, h.store, h.sessionStore)).Methods("GET")
+	router.HandleFunc("/user/me", auth.WithJWTAuth(h.handleUpdateMe, h.store, h.sessionStore)).Methods("PATCH")
+	router.HandleFunc("/user/me/password", auth.WithJWTAuth(h.handleChangePassword, h.store, h.sessionStore)).Methods("POST")
+	router.HandleFunc("/user/me/email", auth.WithJWTAuth(h.handleChangeEmail, h.store, h.sessionStore)).Methods("POST")
 	router.HandleFunc("/user/reset", h.handleResetSendEmail).Methods(http.MethodPost)
 	router.HandleFunc("/user/reset/{encripted:.*}", h.handleResetPassword).Methods(http.MethodPost)
+	router.HandleFunc("/user/verify/resend", auth.WithJWTAuth(h.handleResendVerification, h.store, h.sessionStore)).Methods(http.MethodPost)
+	router.HandleFunc("/user/verify/{token}", h.handleVerifyEmail).Methods(http.MethodGet)
 
 	//login whith google
 	router.HandleFunc("/auth/{provider}", h.HandleAuthProvider).Methods("GET")
@@ -111,9 +118,9 @@ func (h *Handler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
-	user.Password = hashPassword
 
-	err = h.store.ModifyUser(user)
+	//come ogni cambio di password, il recupero chiude le sessioni aperte
+	_, err = h.accounts.ChangePassword(user.ID, hashPassword)
 	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
 		return
@@ -122,28 +129,26 @@ func (h *Handler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
 
 }
 
-func (h *Handler) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
-
+// handleMe restituisce l'utente autenticato con indirizzo, inventario, crediti e ordini aperti
+func (h *Handler) handleMe(w http.ResponseWriter, r *http.Request) {
 	id, err := auth.GetUserIDFromContext(r.Context())
-
 	if err != nil {
-		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("permission denied"))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
-	err = h.store.DeleteUserByID(id)
-
+	user, err := h.store.GetCompleteUserByID(id)
 	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, err)
+		utils.WriteError(w, http.StatusNotFound, err)
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, nil)
+	utils.WriteJSON(w, http.StatusOK, user)
 }
 
-func (h *Handler) handlePOSTAdress(w http.ResponseWriter, r *http.Request) {
-
-	payload, err := utils.DecodePayload[types.AdressPayload](w, r)
+// handleUpdateMe modifica i dati del profilo; email e password hanno percorsi dedicati
+func (h *Handler) handleUpdateMe(w http.ResponseWriter, r *http.Request) {
+	payload, err := utils.DecodePayload[types.UserUpdatePayload](w, r)
 	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
 		return
@@ -151,58 +156,143 @@ func (h *Handler) handlePOSTAdress(w http.ResponseWriter, r *http.Request) {
 
 	id, err := auth.GetUserIDFromContext(r.Context())
 	if err != nil {
-		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("permission denied"))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
-	err = h.store.CreateAdress(&types.Adress{
-		ID:               id,
-		Country:          payload.State,
-		Street:           payload.Street,
-		City:             payload.City,
-		Cap:              payload.Cap,
-		Province:         payload.Province,
-		Apartment_number: payload.Apartment_number,
-		Number:           payload.Number,
-	})
+	if payload.Name != nil {
+		name := strings.TrimSpace(*payload.Name)
+		if name == "" {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("the name can't be empty"))
+			return
+		}
+		if err := h.accounts.UpdateProfile(id, name); err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	h.handleMe(w, r)
+}
 
+// handleChangePassword cambia la password conoscendo quella attuale. Le altre sessioni decadono,
+// quella della richiesta riceve un token nuovo
+func (h *Handler) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	payload, err := utils.DecodePayload[types.ChangePasswordPayload](w, r)
 	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusCreated, nil)
-}
+	u, ok := h.currentUser(w, r, payload.CurrentPassword)
+	if !ok {
+		return
+	}
 
-func (h *Handler) handlePUTAdress(w http.ResponseWriter, r *http.Request) {
+	hashPassword, err := auth.HashPassword(payload.NewPassword)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
 
-	payload, err := utils.DecodePayload[types.AdressPayload](w, r)
+	version, err := h.accounts.ChangePassword(u.ID, hashPassword)
 	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, err)
+		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	id, err := auth.GetUserIDFromContext(r.Context())
+	token, err := auth.CreateJWT([]byte(config.Envs.JWTSecret), uint64(u.ID), version)
 	if err != nil {
-		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("permission denied"))
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.sessionStore.StoreUserSession(w, r, token); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
-	err = h.store.ModifyAdress(&types.Adress{
-		ID:               id,
-		Country:            payload.State,
-		Street:           payload.Street,
-		City:             payload.City,
-		Cap:              payload.Cap,
-		Province:         payload.Province,
-		Apartment_number: payload.Apartment_number,
-		Number:           payload.Number,
-	})
 
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// handleChangeEmail avvia il cambio email: l'indirizzo cambia solo quando l'utente apre il link
+// inviato al nuovo indirizzo, mentre quello attuale riceve un avviso
+func (h *Handler) handleChangeEmail(w http.ResponseWriter, r *http.Request) {
+	payload, err := utils.DecodePayload[types.ChangeEmailPayload](w, r)
 	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
-	utils.WriteJSON(w, http.StatusCreated, nil)
+
+	u, ok := h.currentUser(w, r, payload.Password)
+	if !ok {
+		return
+	}
+
+	if strings.EqualFold(u.Email, payload.Email) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("the new email is the same as the current one"))
+		return
+	}
+	if _, err := h.store.GetUserByEmail(payload.Email); err == nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("user with this email: %s already exist", payload.Email))
+		return
+	}
+	if h.throttled(w, u.ID) {
+		return
+	}
+
+	if err := h.sendEmailChange(u, payload.Email); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, nil)
+}
+
+// currentUser carica l'utente autenticato e controlla la password attuale, scrivendo l'errore se non va bene.
+// Gli account creati con un provider OAuth non hanno una password da confermare
+func (h *Handler) currentUser(w http.ResponseWriter, r *http.Request, password string) (*types.User, bool) {
+	id, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return nil, false
+	}
+
+	u, err := h.store.GetUserByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return nil, false
+	}
+
+	if u.Password == "OAUTH" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("accounts created with an external provider have no password"))
+		return nil, false
+	}
+	if !auth.ComparePasswords(u.Password, []byte(password)) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("the current password is wrong"))
+		return nil, false
+	}
+
+	return u, true
+}
+
+// throttled applica l'intervallo minimo e il limite giornaliero agli invii di link email all'utente,
+// rispondendo 429 quando bisogna aspettare
+func (h *Handler) throttled(w http.ResponseWriter, userID int) bool {
+	now := time.Now()
+	sent, err := h.accounts.GetEmailVerificationsSince(userID, now.Add(-24*time.Hour))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return true
+	}
+
+	interval := time.Duration(config.Envs.VerificationResendSecs) * time.Second
+	if wait := resendWait(sent, now, interval, int(config.Envs.VerificationDailyLimit)); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		utils.WriteError(w, http.StatusTooManyRequests, fmt.Errorf("an email was sent recently, retry later"))
+		return true
+	}
+
+	return false
 }
 
 func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
@@ -224,15 +314,22 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if u.Status == types.UserBlocked {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("this account has been suspended"))
+		return
+	}
+
 	secret := []byte(config.Envs.JWTSecret)
-	token, err := auth.CreateJWT(secret, uint64(u.ID))
+	token, err := auth.CreateJWT(secret, uint64(u.ID), u.TokenVersion)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
 	//utils.WriteJSON(w, http.StatusOK, map[string]string{"token": token})
-	h.sessionStore.StoreUserSession(w, r, token)
+	if err := h.sessionStore.StoreUserSession(w, r, token); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
 }
 
 func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
@@ -258,18 +355,73 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 	}
 
 	//if it doesn't exist create a new user
-	err = h.store.CreateUser(&types.User{
+	u := &types.User{
 		Email:    payload.Email,
 		Password: hashPassword,
 		Name:     payload.Name,
-	})
+	}
+	err = h.store.CreateUser(u)
 
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	//l'account esiste comunque: se l'invio fallisce l'utente può chiedere un nuovo link
+	if err := h.sendVerification(u); err != nil {
+		log.Printf("failed to send the verification email to user %d: %v", u.ID, err)
 	}
 	utils.WriteJSON(w, http.StatusCreated, nil)
 }
 
+// handleVerifyEmail conferma l'indirizzo email con il link ricevuto, che sia la verifica dopo la
+// registrazione o il nuovo indirizzo scelto dall'utente; ogni link vale una sola volta
+func (h *Handler) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	v, err := parseVerificationToken(mux.Vars(r)["token"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.accounts.ConsumeEmailVerification(v); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// handleResendVerification invia un nuovo link di verifica, rispettando l'intervallo minimo tra due
+// invii e il limite giornaliero
+func (h *Handler) handleResendVerification(w http.ResponseWriter, r *http.Request) {
+	id, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	u, err := h.store.GetUserByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if u.EmailVerifiedAt != nil {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("the email address is already verified"))
+		return
+	}
+
+	if h.throttled(w, u.ID) {
+		return
+	}
+
+	if err := h.sendVerification(u); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, nil)
+}
+
 func (h *Handler) HandleAuthCallbackFunction(w http.ResponseWriter, r *http.Request) {
 
 	provider := mux.Vars(r)["provider"]
@@ -280,24 +432,40 @@ func (h *Handler) HandleAuthCallbackFunction(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	//il provider può garantire che l'email è verificata, in quel caso non serve il nostro link
+	var verifiedAt *time.Time
+	if verifiedByProvider(user.RawData) {
+		now := time.Now()
+		verifiedAt = &now
+	}
+
 	//check if user exist in db
-	_, err = h.store.GetUserByEmail(user.Email)
+	existing, err := h.store.GetUserByEmail(user.Email)
 	if err != nil {
 		//if not exist create user in db
-		h.store.CreateUser(&types.User{Email: user.Email, Name: user.NickName, Password: "OAUTH"})
+		h.store.CreateUser(&types.User{Email: user.Email, Name: user.NickName, Password: "OAUTH", EmailVerifiedAt: verifiedAt})
+	} else if existing.EmailVerifiedAt == nil && verifiedAt != nil {
+		h.accounts.MarkEmailVerified(existing.ID)
 	}
 
 	//create autorization token
 	u, _ := h.store.GetUserByEmail(user.Email)
+	if u.Status == types.UserBlocked {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("this account has been suspended"))
+		return
+	}
 	secret := []byte(config.Envs.JWTSecret)
-	token, err := auth.CreateJWT(secret, uint64(u.ID))
+	token, err := auth.CreateJWT(secret, uint64(u.ID), u.TokenVersion)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
 	//utils.WriteJSON(w, http.StatusOK, map[string]string{"token": token})
-	h.sessionStore.StoreUserSession(w, r, token)
+	if err := h.sessionStore.StoreUserSession(w, r, token); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
 
 	http.Redirect(w, r, "http://localhost:5173/profile", http.StatusFound)
 }
@@ -4,6 +4,7 @@ import (
 	"backend/seed-savers/services/auth"
 	"backend/seed-savers/types"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -19,7 +20,8 @@ func TestUserServiceHandlers(t *testing.T) {
 
 	mockStore := &mockUserStore{}
 	autMockStore := &auth.AuthStore{Store: sessions.NewCookieStore([]byte{5})}
-	handler := NewHandler(mockStore, autMockStore)
+	handler := NewHandler(mockStore, &mockAccountStore{}, autMockStore)
+	handler.send = func(to string, msg []byte) error { return nil }
 
 	t.Run("should fail if the user payload is invalid", func(t *testing.T) {
 		payload := types.UserRegisterPayload{
@@ -69,6 +71,42 @@ func TestUserServiceHandlers(t *testing.T) {
 
 }
 
+func TestMe(t *testing.T) {
+	handler := NewHandler(&mockUserStore{}, &mockAccountStore{}, &auth.AuthStore{Store: sessions.NewCookieStore([]byte{5})})
+
+	t.Run("should return the logged-in user without address or seeds", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/user/me", nil)
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, 7))
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/user/me", handler.handleMe)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, rr.Code)
+		}
+		var body map[string]any
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := body["password"]; ok || body["seeds"] == nil || body["openOrders"].(map[string]any)["received"] != 1.0 {
+			t.Errorf("unexpected body %s", rr.Body.String())
+		}
+	})
+
+	t.Run("should refuse anonymous requests", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/user/me", handler.handleMe)
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/user/me", nil))
+
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %d but got %d", http.StatusUnauthorized, rr.Code)
+		}
+	})
+}
+
 type mockUserStore struct{}
 
 // GetCompleteUserByEmail implements types.UserStore.
@@ -78,7 +116,10 @@ func (m *mockUserStore) GetCompleteUserByEmail(email string) (*types.User, error
 
 // GetCompleteUserByID implements types.UserStore.
 func (m *mockUserStore) GetCompleteUserByID(ID int) (*types.User, error) {
-	panic("unimplemented")
+	if ID != 7 {
+		return nil, fmt.Errorf("no user found")
+	}
+	return &types.User{ID: 7, Name: "Anna", Credits: 2, Seeds: []types.Seed{}, OpenOrders: &types.OpenOrders{Received: 1}}, nil
 }
 
 // ModifySeedQuantity implements types.UserStore.
@@ -4,8 +4,12 @@ import (
 	"backend/seed-savers/types"
 	"database/sql"
 	"fmt"
+	"time"
 )
 
+// userColumns elenca le colonne lette da ScanRowIntoUser, nello stesso ordine
+const userColumns = "user_id, name, email, password, credits, role, email_verified_at, token_version, status"
+
 // Store rappresenta una struttura per l'accesso al database
 type Store struct {
 	db *sql.DB
@@ -18,7 +22,7 @@ func NewStore(db *sql.DB) *Store {
 
 // GetUserByEmail cerca un utente nel database usando l'email e restituisce l'utente trovato
 func (s *Store) GetUserByEmail(email string) (*types.User, error) {
-	rows, err := s.db.Query("SELECT * FROM users WHERE email=?", email)
+	rows, err := s.db.Query("SELECT "+userColumns+" FROM users WHERE email=?", email)
 	if err != nil {
 		return nil, err
 	}
@@ -48,7 +52,14 @@ func (s *Store) RegisterSeed(seed *types.Seed, userID int) error {
 	defer tx.Rollback() // Assicurati di eseguire il rollback in caso di errore
 
 	// Inserisci il seme associato all'utente
-	_, err = tx.Exec("INSERT INTO users_seed (seed_id, user_id, quantity) VALUES (?, ?, ?)", seed.ID, userID, seed.Quantity)
+	_, err = tx.Exec("INSERT INTO users_seed (seed_id, user_id, quantity, restocked_at) VALUES (?, ?, ?, IF(? > 0, CURRENT_TIMESTAMP, NULL))", seed.ID, userID, seed.Quantity, seed.Quantity)
+	if err != nil {
+		return err
+	}
+
+	// Registra la partita di origine, senza genitore perché non arriva da uno scambio
+	_, err = tx.Exec(`INSERT INTO seed_lot (seed_id, user_id, source, quantity, province)
+		SELECT ?, ?, 'registered', ?, (SELECT province FROM adress WHERE user_id = ? AND is_default)`, seed.ID, userID, seed.Quantity, userID)
 	if err != nil {
 		return err
 	}
@@ -59,7 +70,7 @@ func (s *Store) RegisterSeed(seed *types.Seed, userID int) error {
 
 // GetUserByID cerca un utente nel database usando l'ID e restituisce l'utente trovato
 func (s *Store) GetUserByID(ID int) (*types.User, error) {
-	rows, err := s.db.Query("SELECT * FROM users WHERE user_id=?", ID)
+	rows, err := s.db.Query("SELECT "+userColumns+" FROM users WHERE user_id=?", ID)
 	if err != nil {
 		return nil, err
 	}
@@ -88,11 +99,17 @@ func (s *Store) CreateUser(user *types.User) error {
 	}
 	defer tx.Rollback() // Esegui rollback in caso di errore
 
-	// Inserisce l'utente
-	_, err = tx.Exec("INSERT INTO users (name, email, password) VALUES (?, ?, ?)", user.Name, user.Email, user.Password)
+	// Inserisce l'utente; email_verified_at è già valorizzato solo per chi arriva da un provider OAuth
+	res, err := tx.Exec("INSERT INTO users (name, email, password, email_verified_at) VALUES (?, ?, ?, ?)", user.Name, user.Email, user.Password, user.EmailVerifiedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
 	if err != nil {
 		return err
 	}
+	user.ID = int(id)
 
 	// Conferma la transazione
 	return tx.Commit()
@@ -136,94 +153,59 @@ func (s *Store) DeleteUserByID(ID int) error {
 	return tx.Commit()
 }
 
-// CreateAdress crea un nuovo indirizzo per un utente
-func (s *Store) CreateAdress(adress *types.Adress) error {
-	// Inizia una transazione
-	tx, err := s.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback() // Esegui rollback in caso di errore
-
-	// Inserisci l'indirizzo
-	_, err = tx.Exec("INSERT INTO adress (id, state, city, street, cap, province, number, apartment_number) VALUES (?, ?, ?, ?, ?, ?, ?, ?)", adress.ID, adress.Country, adress.City, adress.Street, adress.Cap, adress.Province, adress.Number, adress.Apartment_number)
-	if err != nil {
-		return err
-	}
+// completeUserQuery legge profilo, indirizzo (se c'è) e ordini aperti; l'inventario arriva con una
+// seconda query, così le righe non si moltiplicano per ogni seme
+const completeUserQuery = `SELECT u.user_id, u.name, u.email, u.credits, u.role, u.email_verified_at, u.deletion_requested_at,
+		a.adress_id, a.label, a.state, a.city, a.street, a.cap, a.province, a.number, a.apartment_number,
+		(SELECT COUNT(*) FROM orders o WHERE o.sender_user_id = u.user_id AND o.state NOT IN ('Arrivato', 'Annullato')),
+		(SELECT COUNT(*) FROM orders o WHERE o.reciver_user_id = u.user_id AND o.state NOT IN ('Arrivato', 'Annullato'))
+	FROM users u
+	LEFT JOIN adress a ON a.user_id = u.user_id AND a.is_default `
 
-	// Conferma la transazione
-	return tx.Commit()
+// GetCompleteUserByEmail restituisce un utente con tutti i dettagli (indirizzo e semi) usando l'email
+func (s *Store) GetCompleteUserByEmail(email string) (*types.User, error) {
+	return s.getCompleteUser("WHERE u.email = ?", email)
 }
 
-// ModifyAdress modifica un indirizzo esistente nel database
-func (s *Store) ModifyAdress(adress *types.Adress) error {
-	// Inizia una transazione
-	tx, err := s.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback() // Esegui rollback in caso di errore
-
-	// Modifica l'indirizzo
-	_, err = tx.Exec("UPDATE adress SET state = ?, city = ?, street = ?, cap = ?, province = ?, number = ?, apartment_number = ? WHERE id = ?", adress.Country, adress.City, adress.Street, adress.Cap, adress.Province, adress.Number, adress.Apartment_number, adress.ID)
-	if err != nil {
-		return err
-	}
-
-	// Conferma la transazione
-	return tx.Commit()
+// GetCompleteUserByID restituisce un utente con tutti i dettagli (indirizzo e semi) usando l'ID
+func (s *Store) GetCompleteUserByID(ID int) (*types.User, error) {
+	return s.getCompleteUser("WHERE u.user_id = ?", ID)
 }
 
-// GetCompleteUserByEmail restituisce un utente con tutti i dettagli (indirizzo e semi) usando l'email
-func (s *Store) GetCompleteUserByEmail(email string) (*types.User, error) {
-	query := `
-		SELECT u.user_id, u.NAME, u.email, u.credits, 
-		       a.state, a.city, a.street, a.cap, a.province, a.number, a.apartment_number, 
-		       s.seed_id, s.variety_name, s.description, s.vegetable, s.img, us.quantity 
-		FROM users u
-		LEFT JOIN adress a ON u.user_id = a.id
-		LEFT JOIN users_seed us ON u.user_id = us.user_id
-		LEFT JOIN seed s ON us.seed_id = s.seed_id
-		WHERE u.email = ?;
-	`
-	rows, err := s.db.Query(query, email)
+func (s *Store) getCompleteUser(where string, arg any) (*types.User, error) {
+	rows, err := s.db.Query(completeUserQuery+where, arg)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
+	// il primo risultato si chiude prima dell'inventario, altrimenti la richiesta occupa due connessioni
 	u, err := ScanRowsIntoCompleteUser(rows)
+	rows.Close()
 	if err != nil {
 		return nil, err
 	}
 
-	return u, nil
-}
-
-// GetCompleteUserByID restituisce un utente con tutti i dettagli (indirizzo e semi) usando l'ID
-func (s *Store)  GetCompleteUserByID(ID int) (*types.User, error) {
-	query := `
-		SELECT u.user_id, u.NAME, u.email, u.credits, 
-		       a.state, a.city, a.street, a.cap, a.province, a.number, a.apartment_number, 
-		       s.seed_id, s.variety_name, s.description, s.vegetable, s.img, us.quantity 
-		FROM users u
-		LEFT JOIN adress a ON u.user_id = a.id
-		LEFT JOIN users_seed us ON u.user_id = us.user_id
-		LEFT JOIN seed s ON us.seed_id = s.seed_id
-		WHERE u.user_id = ?;
-	`
-	rows, err := s.db.Query(query, ID)
+	inventory, err := s.db.Query(`SELECT s.seed_id, s.variety_name, s.description, s.vegetable, s.img, us.quantity, us.private
+		FROM users_seed us
+		INNER JOIN seed s ON us.seed_id = s.seed_id
+		WHERE us.user_id = ?
+		ORDER BY s.vegetable, s.variety_name`, u.ID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	defer inventory.Close()
 
-	u, err := ScanRowsIntoCompleteUser(rows)
-	if err != nil {
-		return nil, err
+	for inventory.Next() {
+		var seed types.Seed
+		var img sql.NullString
+		if err := inventory.Scan(&seed.ID, &seed.Variety_name, &seed.Description, &seed.Vegetable, &img, &seed.Quantity, &seed.Private); err != nil {
+			return nil, err
+		}
+		seed.Image = img.String
+		u.Seeds = append(u.Seeds, seed)
 	}
 
-	return u, nil
+	return u, inventory.Err()
 }
 
 // ModifySeedQuantity aggiorna la quantità di semi per un utente ha bisogno solo di ID e QUANTITA'
@@ -235,8 +217,9 @@ func (s *Store) ModifySeedQuantity(seed *types.Seed, userID int) error {
 	}
 	defer tx.Rollback() // Esegui rollback in caso di errore
 
-	// Aggiorna la quantità del seme
-	_, err = tx.Exec("UPDATE users_seed SET quantity = ? WHERE user_id = ? AND seed_id = ?", seed.Quantity, userID, seed.ID)
+	// Aggiorna la quantità del seme; restocked_at va assegnato prima, finché quantity è ancora quella vecchia
+	_, err = tx.Exec("UPDATE users_seed SET restocked_at = IF(? > quantity, CURRENT_TIMESTAMP, restocked_at), quantity = ? WHERE user_id = ? AND seed_id = ?",
+		seed.Quantity, seed.Quantity, userID, seed.ID)
 	if err != nil {
 		return err
 	}
@@ -255,6 +238,10 @@ func ScanRowIntoUser(rows *sql.Rows) (*types.User, error) {
 		&user.Email,
 		&user.Password,
 		&user.Credits,
+		&user.Role,
+		&user.EmailVerifiedAt,
+		&user.TokenVersion,
+		&user.Status,
 	)
 
 	if err != nil {
@@ -264,57 +251,198 @@ func ScanRowIntoUser(rows *sql.Rows) (*types.User, error) {
 	return user, nil
 }
 
+// ScanRowsIntoCompleteUser legge l'utente selezionato con completeUserQuery. I campi dell'indirizzo
+// sono NULL quando l'utente non l'ha ancora inserito; l'inventario resta vuoto e va caricato a parte
 func ScanRowsIntoCompleteUser(rows *sql.Rows) (*types.User, error) {
-	// Variabile per memorizzare l'utente
-	var user *types.User
-	var firstRow = true
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("no user found")
+	}
 
-	// Itera su tutte le righe
-	for rows.Next() {
-		// Creiamo temporaneamente oggetti Seed e Adress per ogni riga
-		var seed types.Seed
-		var address types.Adress
-
-		// Estrazione dati dalla riga
-		err := rows.Scan(
-			&user.ID,
-			&user.Name,
-			&user.Email,
-			&user.Password,
-			&user.Credits,
-			&address.ID,
-			&address.Country,
-			&address.City,
-			&address.Street,
-			&address.Cap,
-			&address.Province,
-			&address.Apartment_number,
-			&address.Number,
-			&seed.Variety_name,
-			&seed.Description,
-			&seed.Vegetable,
-			&seed.Image,
-			&seed.Quantity,
-		)
+	user := &types.User{Seeds: make([]types.Seed, 0), OpenOrders: &types.OpenOrders{}}
+	var name, role sql.NullString
+	var credits, adressID sql.NullInt64
+	var label, country, city, street, postalCode, province, number, apartment sql.NullString
+
+	err := rows.Scan(
+		&user.ID,
+		&name,
+		&user.Email,
+		&credits,
+		&role,
+		&user.EmailVerifiedAt,
+		&user.DeletionRequestedAt,
+		&adressID,
+		&label,
+		&country,
+		&city,
+		&street,
+		&postalCode,
+		&province,
+		&number,
+		&apartment,
+		&user.OpenOrders.Sent,
+		&user.OpenOrders.Received,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Name, user.Role = name.String, role.String
+	user.Credits = int8(credits.Int64)
+	if adressID.Valid {
+		user.Adress = types.Adress{
+			ID:               int(adressID.Int64),
+			UserID:           user.ID,
+			Label:            label.String,
+			Default:          true,
+			Country:          country.String,
+			City:             city.String,
+			Street:           street.String,
+			Cap:              postalCode.String,
+			Province:         province.String,
+			Number:           number.String,
+			Apartment_number: apartment.String,
+		}
+	}
 
+	return user, nil
+}
+
+// CreateEmailVerification registra un link di verifica appena inviato. Una nuova richiesta di cambio
+// email annulla quelle ancora in sospeso, così vale solo l'ultimo indirizzo scelto
+func (s *Store) CreateEmailVerification(v *types.EmailVerification) error {
+	// Inizia una transazione
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Assicura che il rollback venga eseguito in caso di errore
+
+	if v.Purpose == types.EmailChange {
+		_, err = tx.Exec("UPDATE email_verification SET used_at = CURRENT_TIMESTAMP WHERE user_id = ? AND purpose = ? AND used_at IS NULL", v.UserID, types.EmailChange)
 		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("INSERT INTO email_verification (token_id, user_id, email, purpose, expires_at) VALUES (?, ?, ?, ?, ?)",
+		v.TokenID, v.UserID, v.Email, v.Purpose, v.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	// Conferma la transazione
+	return tx.Commit()
+}
+
+// GetEmailVerificationsSince restituisce gli orari di invio dei link di verifica dell'utente a partire da
+// since, dal più recente; servono a limitare i reinvii
+func (s *Store) GetEmailVerificationsSince(userID int, since time.Time) ([]time.Time, error) {
+	rows, err := s.db.Query("SELECT created_at FROM email_verification WHERE user_id = ? AND created_at >= ? ORDER BY created_at DESC", userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sent := make([]time.Time, 0)
+	for rows.Next() {
+		var t time.Time
+		if err := rows.Scan(&t); err != nil {
 			return nil, err
 		}
+		sent = append(sent, t)
+	}
+
+	return sent, rows.Err()
+}
+
+// ConsumeEmailVerification usa il link e segna l'email come verificata; per un cambio email sostituisce
+// anche l'indirizzo dell'utente. Il link vale una sola volta ed entro la scadenza; un link di verifica
+// vale solo se l'utente ha ancora l'indirizzo a cui è stato inviato
+func (s *Store) ConsumeEmailVerification(v *types.EmailVerification) error {
+	// Inizia una transazione
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Assicura che il rollback venga eseguito in caso di errore
+
+	var current string
+	if err := tx.QueryRow("SELECT email FROM users WHERE user_id = ? FOR UPDATE", v.UserID).Scan(&current); err != nil {
+		return fmt.Errorf("user not found")
+	}
+	if v.Purpose == types.EmailVerify && current != v.Email {
+		return fmt.Errorf("the verification link was sent to a different email address")
+	}
 
-		// Se è la prima riga, inizializza l'utente e assegna l'indirizzo
-		if firstRow {
-			user = new(types.User)
-			user.Adress = address
-			firstRow = false
+	res, err := tx.Exec(`UPDATE email_verification SET used_at = CURRENT_TIMESTAMP
+		WHERE token_id = ? AND user_id = ? AND email = ? AND purpose = ? AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP`,
+		v.TokenID, v.UserID, v.Email, v.Purpose)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("the verification link was already used or has expired")
+	}
+
+	if v.Purpose == types.EmailChange {
+		var taken bool
+		err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email = ? AND user_id <> ?)", v.Email, v.UserID).Scan(&taken)
+		if err != nil {
+			return err
 		}
+		if taken {
+			return fmt.Errorf("user with this email: %s already exist", v.Email)
+		}
+	}
 
-		// Aggiunge il seme alla lista dei semi dell'utente
-		user.Seeds = append(user.Seeds, seed)
+	// l'indirizzo confermato dal link è verificato, anche quando è quello nuovo; MySQL assegna le colonne
+	// in ordine, quindi email_verified_at va calcolato prima di cambiare email
+	_, err = tx.Exec("UPDATE users SET email_verified_at = IF(email = ?, COALESCE(email_verified_at, CURRENT_TIMESTAMP), CURRENT_TIMESTAMP), email = ? WHERE user_id = ?",
+		v.Email, v.Email, v.UserID)
+	if err != nil {
+		return err
 	}
 
-	if user == nil {
-		return nil, fmt.Errorf("no user found")
+	// Conferma la transazione
+	return tx.Commit()
+}
+
+// MarkEmailVerified segna come verificata l'email dell'utente, ad esempio quando lo garantisce il provider OAuth
+func (s *Store) MarkEmailVerified(userID int) error {
+	_, err := s.db.Exec("UPDATE users SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP) WHERE user_id = ?", userID)
+	return err
+}
+
+// UpdateProfile modifica i dati del profilo che l'utente può cambiare liberamente
+func (s *Store) UpdateProfile(userID int, name string) error {
+	_, err := s.db.Exec("UPDATE users SET name = ? WHERE user_id = ?", name, userID)
+	return err
+}
+
+// ChangePassword salva la nuova password e incrementa token_version, così le sessioni aperte con la
+// password precedente decadono. Restituisce la nuova versione da firmare nel token di sessione
+func (s *Store) ChangePassword(userID int, hash string) (int, error) {
+	// Inizia una transazione
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
 	}
+	defer tx.Rollback() // Assicura che il rollback venga eseguito in caso di errore
 
-	return user, nil
+	_, err = tx.Exec("UPDATE users SET password = ?, token_version = token_version + 1 WHERE user_id = ?", hash, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	var version int
+	if err := tx.QueryRow("SELECT token_version FROM users WHERE user_id = ?", userID).Scan(&version); err != nil {
+		return 0, err
+	}
+
+	// Conferma la transazione
+	return version, tx.Commit()
 }
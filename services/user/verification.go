@@ -0,0 +1,156 @@
+package user
+
+import (
+	"backend/seed-savers/config"
+	"backend/seed-savers/services/email"
+	"backend/seed-savers/types"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"log"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// verificationClaims sono i dati firmati nel link di verifica. Purpose distingue anche questi token da
+// quelli di sessione firmati con lo stesso segreto
+type verificationClaims struct {
+	Purpose string `json:"purpose"`
+	UserID  int    `json:"uid"`
+	Email   string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// signVerificationToken firma il link di verifica; l'ID identifica la riga di email_verification
+// che lo rende usabile una sola volta
+func signVerificationToken(v *types.EmailVerification) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, verificationClaims{
+		Purpose: v.Purpose,
+		UserID:  v.UserID,
+		Email:   v.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        v.TokenID,
+			ExpiresAt: jwt.NewNumericDate(v.ExpiresAt),
+		},
+	})
+
+	return token.SignedString([]byte(config.Envs.JWTSecret))
+}
+
+// parseVerificationToken controlla firma, scadenza e scopo del token e restituisce la verifica a cui si riferisce
+func parseVerificationToken(tokenString string) (*types.EmailVerification, error) {
+	claims := new(verificationClaims)
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(config.Envs.JWTSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, fmt.Errorf("invalid verification link: %v", err)
+	}
+	if (claims.Purpose != types.EmailVerify && claims.Purpose != types.EmailChange) || claims.ID == "" {
+		return nil, fmt.Errorf("invalid verification link")
+	}
+
+	return &types.EmailVerification{TokenID: claims.ID, UserID: claims.UserID, Email: claims.Email, Purpose: claims.Purpose, ExpiresAt: claims.ExpiresAt.Time}, nil
+}
+
+// resendWait dice quanto manca prima di poter inviare un altro link, dati gli invii delle ultime 24 ore
+// dal più recente: serve un intervallo minimo tra due invii e c'è un tetto giornaliero
+func resendWait(sent []time.Time, now time.Time, interval time.Duration, daily int) time.Duration {
+	if len(sent) == 0 {
+		return 0
+	}
+	if wait := sent[0].Add(interval).Sub(now); wait > 0 {
+		return wait
+	}
+	if len(sent) >= daily {
+		if wait := sent[daily-1].Add(24 * time.Hour).Sub(now); wait > 0 {
+			return wait
+		}
+	}
+
+	return 0
+}
+
+// createEmailLink registra un nuovo link a scadenza per confermare email e restituisce l'URL da inviare
+func (h *Handler) createEmailLink(userID int, email, purpose string) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	v := &types.EmailVerification{
+		TokenID:   hex.EncodeToString(id),
+		UserID:    userID,
+		Email:     email,
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(time.Duration(config.Envs.VerificationTTLHours) * time.Hour),
+	}
+	token, err := signVerificationToken(v)
+	if err != nil {
+		return "", err
+	}
+	if err := h.accounts.CreateEmailVerification(v); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s:%s/user/verify/%s", config.Envs.PublicHost, config.Envs.Port, token), nil
+}
+
+// sendVerification invia all'utente un nuovo link per verificare il suo indirizzo email
+func (h *Handler) sendVerification(u *types.User) error {
+	link, err := h.createEmailLink(u.ID, u.Email, types.EmailVerify)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("<html><body><h1>Ciao %s, conferma il tuo indirizzo email</h1>"+
+		"<p>Per scambiare semi e registrare il tuo inventario apri <a href='%s'>questo link</a> entro %d ore.</p></body></html>",
+		html.EscapeString(u.Name), link, config.Envs.VerificationTTLHours)
+
+	return h.send(u.Email, email.Message("Conferma il tuo indirizzo email", body))
+}
+
+// sendEmailChange invia il link di conferma al nuovo indirizzo e avvisa quello attuale, così il
+// proprietario si accorge di un cambio che non ha chiesto
+func (h *Handler) sendEmailChange(u *types.User, newEmail string) error {
+	link, err := h.createEmailLink(u.ID, newEmail, types.EmailChange)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("<html><body><h1>Ciao %s, conferma il tuo nuovo indirizzo email</h1>"+
+		"<p>Apri <a href='%s'>questo link</a> entro %d ore per usare questo indirizzo su Seed Savers.</p></body></html>",
+		html.EscapeString(u.Name), link, config.Envs.VerificationTTLHours)
+	if err := h.send(newEmail, email.Message("Conferma il tuo nuovo indirizzo email", body)); err != nil {
+		return err
+	}
+
+	notice := fmt.Sprintf("<html><body><h1>Ciao %s, è stato chiesto di cambiare l'email del tuo account</h1>"+
+		"<p>Il nuovo indirizzo sarà %s appena verrà confermato. Se non sei stato tu, cambia subito la password.</p></body></html>",
+		html.EscapeString(u.Name), html.EscapeString(newEmail))
+	if err := h.send(u.Email, email.Message("Richiesta di cambio email", notice)); err != nil {
+		log.Printf("failed to notify user %d of the email change: %v", u.ID, err)
+	}
+
+	return nil
+}
+
+// verifiedByProvider dice se il provider OAuth garantisce che l'email è verificata
+func verifiedByProvider(raw map[string]interface{}) bool {
+	for _, key := range []string{"email_verified", "verified_email"} {
+		switch v := raw[key].(type) {
+		case bool:
+			if v {
+				return true
+			}
+		case string:
+			if v == "true" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,195 @@
+package user
+
+import (
+	"backend/seed-savers/config"
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/sessions"
+)
+
+func TestVerificationToken(t *testing.T) {
+	v := &types.EmailVerification{TokenID: "abc123", UserID: 7, Email: "anna@mail.com", Purpose: types.EmailVerify, ExpiresAt: time.Now().Add(time.Hour)}
+
+	t.Run("should round-trip a signed token", func(t *testing.T) {
+		token, err := signVerificationToken(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := parseVerificationToken(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.TokenID != v.TokenID || got.UserID != 7 || got.Email != v.Email {
+			t.Errorf("unexpected verification %+v", got)
+		}
+	})
+
+	t.Run("should reject expired or tampered tokens", func(t *testing.T) {
+		expired := *v
+		expired.ExpiresAt = time.Now().Add(-time.Minute)
+		token, _ := signVerificationToken(&expired)
+		if _, err := parseVerificationToken(token); err == nil {
+			t.Error("expected an expired token to be rejected")
+		}
+
+		token, _ = signVerificationToken(v)
+		if _, err := parseVerificationToken(token[:len(token)-2] + "xx"); err == nil {
+			t.Error("expected a tampered token to be rejected")
+		}
+	})
+
+	t.Run("should not accept a session token", func(t *testing.T) {
+		session, _ := auth.CreateJWT([]byte(config.Envs.JWTSecret), 7, 0)
+		if _, err := parseVerificationToken(session); err == nil {
+			t.Error("expected a session token to be rejected")
+		}
+	})
+}
+
+func TestResendWait(t *testing.T) {
+	now := time.Now()
+
+	if wait := resendWait(nil, now, time.Minute, 5); wait != 0 {
+		t.Errorf("the first link should not wait, got %v", wait)
+	}
+	if wait := resendWait([]time.Time{now.Add(-20 * time.Second)}, now, time.Minute, 5); wait != 40*time.Second {
+		t.Errorf("expected to wait 40s, got %v", wait)
+	}
+
+	sent := []time.Time{now.Add(-2 * time.Hour), now.Add(-3 * time.Hour), now.Add(-23 * time.Hour)}
+	if wait := resendWait(sent, now, time.Minute, 3); wait != time.Hour {
+		t.Errorf("expected the daily limit to wait 1h, got %v", wait)
+	}
+	if wait := resendWait(sent, now, time.Minute, 4); wait != 0 {
+		t.Errorf("expected no wait under the daily limit, got %v", wait)
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	store := &mockAccountStore{}
+	handler := NewHandler(&mockUserStore{}, store, &auth.AuthStore{Store: sessions.NewCookieStore([]byte{5})})
+
+	verify := func(token string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/user/verify/{token}", handler.handleVerifyEmail)
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/user/verify/"+token, nil))
+		return rr
+	}
+
+	token, _ := signVerificationToken(&types.EmailVerification{TokenID: "abc123", UserID: 7, Email: "anna@mail.com", Purpose: types.EmailVerify, ExpiresAt: time.Now().Add(time.Hour)})
+
+	t.Run("should verify the email only once", func(t *testing.T) {
+		if rr := verify(token); rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, rr.Code)
+		}
+		if store.consumed["abc123"] != 7 {
+			t.Errorf("expected the link to be consumed for user 7, got %v", store.consumed)
+		}
+		if rr := verify(token); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should reject an invalid link", func(t *testing.T) {
+		if rr := verify("not-a-token"); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+}
+
+func TestResendVerification(t *testing.T) {
+	store := &mockAccountStore{}
+	handler := NewHandler(&mockUserStore{}, store, &auth.AuthStore{Store: sessions.NewCookieStore([]byte{5})})
+	var mails []string
+	handler.send = func(to string, msg []byte) error {
+		mails = append(mails, string(msg))
+		return nil
+	}
+
+	resend := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/user/verify/resend", nil)
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, 7))
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/user/verify/resend", handler.handleResendVerification)
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should send a new link", func(t *testing.T) {
+		if rr := resend(); rr.Code != http.StatusAccepted {
+			t.Fatalf("expected status code %d but got %d", http.StatusAccepted, rr.Code)
+		}
+		if len(mails) != 1 || !strings.Contains(mails[0], config.Envs.PublicHost+":"+config.Envs.Port+"/user/verify/") {
+			t.Errorf("expected a verification link, got %v", mails)
+		}
+	})
+
+	t.Run("should throttle a second request", func(t *testing.T) {
+		rr := resend()
+		if rr.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected status code %d but got %d", http.StatusTooManyRequests, rr.Code)
+		}
+		if rr.Header().Get("Retry-After") == "" || len(mails) != 1 {
+			t.Errorf("expected a Retry-After header and no new mail")
+		}
+	})
+}
+
+type mockAccountStore struct {
+	sent     []time.Time
+	links    []types.EmailVerification
+	consumed map[string]int
+	names    map[int]string
+	version  int
+}
+
+func (m *mockAccountStore) CreateEmailVerification(v *types.EmailVerification) error {
+	m.sent = append([]time.Time{time.Now()}, m.sent...)
+	m.links = append(m.links, *v)
+	return nil
+}
+
+func (m *mockAccountStore) GetEmailVerificationsSince(userID int, since time.Time) ([]time.Time, error) {
+	return m.sent, nil
+}
+
+func (m *mockAccountStore) ConsumeEmailVerification(v *types.EmailVerification) error {
+	if _, ok := m.consumed[v.TokenID]; ok {
+		return fmt.Errorf("the verification link was already used or has expired")
+	}
+	if m.consumed == nil {
+		m.consumed = make(map[string]int)
+	}
+	m.consumed[v.TokenID] = v.UserID
+	return nil
+}
+
+func (m *mockAccountStore) MarkEmailVerified(userID int) error {
+	return nil
+}
+
+func (m *mockAccountStore) UpdateProfile(userID int, name string) error {
+	if m.names == nil {
+		m.names = make(map[int]string)
+	}
+	m.names[userID] = name
+	return nil
+}
+
+func (m *mockAccountStore) ChangePassword(userID int, hash string) (int, error) {
+	m.version++
+	return m.version, nil
+}
@@ -0,0 +1,37 @@
+package wishlist
+
+import (
+	"backend/seed-savers/geo"
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"strings"
+)
+
+// Matches controlla se un seme appena reso disponibile da un utente della provincia owner soddisfa
+// la ricerca salvata. Il testo deve comparire, parola per parola, nella varietà o nella descrizione
+func Matches(search types.SavedSearch, seed *types.Seed, owner *geo.Province) bool {
+	if search.Vegetable != "" && !strings.Contains(utils.NormalizeName(seed.Vegetable), utils.NormalizeName(search.Vegetable)) {
+		return false
+	}
+
+	if search.Query != "" {
+		text := utils.NormalizeName(seed.Variety_name + " " + seed.Description)
+		for _, word := range strings.Fields(utils.NormalizeName(search.Query)) {
+			if !strings.Contains(text, word) {
+				return false
+			}
+		}
+	}
+
+	if search.Region != "" {
+		if owner == nil {
+			return false
+		}
+		region := utils.NormalizeName(search.Region)
+		if region != utils.NormalizeName(owner.Region) && region != utils.NormalizeName(owner.Name) && region != strings.ToLower(owner.Code) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,192 @@
+package wishlist
+
+import (
+	"backend/seed-savers/config"
+	"backend/seed-savers/geo"
+	"backend/seed-savers/services/email"
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+)
+
+// Notifier confronta i semi appena resi disponibili con desideri e ricerche salvate, mette in
+// coda gli avvisi e li consegna subito o nel riepilogo giornaliero, secondo la scelta dell'utente
+type Notifier struct {
+	store     types.WishlistStore
+	seedStore types.SeedStore
+	send      func(to string, msg []byte) error
+}
+
+func NewNotifier(store types.WishlistStore, seedStore types.SeedStore) *Notifier {
+	return &Notifier{store: store, seedStore: seedStore, send: email.SendMail}
+}
+
+// SeedAvailable implementa types.AvailabilityNotifier senza rallentare la richiesta che l'ha causato
+func (n *Notifier) SeedAvailable(seedID, ownerID int) {
+	go func() {
+		if err := n.Notify(seedID, ownerID); err != nil {
+			log.Printf("failed to queue alerts for seed %d: %v", seedID, err)
+		}
+	}()
+}
+
+// Notify mette in coda un avviso per ogni utente interessato al seme (al massimo uno per utente)
+// e invia subito quelli di chi ha scelto la consegna immediata. Non avvisa nessuno se il possessore
+// non mostra il seme agli altri, né chi è bloccato con lui
+func (n *Notifier) Notify(seedID, ownerID int) error {
+	seed, err := n.seedStore.GetSeedByID(seedID)
+	if err != nil {
+		return err
+	}
+
+	shared, err := n.store.IsSeedShared(ownerID, seed.ID)
+	if err != nil || !shared {
+		return err
+	}
+
+	var owner *geo.Province
+	if postalCode, province, err := n.seedStore.GetUserArea(ownerID); err == nil {
+		owner, _ = geo.Locate(postalCode, province)
+	}
+
+	alerts := make([]types.Alert, 0)
+	notified := map[int]bool{ownerID: true}
+	newAlert := func(userID int, reason string) types.Alert {
+		notified[userID] = true
+		return types.Alert{
+			UserID:       userID,
+			SeedID:       seed.ID,
+			OwnerID:      ownerID,
+			Variety_name: seed.Variety_name,
+			Vegetable:    seed.Vegetable,
+			Reason:       reason,
+		}
+	}
+
+	users, err := n.store.GetWishlistUsers(seed.ID, ownerID)
+	if err != nil {
+		return err
+	}
+	for _, userID := range users {
+		if !notified[userID] {
+			alerts = append(alerts, newAlert(userID, types.AlertWishlist))
+		}
+	}
+
+	regions := make([]string, 0, 3)
+	if owner != nil {
+		regions = append(regions, utils.NormalizeName(owner.Region), utils.NormalizeName(owner.Name), strings.ToLower(owner.Code))
+	}
+	searches, err := n.store.GetCandidateSearches(utils.NormalizeName(seed.Vegetable), regions, ownerID)
+	if err != nil {
+		return err
+	}
+	for _, search := range searches {
+		if notified[search.UserID] || !Matches(search, seed, owner) {
+			continue
+		}
+		a := newAlert(search.UserID, types.AlertSavedSearch)
+		a.SearchID, a.SearchName = search.ID, search.Name
+		alerts = append(alerts, a)
+	}
+
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	queued, err := n.store.QueueAlerts(alerts)
+	if err != nil {
+		return err
+	}
+
+	return n.deliver(queued, types.AlertImmediate)
+}
+
+// SendDigest invia a ogni utente un'unica email con tutti gli avvisi in sospeso, compresi quelli
+// immediati che non era stato possibile consegnare
+func (n *Notifier) SendDigest() error {
+	pending, err := n.store.GetPendingAlerts()
+	if err != nil {
+		return err
+	}
+
+	return n.deliver(pending, "")
+}
+
+// RunDigest invia il riepilogo ogni giorno all'ora indicata. Va avviato in una goroutine
+func (n *Notifier) RunDigest(hour int) {
+	for {
+		time.Sleep(time.Until(nextDigest(time.Now(), hour)))
+		if err := n.SendDigest(); err != nil {
+			log.Printf("failed to send alert digest: %v", err)
+		}
+	}
+}
+
+func nextDigest(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// deliver raggruppa gli avvisi per utente e li invia; con only diverso da "" considera solo gli
+// utenti con quella preferenza. Gli avvisi non consegnati restano in coda
+func (n *Notifier) deliver(alerts []types.Alert, only string) error {
+	byUser := make(map[int][]types.Alert)
+	userIDs := make([]int, 0)
+	for _, a := range alerts {
+		if _, ok := byUser[a.UserID]; !ok {
+			userIDs = append(userIDs, a.UserID)
+		}
+		byUser[a.UserID] = append(byUser[a.UserID], a)
+	}
+
+	recipients, err := n.store.GetAlertRecipients(userIDs)
+	if err != nil {
+		return err
+	}
+
+	sent := make([]int, 0, len(alerts))
+	for _, userID := range userIDs {
+		r, ok := recipients[userID]
+		if !ok || (only != "" && r.Delivery != only) {
+			continue
+		}
+
+		if err := n.send(r.Email, alertMessage(r, byUser[userID])); err != nil {
+			log.Printf("failed to send alerts to user %d: %v", userID, err)
+			continue
+		}
+		for _, a := range byUser[userID] {
+			sent = append(sent, a.ID)
+		}
+	}
+
+	return n.store.MarkAlertsSent(sent)
+}
+
+func alertMessage(r types.AlertRecipient, alerts []types.Alert) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<html><body><h1>Ciao %s, ci sono nuovi semi per te</h1><ul>", html.EscapeString(r.Name))
+	for _, a := range alerts {
+		reason := "nella tua lista dei desideri"
+		if a.Reason == types.AlertSavedSearch {
+			reason = fmt.Sprintf("per la ricerca \"%s\"", html.EscapeString(a.SearchName))
+		}
+		fmt.Fprintf(&b, "<li><a href='%s:%s/seeds/%d'>%s (%s)</a> è disponibile, %s</li>",
+			config.Envs.PublicHost, config.Envs.Port, a.SeedID, html.EscapeString(a.Variety_name), html.EscapeString(a.Vegetable), reason)
+	}
+	b.WriteString("</ul></body></html>")
+
+	subject := "Nuovi semi disponibili su Seed Savers"
+	if len(alerts) == 1 {
+		subject = fmt.Sprintf("%s è disponibile su Seed Savers", alerts[0].Variety_name)
+	}
+	return email.Message(subject, b.String())
+}
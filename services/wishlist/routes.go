@@ -0,0 +1,216 @@
+package wishlist
+
+import (
+	"backend/seed-savers/services/auth"
+	"backend/seed-savers/types"
+	"backend/seed-savers/utils"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store        types.WishlistStore
+	seedStore    types.SeedStore
+	usersStore   types.UserStore
+	sessionStore *auth.AuthStore
+}
+
+func NewHandler(s types.WishlistStore, seedStore types.SeedStore, us types.UserStore, sessionStore *auth.AuthStore) *Handler {
+	return &Handler{s, seedStore, us, sessionStore}
+}
+
+func (h *Handler) RegisterRouter(router *mux.Router) {
+	router.HandleFunc("/wishlist", auth.WithJWTAuth(h.handleGetWishlist, h.usersStore, h.sessionStore)).Methods("GET")
+	router.HandleFunc("/wishlist/{seedID:[0-9]+}", auth.WithJWTAuth(h.handleAddToWishlist, h.usersStore, h.sessionStore)).Methods("POST")
+	router.HandleFunc("/wishlist/{seedID:[0-9]+}", auth.WithJWTAuth(h.handleRemoveFromWishlist, h.usersStore, h.sessionStore)).Methods("DELETE")
+	router.HandleFunc("/saved-searches", auth.WithJWTAuth(h.handleGetSavedSearches, h.usersStore, h.sessionStore)).Methods("GET")
+	router.HandleFunc("/saved-searches", auth.WithJWTAuth(h.handleCreateSavedSearch, h.usersStore, h.sessionStore)).Methods("POST")
+	router.HandleFunc("/saved-searches/{searchID:[0-9]+}", auth.WithJWTAuth(h.handleUpdateSavedSearch, h.usersStore, h.sessionStore)).Methods("PUT")
+	router.HandleFunc("/saved-searches/{searchID:[0-9]+}", auth.WithJWTAuth(h.handleDeleteSavedSearch, h.usersStore, h.sessionStore)).Methods("DELETE")
+	router.HandleFunc("/alerts", auth.WithJWTAuth(h.handleGetAlerts, h.usersStore, h.sessionStore)).Methods("GET")
+	router.HandleFunc("/alerts/delivery", auth.WithJWTAuth(h.handleSetDelivery, h.usersStore, h.sessionStore)).Methods("PUT")
+}
+
+func (h *Handler) handleGetWishlist(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	items, err := h.store.GetWishlist(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, items)
+}
+
+func (h *Handler) handleAddToWishlist(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+	seedID, _ := strconv.Atoi(mux.Vars(r)["seedID"])
+
+	//i semi uniti a un altro finiscono nella lista con l'ID del sopravvissuto
+	seed, err := h.seedStore.GetSeedByID(seedID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err := h.store.AddToWishlist(userID, seed.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, nil)
+}
+
+func (h *Handler) handleRemoveFromWishlist(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+	seedID, _ := strconv.Atoi(mux.Vars(r)["seedID"])
+
+	if err := h.store.RemoveFromWishlist(userID, seedID); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *Handler) handleGetSavedSearches(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	searches, err := h.store.GetSavedSearches(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, searches)
+}
+
+func (h *Handler) handleCreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	search, err := h.decodeSearch(w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.store.CreateSavedSearch(search); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, search)
+}
+
+func (h *Handler) handleUpdateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	search, err := h.decodeSearch(w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	search.ID, _ = strconv.Atoi(mux.Vars(r)["searchID"])
+
+	if err := h.store.UpdateSavedSearch(search); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, search)
+}
+
+func (h *Handler) handleDeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+	searchID, _ := strconv.Atoi(mux.Vars(r)["searchID"])
+
+	if err := h.store.DeleteSavedSearch(userID, searchID); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *Handler) handleGetAlerts(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	alerts, err := h.store.GetAlerts(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, alerts)
+}
+
+func (h *Handler) handleSetDelivery(w http.ResponseWriter, r *http.Request) {
+	payload, err := utils.DecodePayload[types.AlertDeliveryPayload](w, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	if err := h.store.SetAlertDelivery(userID, payload.Delivery); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *Handler) decodeSearch(w http.ResponseWriter, r *http.Request) (*types.SavedSearch, error) {
+	payload, err := utils.DecodePayload[types.SavedSearchPayload](w, r)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	//ortaggio e regione si salvano normalizzati, così GetCandidateSearches li può confrontare in SQL
+	vegetable, region := utils.NormalizeName(payload.Vegetable), utils.NormalizeName(payload.Region)
+	if payload.Query == "" && vegetable == "" && region == "" {
+		return nil, fmt.Errorf("a saved search needs at least one of query, vegetable or region")
+	}
+
+	return &types.SavedSearch{
+		UserID:    userID,
+		Name:      payload.Name,
+		Query:     payload.Query,
+		Vegetable: vegetable,
+		Region:    region,
+	}, nil
+}
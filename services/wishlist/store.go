@@ -0,0 +1,328 @@
+package wishlist
+
+import (
+	"backend/seed-savers/types"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Store gestisce l'accesso al database per desideri, ricerche salvate e avvisi
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore crea e restituisce un nuovo oggetto Store con il database passato come parametro
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// GetWishlist restituisce i semi desiderati dall'utente con la quantità disponibile presso gli altri
+func (s *Store) GetWishlist(userID int) ([]types.WishlistItem, error) {
+	rows, err := s.db.Query(`SELECT w.seed_id, s.variety_name, s.vegetable, w.created_at,
+		COALESCE((SELECT SUM(us.quantity) FROM users_seed us WHERE us.seed_id = w.seed_id AND us.user_id <> w.user_id), 0)
+		FROM wishlist w JOIN seed s ON s.seed_id = w.seed_id
+		WHERE w.user_id = ? ORDER BY w.created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]types.WishlistItem, 0)
+	for rows.Next() {
+		var item types.WishlistItem
+		if err := rows.Scan(&item.SeedID, &item.Variety_name, &item.Vegetable, &item.CreatedAt, &item.Available); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// AddToWishlist aggiunge un seme ai desideri; aggiungerlo due volte non è un errore
+func (s *Store) AddToWishlist(userID, seedID int) error {
+	_, err := s.db.Exec("INSERT IGNORE INTO wishlist (user_id, seed_id) VALUES (?, ?)", userID, seedID)
+	return err
+}
+
+// RemoveFromWishlist toglie un seme dai desideri
+func (s *Store) RemoveFromWishlist(userID, seedID int) error {
+	res, err := s.db.Exec("DELETE FROM wishlist WHERE user_id = ? AND seed_id = ?", userID, seedID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res, "seed not in wishlist")
+}
+
+// notBlockedBy esclude gli utenti che hanno bloccato il possessore o che il possessore ha bloccato;
+// vuole due volte l'id del possessore
+const notBlockedBy = `NOT EXISTS (SELECT 1 FROM user_block b
+	WHERE (b.blocker_user_id = user_id AND b.blocked_user_id = ?) OR (b.blocker_user_id = ? AND b.blocked_user_id = user_id))`
+
+// IsSeedShared dice se il possessore mostra il seme agli altri utenti con le regole dell'elenco dei
+// possessori: scorta disponibile e non privata, account attivo, non in vacanza e inventario non nascosto
+func (s *Store) IsSeedShared(ownerID, seedID int) (bool, error) {
+	var shared bool
+	err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM users_seed us INNER JOIN users u ON us.user_id = u.user_id
+		WHERE us.user_id = ? AND us.seed_id = ? AND us.quantity > 0 AND us.private = FALSE
+		AND u.status = 'active' AND u.vacation_mode = FALSE AND u.owner_visibility IN (?, ?))`,
+		ownerID, seedID, types.VisibilityPublic, types.VisibilityMembers).Scan(&shared)
+	return shared, err
+}
+
+// GetWishlistUsers restituisce gli utenti che desiderano un seme, esclusi quelli bloccati con ownerID
+func (s *Store) GetWishlistUsers(seedID, ownerID int) ([]int, error) {
+	rows, err := s.db.Query("SELECT user_id FROM wishlist WHERE seed_id = ? AND "+notBlockedBy, seedID, ownerID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		users = append(users, id)
+	}
+
+	return users, rows.Err()
+}
+
+const searchColumns = "search_id, user_id, name, query, vegetable, region, created_at"
+
+// GetSavedSearches restituisce le ricerche salvate dall'utente
+func (s *Store) GetSavedSearches(userID int) ([]types.SavedSearch, error) {
+	return s.querySearches("SELECT "+searchColumns+" FROM saved_search WHERE user_id = ? ORDER BY created_at DESC", userID)
+}
+
+// GetCandidateSearches restituisce le ricerche salvate che possono riguardare un seme di vegetable
+// reso disponibile in una delle regions (regione, provincia o sigla, già normalizzate): le altre
+// restano nel database, come quelle di chi è bloccato con ownerID. Il confronto completo, testo
+// compreso, lo fa Matches
+func (s *Store) GetCandidateSearches(vegetable string, regions []string, ownerID int) ([]types.SavedSearch, error) {
+	args := []any{vegetable}
+	region := "region = ''"
+	if len(regions) > 0 {
+		region = "(region = '' OR region IN (" + strings.TrimSuffix(strings.Repeat("?,", len(regions)), ",") + "))"
+		for _, r := range regions {
+			args = append(args, r)
+		}
+	}
+
+	return s.querySearches("SELECT "+searchColumns+" FROM saved_search WHERE (vegetable = '' OR ? LIKE CONCAT('%', vegetable, '%')) AND "+
+		region+" AND "+notBlockedBy, append(args, ownerID, ownerID)...)
+}
+
+func (s *Store) querySearches(query string, args ...any) ([]types.SavedSearch, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := make([]types.SavedSearch, 0)
+	for rows.Next() {
+		var ss types.SavedSearch
+		if err := rows.Scan(&ss.ID, &ss.UserID, &ss.Name, &ss.Query, &ss.Vegetable, &ss.Region, &ss.CreatedAt); err != nil {
+			return nil, err
+		}
+		searches = append(searches, ss)
+	}
+
+	return searches, rows.Err()
+}
+
+// CreateSavedSearch salva una nuova ricerca
+func (s *Store) CreateSavedSearch(search *types.SavedSearch) error {
+	res, err := s.db.Exec("INSERT INTO saved_search (user_id, name, query, vegetable, region) VALUES (?, ?, ?, ?, ?)",
+		search.UserID, search.Name, search.Query, search.Vegetable, search.Region)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	search.ID = int(id)
+
+	return nil
+}
+
+// UpdateSavedSearch modifica una ricerca dell'utente
+func (s *Store) UpdateSavedSearch(search *types.SavedSearch) error {
+	_, err := s.db.Exec("UPDATE saved_search SET name = ?, query = ?, vegetable = ?, region = ? WHERE search_id = ? AND user_id = ?",
+		search.Name, search.Query, search.Vegetable, search.Region, search.ID, search.UserID)
+	if err != nil {
+		return err
+	}
+
+	// MySQL non conta le righe rimaste uguali, quindi si controlla che la ricerca esista
+	var exists bool
+	err = s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM saved_search WHERE search_id = ? AND user_id = ?)", search.ID, search.UserID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("saved search not found")
+	}
+
+	return nil
+}
+
+// DeleteSavedSearch elimina una ricerca dell'utente
+func (s *Store) DeleteSavedSearch(userID, searchID int) error {
+	res, err := s.db.Exec("DELETE FROM saved_search WHERE search_id = ? AND user_id = ?", searchID, userID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res, "saved search not found")
+}
+
+// QueueAlerts mette in coda gli avvisi, saltando quelli per cui l'utente ha già un avviso non
+// ancora inviato sullo stesso seme. Restituisce gli avvisi effettivamente inseriti
+func (s *Store) QueueAlerts(alerts []types.Alert) ([]types.Alert, error) {
+	// Inizia una transazione
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // Assicura che il rollback venga eseguito in caso di errore
+
+	queued := make([]types.Alert, 0, len(alerts))
+	for _, a := range alerts {
+		res, err := tx.Exec(`INSERT INTO alert (user_id, seed_id, owner_id, reason, search_id)
+			SELECT ?, ?, ?, ?, ? FROM DUAL
+			WHERE NOT EXISTS (SELECT 1 FROM alert WHERE user_id = ? AND seed_id = ? AND sent_at IS NULL)`,
+			a.UserID, a.SeedID, nullInt(a.OwnerID), a.Reason, nullInt(a.SearchID), a.UserID, a.SeedID)
+		if err != nil {
+			return nil, err
+		}
+
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		a.ID = int(id)
+		queued = append(queued, a)
+	}
+
+	// Conferma la transazione
+	return queued, tx.Commit()
+}
+
+const alertQuery = `SELECT a.alert_id, a.user_id, a.seed_id, a.owner_id, s.variety_name, s.vegetable,
+	a.reason, a.search_id, ss.name, a.created_at, a.sent_at
+	FROM alert a JOIN seed s ON s.seed_id = a.seed_id
+	LEFT JOIN saved_search ss ON ss.search_id = a.search_id `
+
+// GetAlerts restituisce gli avvisi più recenti dell'utente
+func (s *Store) GetAlerts(userID int) ([]types.Alert, error) {
+	return s.queryAlerts(alertQuery+"WHERE a.user_id = ? ORDER BY a.created_at DESC LIMIT 100", userID)
+}
+
+// GetPendingAlerts restituisce tutti gli avvisi non ancora inviati
+func (s *Store) GetPendingAlerts() ([]types.Alert, error) {
+	return s.queryAlerts(alertQuery + "WHERE a.sent_at IS NULL ORDER BY a.user_id, a.created_at")
+}
+
+func (s *Store) queryAlerts(query string, args ...any) ([]types.Alert, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := make([]types.Alert, 0)
+	for rows.Next() {
+		var a types.Alert
+		var ownerID, searchID sql.NullInt64
+		var searchName sql.NullString
+		var sentAt sql.NullTime
+		err := rows.Scan(&a.ID, &a.UserID, &a.SeedID, &ownerID, &a.Variety_name, &a.Vegetable,
+			&a.Reason, &searchID, &searchName, &a.CreatedAt, &sentAt)
+		if err != nil {
+			return nil, err
+		}
+		a.OwnerID, a.SearchID, a.SearchName = int(ownerID.Int64), int(searchID.Int64), searchName.String
+		if sentAt.Valid {
+			a.SentAt = &sentAt.Time
+		}
+		alerts = append(alerts, a)
+	}
+
+	return alerts, rows.Err()
+}
+
+// MarkAlertsSent segna gli avvisi come inviati
+func (s *Store) MarkAlertsSent(ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	_, err := s.db.Exec("UPDATE alert SET sent_at = NOW() WHERE alert_id IN ("+placeholders(len(ids))+")", args...)
+	return err
+}
+
+// GetAlertRecipients restituisce nome, email e preferenza di consegna degli utenti indicati
+func (s *Store) GetAlertRecipients(userIDs []int) (map[int]types.AlertRecipient, error) {
+	recipients := make(map[int]types.AlertRecipient, len(userIDs))
+	if len(userIDs) == 0 {
+		return recipients, nil
+	}
+
+	args := make([]any, len(userIDs))
+	for i, id := range userIDs {
+		args[i] = id
+	}
+	rows, err := s.db.Query("SELECT user_id, name, email, alert_delivery FROM users WHERE user_id IN ("+placeholders(len(userIDs))+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r types.AlertRecipient
+		if err := rows.Scan(&r.UserID, &r.Name, &r.Email, &r.Delivery); err != nil {
+			return nil, err
+		}
+		recipients[r.UserID] = r
+	}
+
+	return recipients, rows.Err()
+}
+
+// SetAlertDelivery salva se l'utente vuole gli avvisi subito o nel riepilogo giornaliero
+func (s *Store) SetAlertDelivery(userID int, delivery string) error {
+	_, err := s.db.Exec("UPDATE users SET alert_delivery = ? WHERE user_id = ?", delivery, userID)
+	return err
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+func nullInt(v int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(v), Valid: v != 0}
+}
+
+func checkAffected(res sql.Result, msg string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package wishlist
+
+import (
+	"backend/seed-savers/geo"
+	"backend/seed-savers/types"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockSeedStore struct {
+	types.SeedStore
+}
+
+func (m *mockSeedStore) GetSeedByID(id int) (*types.Seed, error) {
+	return &types.Seed{ID: id, Variety_name: "fagiolo di saluggia", Vegetable: "Fagiolo", Description: "varietà antica da sgranare"}, nil
+}
+
+func (m *mockSeedStore) GetUserArea(userID int) (string, string, error) {
+	return "13040", "VC", nil
+}
+
+type mockWishlistStore struct {
+	types.WishlistStore
+	wishlist []int
+	searches []types.SavedSearch
+	filter   []any
+	queued   []types.Alert
+	sent     []int
+	unshared bool
+}
+
+func (m *mockWishlistStore) IsSeedShared(ownerID, seedID int) (bool, error) {
+	return !m.unshared, nil
+}
+
+func (m *mockWishlistStore) GetWishlistUsers(seedID, ownerID int) ([]int, error) {
+	return m.wishlist, nil
+}
+
+func (m *mockWishlistStore) GetCandidateSearches(vegetable string, regions []string, ownerID int) ([]types.SavedSearch, error) {
+	m.filter = []any{vegetable, regions, ownerID}
+	return m.searches, nil
+}
+
+func (m *mockWishlistStore) QueueAlerts(alerts []types.Alert) ([]types.Alert, error) {
+	for i := range alerts {
+		alerts[i].ID = len(m.queued) + i + 1
+	}
+	m.queued = append(m.queued, alerts...)
+	return alerts, nil
+}
+
+func (m *mockWishlistStore) GetAlertRecipients(userIDs []int) (map[int]types.AlertRecipient, error) {
+	recipients := map[int]types.AlertRecipient{}
+	for _, id := range userIDs {
+		delivery := types.AlertDaily
+		if id%2 == 0 {
+			delivery = types.AlertImmediate
+		}
+		recipients[id] = types.AlertRecipient{UserID: id, Name: fmt.Sprint("utente ", id), Email: fmt.Sprint(id, "@example.com"), Delivery: delivery}
+	}
+	return recipients, nil
+}
+
+func (m *mockWishlistStore) MarkAlertsSent(ids []int) error {
+	m.sent = append(m.sent, ids...)
+	return nil
+}
+
+func TestMatches(t *testing.T) {
+	seed := &types.Seed{Variety_name: "Fagiolo di Saluggia", Vegetable: "fagiolo", Description: "Varietà antica"}
+	piemonte, _ := geo.LookupProvince("VC")
+	sicilia, _ := geo.LookupProvince("PA")
+
+	search := types.SavedSearch{Query: "antica", Vegetable: "Fagiolo", Region: "Piemonte"}
+	if !Matches(search, seed, piemonte) {
+		t.Error("expected heirloom beans in Piemonte to match")
+	}
+	if Matches(search, seed, sicilia) {
+		t.Error("the region must be checked")
+	}
+	if Matches(search, seed, nil) {
+		t.Error("an owner without address cannot match a search by region")
+	}
+	if Matches(types.SavedSearch{Query: "antica nana"}, seed, nil) {
+		t.Error("every word of the query must be found")
+	}
+	if !Matches(types.SavedSearch{Region: "vc"}, seed, piemonte) {
+		t.Error("a province code must be accepted as region")
+	}
+}
+
+func TestNotify(t *testing.T) {
+	store := &mockWishlistStore{
+		wishlist: []int{2, 5},
+		searches: []types.SavedSearch{
+			{ID: 1, UserID: 2, Name: "fagioli", Vegetable: "fagiolo"},
+			{ID: 2, UserID: 3, Name: "fagioli piemontesi", Vegetable: "fagiolo", Region: "Piemonte"},
+			{ID: 3, UserID: 4, Name: "pomodori", Vegetable: "pomodoro"},
+			{ID: 4, UserID: 5, Name: "fagioli di 5", Vegetable: "fagiolo"},
+			{ID: 5, UserID: 9, Name: "mie ricerche", Vegetable: "fagiolo"},
+		},
+	}
+	mails := map[string]string{}
+	n := &Notifier{store: store, seedStore: &mockSeedStore{}, send: func(to string, msg []byte) error {
+		mails[to] = string(msg)
+		return nil
+	}}
+
+	if err := n.Notify(7, 9); err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(store.filter) != "[fagiolo [piemonte vercelli vc] 9]" {
+		t.Errorf("expected the searches to be filtered by vegetable, the owner's area and blocks, got %v", store.filter)
+	}
+	if len(store.queued) != 3 {
+		t.Fatalf("expected one alert each for users 2, 3 and 5 and none for the owner, got %+v", store.queued)
+	}
+	if store.queued[2].UserID != 3 || store.queued[2].Reason != types.AlertSavedSearch || store.queued[2].SearchName != "fagioli piemontesi" {
+		t.Errorf("unexpected saved search alert %+v", store.queued[2])
+	}
+
+	// solo l'utente 2 ha scelto la consegna immediata
+	if len(mails) != 1 || !strings.Contains(mails["2@example.com"], "fagiolo di saluggia") {
+		t.Errorf("expected an immediate mail only for user 2, got %v", mails)
+	}
+	if len(store.sent) != 1 || store.sent[0] != store.queued[0].ID {
+		t.Errorf("only the delivered alert must be marked as sent, got %v", store.sent)
+	}
+
+	// un possessore in vacanza, nascosto o con il seme privato non fa partire avvisi
+	store.unshared = true
+	store.queued = nil
+	if err := n.Notify(7, 9); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.queued) != 0 {
+		t.Errorf("expected no alerts for a seed the owner does not share, got %+v", store.queued)
+	}
+}
+
+func TestNextDigest(t *testing.T) {
+	now := time.Date(2026, 5, 10, 8, 30, 0, 0, time.UTC)
+	if next := nextDigest(now, 7); !next.Equal(time.Date(2026, 5, 11, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected tomorrow at 7, got %v", next)
+	}
+	if next := nextDigest(now, 20); !next.Equal(time.Date(2026, 5, 10, 20, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected today at 20, got %v", next)
+	}
+}
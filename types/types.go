@@ -1,6 +1,11 @@
 package types
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
 
 type UserResetPassword struct {
 	Password string `json:"password" validate:"required,min=3,max=130"`
@@ -16,41 +21,94 @@ type UserRegisterPayload struct {
 	Password string `json:"password" validate:"required,min=3,max=130"`
 }
 
+type UserUpdatePayload struct {
+	Name *string `json:"name" validate:"omitempty,min=1,max=50"`
+}
+
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required,min=3,max=130"`
+}
+
+type ChangeEmailPayload struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
 type UserLoginPayload struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
 }
 
 type AdressPayload struct {
-	State            string `json:"state" validate:"required"`
-	City             string `json:"city" validate:"required"`
-	Street           string `json:"street" validate:"required"`
-	Cap              string `json:"cap" validate:"required"`
-	Province         string `json:"province" validate:"required"`
-	Apartment_number string `json:"apartment_number"`
-	Number           uint16 `json:"number" validate:"required"`
+	State            string      `json:"state" validate:"required"`
+	City             string      `json:"city" validate:"required"`
+	Street           string      `json:"street" validate:"required"`
+	Cap              string      `json:"cap" validate:"required"`
+	Province         string      `json:"province" validate:"required"`
+	Apartment_number string      `json:"apartment_number"`
+	Number           CivicNumber `json:"number" validate:"required,max=10"`
+	Label            string      `json:"label" validate:"max=40"`
+	Default          bool        `json:"default"`
+}
+
+// CivicNumber è un numero civico con l'eventuale esponente ("12/B"). Accetta anche un numero JSON,
+// come lo mandavano i client quando il civico era solo numerico
+type CivicNumber string
+
+func (n *CivicNumber) UnmarshalJSON(data []byte) error {
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err == nil {
+		*n = CivicNumber(number)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("the civic number must be a string")
+	}
+	*n = CivicNumber(s)
+	return nil
 }
 
 type CreateSeedPayload struct {
-	Description  string `json:"description" validate:"required"`
-	Variety_name string `json:"variety_name" validate:"required"`
-	Vegetable    string `json:"vegetable" validate:"required"`
-	Image        string `json:"image" validate:"required"`
-	Quantity     int    `json:"quantity" validate:"required"`
+	Description  string         `json:"description" validate:"required"`
+	Variety_name string         `json:"variety_name" validate:"required"`
+	Vegetable    string         `json:"vegetable" validate:"required"`
+	Image        string         `json:"image"`
+	Quantity     int            `json:"quantity" validate:"required"`
+	Sowing       *SowingWindows `json:"sowing" validate:"omitnil"`
+	Pollination  string         `json:"pollination" validate:"omitempty,oneof=self cross hybrid"`
 }
 
+// UpdateOrderPayload modifica la quantità o lo stato di un ordine. "Annullato" non si può impostare
+// da qui: solo l'anonimizzazione di un account annulla gli ordini, restituendo le quantità
 type UpdateOrderPayload struct {
 	OrderId      int    `json:"orderId" validate:"required"`
 	SeedQuantity int    `json:"seedQuantity"`
-	State        string `json:"state"`
+	State        string `json:"state" validate:"omitempty,oneof='In attesa' 'In preparazione' 'In spedizione' 'Arrivato'"`
 }
 
 type OrderPayload struct {
 	SenderID     int `json:"sender" validate:"required"`
 	SeedID       int `json:"seedId" validate:"required"`
 	SeedQuantity int `json:"seedQuantity"`
+	// AdressID sceglie l'indirizzo di spedizione dalla rubrica; se manca si usa quello predefinito
+	AdressID int `json:"adressId" validate:"omitempty,min=1"`
 }
 
+const (
+	RoleUser    = "user"
+	RoleCurator = "curator"
+	RoleAdmin   = "admin"
+)
+
+const (
+	UserActive  = "active"
+	UserBlocked = "blocked"
+	UserDeleted = "deleted"
+)
+
 type User struct {
 	Name     string `json:"firstName"`
 	Email    string `json:"email"`
@@ -58,27 +116,692 @@ type User struct {
 	Adress   Adress `json:"adress"`
 	Seeds    []Seed `json:"seeds"`
 	Credits  int8   `json:"credits"`
+	Role     string `json:"role"`
 	ID       int    `json:"id"`
+	// OpenOrders è valorizzato solo quando l'utente è caricato completo
+	OpenOrders *OpenOrders `json:"openOrders,omitempty"`
+	// EmailVerifiedAt resta nil finché l'utente non conferma l'indirizzo email
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt"`
+	// TokenVersion è firmato nel token di sessione: cambiando la password le sessioni precedenti decadono
+	TokenVersion int `json:"-"`
+	// DeletionRequestedAt è valorizzato durante il periodo di ripensamento prima della cancellazione
+	DeletionRequestedAt *time.Time `json:"deletionRequestedAt,omitempty"`
+	// Status è UserActive, UserBlocked per gli account sospesi da un amministratore o UserDeleted
+	Status string `json:"status"`
 }
 
+// AccountDeletion descrive una cancellazione in attesa: fino a DeleteAt l'utente può annullarla
+type AccountDeletion struct {
+	RequestedAt time.Time `json:"requestedAt"`
+	DeleteAt    time.Time `json:"deleteAt"`
+}
+
+// DeletionPayload conferma la cancellazione con la password; chi usa un provider OAuth deve invece
+// aver fatto login da poco
+type DeletionPayload struct {
+	Password string `json:"password"`
+}
+
+// DataExport raccoglie i dati dell'utente per l'esportazione; profilo, indirizzo e inventario
+// arrivano dall'utente completo
+type DataExport struct {
+	Addresses     []Adress          `json:"addresses"`
+	Orders        []DataExportOrder `json:"orders"`
+	Ratings       []GrowReport      `json:"ratings"`
+	Wishlist      []WishlistItem    `json:"wishlist"`
+	SavedSearches []SavedSearch     `json:"savedSearches"`
+}
+
+// DataExportOrder è un ordine visto dall'utente esportato: Role dice se l'ha spedito o ricevuto
+type DataExportOrder struct {
+	ID           int       `json:"id"`
+	Role         string    `json:"role"`
+	Counterpart  string    `json:"counterpart"`
+	State        string    `json:"state"`
+	OrderDate    time.Time `json:"orderDate"`
+	SeedID       int       `json:"seedId"`
+	Variety_name string    `json:"variety_name"`
+	Quantity     int       `json:"quantity"`
+}
+
+const (
+	EmailVerify = "verify"
+	EmailChange = "change"
+)
+
+// EmailVerification è un link inviato a Email per confermarla: l'indirizzo dell'utente (EmailVerify)
+// o quello nuovo che l'utente ha chiesto di usare (EmailChange). Vale una sola volta e fino a ExpiresAt
+type EmailVerification struct {
+	TokenID   string    `json:"-"`
+	UserID    int       `json:"userId"`
+	Email     string    `json:"email"`
+	Purpose   string    `json:"purpose"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// OpenOrders conta gli ordini non ancora arrivati né annullati che l'utente deve spedire o ricevere
+type OpenOrders struct {
+	Sent     int `json:"sent"`
+	Received int `json:"received"`
+}
+
+// Adress è un indirizzo della rubrica dell'utente; uno solo è quello predefinito
 type Adress struct {
 	ID               int    `json:"id"`
+	UserID           int    `json:"-"`
+	Label            string `json:"label"`
+	Default          bool   `json:"default"`
 	Country          string `json:"state"`
 	City             string `json:"city"`
 	Street           string `json:"street"`
 	Cap              string `json:"cap"`
 	Province         string `json:"province"`
 	Apartment_number string `json:"apartment_number"`
-	Number           uint16 `json:"Number"`
+	Number           string `json:"Number"`
+}
+
+type UpdateSeedPayload struct {
+	Description  *string        `json:"description" validate:"omitnil,min=1"`
+	Variety_name *string        `json:"variety_name" validate:"omitnil,min=1,max=100"`
+	Vegetable    *string        `json:"vegetable" validate:"omitnil,min=1,max=100"`
+	Image        *string        `json:"image"`
+	Sowing       *SowingWindows `json:"sowing" validate:"omitnil"`
+	Pollination  *string        `json:"pollination" validate:"omitnil,oneof='' self cross hybrid"`
+}
+
+// MonthRange è un intervallo di mesi (1-12); se End è minore di Start l'intervallo scavalca l'anno
+type MonthRange struct {
+	Start int `json:"start" validate:"min=1,max=12"`
+	End   int `json:"end" validate:"min=1,max=12"`
+}
+
+func (r *MonthRange) String() string {
+	if r == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// Contains indica se il mese cade nell'intervallo, anche quando questo scavalca l'anno
+func (r *MonthRange) Contains(month int) bool {
+	if r == nil {
+		return false
+	}
+	if r.Start <= r.End {
+		return month >= r.Start && month <= r.End
+	}
+	return month >= r.Start || month <= r.End
+}
+
+// SowingWindows sono i periodi di semina, trapianto e raccolta per la zona climatica D (centro Italia)
+type SowingWindows struct {
+	SowIndoor  *MonthRange `json:"sowIndoor,omitempty" validate:"omitnil"`
+	SowOutdoor *MonthRange `json:"sowOutdoor,omitempty" validate:"omitnil"`
+	Transplant *MonthRange `json:"transplant,omitempty" validate:"omitnil"`
+	Harvest    *MonthRange `json:"harvest,omitempty" validate:"omitnil"`
 }
 
 type Seed struct {
-	Description  string `json:"description"`
+	Description        string        `json:"description"`
+	Variety_name       string        `json:"variety_name"`
+	Vegetable          string        `json:"vegetable"`
+	Image              string        `json:"image"`
+	Quantity           int           `json:"quantity"`
+	Owners             int           `json:"owners"`
+	RestockedAt        *time.Time    `json:"restockedAt,omitempty"`
+	CreatedBy          int           `json:"createdBy,omitempty"`
+	Sowing             SowingWindows `json:"sowing"`
+	Pollination        string        `json:"pollination,omitempty"`
+	Tags               []string      `json:"tags,omitempty"`
+	ConservationStatus string        `json:"conservationStatus,omitempty"`
+	ConservationSource string        `json:"conservationSource,omitempty"`
+	Badges             []string      `json:"badges,omitempty"`
+	Private            bool          `json:"private,omitempty"`
+	ID                 int           `json:"id"`
+	DistanceKm         *int          `json:"distanceKm,omitempty"`
+}
+
+// SeedHolder è chi ha in inventario un seme, con i campi dell'indirizzo usati per collocarlo
+type SeedHolder struct {
+	SeedID   int
+	UserID   int
+	Name     string
+	Quantity int
+	Cap      string
+	Province string
+	// MembersOnly è vero se il possessore si mostra solo agli utenti autenticati
+	MembersOnly bool
+	// LastHarvest è l'anno del raccolto più recente tra le partite del possessore, 0 se non è noto
+	LastHarvest int
+}
+
+const (
+	VisibilityPublic  = "public"
+	VisibilityMembers = "members"
+	VisibilityHidden  = "hidden"
+)
+
+// OwnerSharing decide se e a chi mostrare l'utente tra i possessori dei suoi semi
+type OwnerSharing struct {
+	Vacation   bool   `json:"vacation"`
+	Visibility string `json:"visibility" validate:"required,oneof=public members hidden"`
+}
+
+// SeedOwner è una voce della lista dei possessori di un seme. Reputation conta gli scambi spediti
+// dall'utente e arrivati a destinazione
+type SeedOwner struct {
+	UserID     int        `json:"userId"`
+	Name       string     `json:"name"`
+	Region     string     `json:"region,omitempty"`
+	Reputation int        `json:"reputation"`
+	Quantity   int        `json:"quantity"`
+	Lots       []OwnerLot `json:"lots"`
+}
+
+type OwnerLot struct {
+	ID          int    `json:"id"`
+	Source      string `json:"source"`
+	Quantity    int    `json:"quantity"`
+	HarvestYear int    `json:"harvestYear,omitempty"`
+}
+
+type SeedOwnerPage struct {
+	Results []SeedOwner `json:"results"`
+	Total   int         `json:"total"`
+	Page    int         `json:"page"`
+	Limit   int         `json:"limit"`
+}
+
+type NearbyOwner struct {
+	UserID     int    `json:"userId"`
+	Name       string `json:"name"`
+	Quantity   int    `json:"quantity"`
+	Province   string `json:"province,omitempty"`
+	DistanceKm *int   `json:"distanceKm,omitempty"`
+}
+
+// SeedCatalogFields sono i campi del catalogo salvati in ogni revisione
+type SeedCatalogFields struct {
+	Description  string        `json:"description"`
+	Variety_name string        `json:"variety_name"`
+	Vegetable    string        `json:"vegetable"`
+	Image        string        `json:"image"`
+	Sowing       SowingWindows `json:"sowing"`
+	Pollination  string        `json:"pollination"`
+}
+
+type FieldChange struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+type SeedRevision struct {
+	ID           int                    `json:"id"`
+	SeedID       int                    `json:"seedId"`
+	UserID       int                    `json:"userId"`
+	AuthorName   string                 `json:"authorName"`
+	CreatedAt    time.Time              `json:"createdAt"`
+	Changes      map[string]FieldChange `json:"changes"`
+	Snapshot     SeedCatalogFields      `json:"snapshot"`
+	RevertedFrom int                    `json:"revertedFrom,omitempty"`
+}
+
+type SeedImage struct {
+	ID          int       `json:"id"`
+	SeedID      int       `json:"seedId"`
+	UserID      int       `json:"userId"`
+	StorageKey  string    `json:"-"`
+	ThumbKey    string    `json:"-"`
+	ContentType string    `json:"-"`
+	Position    int       `json:"position"`
+	URL         string    `json:"url"`
+	ThumbURL    string    `json:"thumbUrl"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type ReorderImagesPayload struct {
+	ImageIDs []int `json:"imageIds" validate:"required,min=1"`
+}
+
+type DuplicateCandidate struct {
+	Seed      Seed    `json:"seed"`
+	Duplicate Seed    `json:"duplicate"`
+	Score     float64 `json:"score"`
+}
+
+type MergeSeedsPayload struct {
+	SurvivorID  int `json:"survivorId" validate:"required"`
+	DuplicateID int `json:"duplicateId" validate:"required,nefield=SurvivorID"`
+}
+
+const (
+	LotRegistered = "registered"
+	LotExchange   = "exchange"
+	LotGrowOut    = "grow_out"
+)
+
+// SeedLot è una partita di semi posseduta da un utente, collegata alla partita da cui discende
+type SeedLot struct {
+	ID          int       `json:"id"`
+	SeedID      int       `json:"seedId"`
+	UserID      int       `json:"userId"`
+	UserName    string    `json:"userName"`
+	ParentID    int       `json:"parentId,omitempty"`
+	Source      string    `json:"source"`
+	OrderID     int       `json:"orderId,omitempty"`
+	Quantity    int       `json:"quantity"`
+	HarvestYear int       `json:"harvestYear,omitempty"`
+	Province    string    `json:"province,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Hidden      bool      `json:"-"`
+}
+
+type LineageNode struct {
+	SeedLot
+	Children []*LineageNode `json:"children"`
+}
+
+type GrowOutPayload struct {
+	ParentLotID int `json:"parentLotId" validate:"required"`
+	Quantity    int `json:"quantity" validate:"required,min=1"`
+	HarvestYear int `json:"harvestYear" validate:"omitempty,min=1900,max=2100"`
+}
+
+type ClimateZonePayload struct {
+	Zone string `json:"zone" validate:"omitempty,oneof=A B C D E F"`
+}
+
+type CalendarSeed struct {
+	ID           int    `json:"id"`
 	Variety_name string `json:"variety_name"`
 	Vegetable    string `json:"vegetable"`
-	Image        string `json:"image"`
 	Quantity     int    `json:"quantity"`
-	ID           int    `json:"id"`
+}
+
+type CalendarMonth struct {
+	Month      int            `json:"month"`
+	SowIndoor  []CalendarSeed `json:"sowIndoor"`
+	SowOutdoor []CalendarSeed `json:"sowOutdoor"`
+	Transplant []CalendarSeed `json:"transplant"`
+	Harvest    []CalendarSeed `json:"harvest"`
+}
+
+const (
+	RelationCompanion       = "companion"
+	RelationAntagonist      = "antagonist"
+	RelationRotationFollows = "rotation_follows"
+)
+
+// CropRelation lega due ortaggi. Per rotation_follows CropA va coltivato dopo CropB
+type CropRelation struct {
+	ID    int    `json:"id"`
+	CropA string `json:"cropA"`
+	CropB string `json:"cropB"`
+	Kind  string `json:"kind"`
+	Note  string `json:"note,omitempty"`
+}
+
+type CropRelationPayload struct {
+	CropA string `json:"cropA" validate:"required,max=100"`
+	CropB string `json:"cropB" validate:"required,max=100"`
+	Kind  string `json:"kind" validate:"required,oneof=companion antagonist rotation_follows"`
+	Note  string `json:"note" validate:"max=255"`
+}
+
+type CropNote struct {
+	Crop string `json:"crop"`
+	Note string `json:"note,omitempty"`
+}
+
+type CompanionInfo struct {
+	Crop        string     `json:"crop"`
+	Companions  []CropNote `json:"companions"`
+	Antagonists []CropNote `json:"antagonists"`
+	GrowAfter   []CropNote `json:"growAfter"`
+	GrowBefore  []CropNote `json:"growBefore"`
+}
+
+type CompanionCheckPayload struct {
+	SeedIDs []int `json:"seedIds" validate:"required,min=2,max=50"`
+}
+
+type CompanionPair struct {
+	SeedA int    `json:"seedA"`
+	SeedB int    `json:"seedB"`
+	CropA string `json:"cropA"`
+	CropB string `json:"cropB"`
+	Note  string `json:"note,omitempty"`
+}
+
+type CompanionReport struct {
+	Conflicts  []CompanionPair `json:"conflicts"`
+	Companions []CompanionPair `json:"companions"`
+}
+
+const (
+	ImportNew     = "new"
+	ImportMatched = "matched"
+	ImportError   = "error"
+)
+
+// ImportRow è una riga del file importato già tradotta nei campi del catalogo
+type ImportRow struct {
+	Line         int
+	Variety_name string
+	Vegetable    string
+	Description  string
+	Image        string
+	Quantity     int
+	SeedID       int
+}
+
+type ImportRowResult struct {
+	Line         int    `json:"line"`
+	Status       string `json:"status"`
+	SeedID       int    `json:"seedId,omitempty"`
+	Variety_name string `json:"variety_name,omitempty"`
+	Vegetable    string `json:"vegetable,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	New     int               `json:"new"`
+	Matched int               `json:"matched"`
+	Errors  int               `json:"errors"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+type ImportJob struct {
+	ID         string        `json:"id"`
+	UserID     int           `json:"-"`
+	Status     string        `json:"status"`
+	Total      int           `json:"total"`
+	Processed  int           `json:"processed"`
+	Report     *ImportReport `json:"report,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	StartedAt  time.Time     `json:"startedAt"`
+	FinishedAt *time.Time    `json:"finishedAt,omitempty"`
+}
+
+// ExportSeed è la riga pubblica del catalogo esportato: nessun dato personale, solo la disponibilità aggregata
+type ExportSeed struct {
+	ID            int       `json:"id"`
+	Variety_name  string    `json:"variety_name"`
+	Vegetable     string    `json:"vegetable"`
+	Description   string    `json:"description"`
+	TotalQuantity int       `json:"totalQuantity"`
+	Owners        int       `json:"owners"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+type ExportFilter struct {
+	Vegetable string
+	Since     time.Time
+}
+
+const (
+	AlertImmediate = "immediate"
+	AlertDaily     = "daily"
+
+	AlertWishlist    = "wishlist"
+	AlertSavedSearch = "saved_search"
+)
+
+type WishlistItem struct {
+	SeedID       int       `json:"seedId"`
+	Variety_name string    `json:"variety_name"`
+	Vegetable    string    `json:"vegetable"`
+	Available    int       `json:"available"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// SavedSearch è una ricerca salvata: i criteri vuoti non filtrano. Region accetta una regione,
+// una provincia o una sigla
+type SavedSearch struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"-"`
+	Name      string    `json:"name"`
+	Query     string    `json:"query"`
+	Vegetable string    `json:"vegetable"`
+	Region    string    `json:"region"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type SavedSearchPayload struct {
+	Name      string `json:"name" validate:"required,max=100"`
+	Query     string `json:"query" validate:"max=100"`
+	Vegetable string `json:"vegetable" validate:"max=100"`
+	Region    string `json:"region" validate:"max=40"`
+}
+
+type Alert struct {
+	ID           int        `json:"id"`
+	UserID       int        `json:"-"`
+	SeedID       int        `json:"seedId"`
+	OwnerID      int        `json:"ownerId,omitempty"`
+	Variety_name string     `json:"variety_name"`
+	Vegetable    string     `json:"vegetable"`
+	Reason       string     `json:"reason"`
+	SearchID     int        `json:"searchId,omitempty"`
+	SearchName   string     `json:"searchName,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	SentAt       *time.Time `json:"sentAt,omitempty"`
+}
+
+// AlertRecipient sono i dati necessari per consegnare gli avvisi a un utente
+type AlertRecipient struct {
+	UserID   int
+	Name     string
+	Email    string
+	Delivery string
+}
+
+type AlertDeliveryPayload struct {
+	Delivery string `json:"delivery" validate:"required,oneof=immediate daily"`
+}
+
+const (
+	TagCurated  = "curated"
+	TagPending  = "pending"
+	TagRejected = "rejected"
+)
+
+type Tag struct {
+	ID          int       `json:"id"`
+	Slug        string    `json:"slug"`
+	Label       string    `json:"label"`
+	Status      string    `json:"status"`
+	SuggestedBy int       `json:"suggestedBy,omitempty"`
+	Seeds       int       `json:"seeds"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type TagPayload struct {
+	Label string `json:"label" validate:"required,min=2,max=60"`
+}
+
+// CatalogFilter sono i filtri a faccette del catalogo: i valori della stessa faccetta sono in OR,
+// faccette diverse in AND. Available, Rare e SeedIDs restringono il catalogo prima del calcolo delle
+// faccette; SeedIDs diverso da nil lascia solo quei semi, nell'ordine indicato
+type CatalogFilter struct {
+	Vegetables  []string
+	Tags        []string
+	Pollination []string
+	Available   bool
+	Rare        bool
+	SeedIDs     []int
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+type Facets struct {
+	Vegetable   []FacetCount `json:"vegetable"`
+	Tag         []FacetCount `json:"tag"`
+	Pollination []FacetCount `json:"pollination"`
+}
+
+const (
+	ConservationWatch      = "watch"
+	ConservationEndangered = "endangered"
+	ConservationCritical   = "critical"
+
+	BadgeAtRisk = "at-risk"
+)
+
+// Rarity misura quanto una varietà rischia di andare perduta: Score va da 0 a 100 e cresce con pochi
+// possessori, concentrati in una sola regione e con semi vecchi
+type Rarity struct {
+	SeedID        int  `json:"seedId"`
+	Score         int  `json:"score"`
+	Owners        int  `json:"owners"`
+	Regions       int  `json:"regions"`
+	NewestHarvest int  `json:"newestHarvest,omitempty"`
+	AtRisk        bool `json:"atRisk"`
+}
+
+type AtRiskSeed struct {
+	Seed   Seed   `json:"seed"`
+	Rarity Rarity `json:"rarity"`
+}
+
+// ConservationPayload imposta lo stato ufficiale di una varietà, con la lista da cui proviene in Source.
+// Uno stato vuoto lo rimuove
+type ConservationPayload struct {
+	Status string `json:"status" validate:"omitempty,oneof=watch endangered critical"`
+	Source string `json:"source" validate:"max=150"`
+}
+
+const (
+	GrowReportPublished = "published"
+	GrowReportHidden    = "hidden"
+)
+
+// GrowReport racconta come è andata una varietà nell'orto di un utente. I voti sono facoltativi:
+// germinazione in percentuale, resa, resistenza alle malattie e sapore da 1 a 5
+type GrowReport struct {
+	ID                int       `json:"id"`
+	SeedID            int       `json:"seedId"`
+	UserID            int       `json:"userId"`
+	UserName          string    `json:"userName"`
+	OrderID           int       `json:"orderId,omitempty"`
+	Year              int       `json:"year"`
+	Province          string    `json:"province,omitempty"`
+	Region            string    `json:"region,omitempty"`
+	Germination       *int      `json:"germination,omitempty"`
+	Yield             *int      `json:"yield,omitempty"`
+	DiseaseResistance *int      `json:"diseaseResistance,omitempty"`
+	Taste             *int      `json:"taste,omitempty"`
+	Notes             string    `json:"notes,omitempty"`
+	Status            string    `json:"status"`
+	Flags             int       `json:"flags,omitempty"`
+	ImageIDs          []int     `json:"-"`
+	Photos            []string  `json:"photos"`
+	CreatedAt         time.Time `json:"createdAt"`
+}
+
+type GrowReportPayload struct {
+	OrderID           int    `json:"orderId" validate:"omitempty,min=1"`
+	Year              int    `json:"year" validate:"required,min=1900"`
+	Province          string `json:"province" validate:"max=40"`
+	Germination       *int   `json:"germination" validate:"omitnil,min=0,max=100"`
+	Yield             *int   `json:"yield" validate:"omitnil,min=1,max=5"`
+	DiseaseResistance *int   `json:"diseaseResistance" validate:"omitnil,min=1,max=5"`
+	Taste             *int   `json:"taste" validate:"omitnil,min=1,max=5"`
+	Notes             string `json:"notes" validate:"max=2000"`
+	ImageIDs          []int  `json:"imageIds" validate:"max=10"`
+}
+
+type GrowReportFlagPayload struct {
+	Reason string `json:"reason" validate:"max=255"`
+}
+
+// GrowTally sono le somme dei voti pubblicati di un seme in una provincia; Counts conta i voti
+// effettivamente dati per ciascuna voce, nell'ordine germinazione, resa, resistenza, sapore
+type GrowTally struct {
+	Province string
+	Reports  int
+	Sums     [4]int
+	Counts   [4]int
+}
+
+// GrowRatings sono le medie dei voti, assenti se nessuno ha votato quella voce
+type GrowRatings struct {
+	Germination       *float64 `json:"germination,omitempty"`
+	Yield             *float64 `json:"yield,omitempty"`
+	DiseaseResistance *float64 `json:"diseaseResistance,omitempty"`
+	Taste             *float64 `json:"taste,omitempty"`
+}
+
+type RegionGrowSummary struct {
+	Region  string `json:"region"`
+	Reports int    `json:"reports"`
+	GrowRatings
+}
+
+type GrowSummary struct {
+	Reports int `json:"reports"`
+	GrowRatings
+	Regions []RegionGrowSummary `json:"regions"`
+}
+
+type GrowReportPage struct {
+	Results []GrowReport `json:"results"`
+	Summary GrowSummary  `json:"summary"`
+	Total   int          `json:"total"`
+	Page    int          `json:"page"`
+	Limit   int          `json:"limit"`
+}
+
+// PublicProfile è quello che chiunque può vedere di un utente: niente email né indirizzo, solo la
+// provincia. Reputation conta gli scambi spediti e arrivati, CompletedExchanges anche quelli ricevuti
+type PublicProfile struct {
+	ID                 int          `json:"id"`
+	Name               string       `json:"name"`
+	Province           string       `json:"province,omitempty"`
+	MemberSince        *time.Time   `json:"memberSince,omitempty"`
+	Reputation         int          `json:"reputation"`
+	CompletedExchanges int          `json:"completedExchanges"`
+	Inventory          []PublicSeed `json:"inventory"`
+	Visibility         string       `json:"-"`
+}
+
+type PublicSeed struct {
+	SeedID       int    `json:"seedId"`
+	Variety_name string `json:"variety_name"`
+	Vegetable    string `json:"vegetable"`
+	Quantity     int    `json:"quantity"`
+}
+
+// Quando chi spedisce i semi mostra il proprio indirizzo completo a chi li ha ordinati. Il predefinito è
+// DiscloseOnAccept: prima di queste impostazioni chi ordinava non vedeva affatto l'indirizzo di chi spedisce,
+// così nessun indirizzo già visibile viene nascosto e chi non sceglie lo rivela solo agli ordini accettati
+const (
+	DiscloseOnOrder  = "order"
+	DiscloseOnAccept = "accepted"
+)
+
+// PrivacySettings decide chi vede il profilo e l'inventario e quando chi riceve un ordine vede
+// l'indirizzo completo di chi spedisce. InventoryVisibility è la stessa impostazione di OwnerSharing.Visibility
+type PrivacySettings struct {
+	ProfileVisibility   string `json:"profileVisibility" validate:"required,oneof=public members hidden"`
+	InventoryVisibility string `json:"inventoryVisibility" validate:"required,oneof=public members hidden"`
+	AddressDisclosure   string `json:"addressDisclosure" validate:"required,oneof=order accepted"`
+}
+
+type SeedPrivacyPayload struct {
+	Private bool `json:"private"`
+}
+
+type CatalogPage struct {
+	Results []Seed `json:"results"`
+	Facets  Facets `json:"facets"`
+	Total   int    `json:"total"`
+	Page    int    `json:"page"`
+	Limit   int    `json:"limit"`
 }
 
 type Order struct {
@@ -90,6 +813,7 @@ type Order struct {
 	ReciverAdress Adress    `json:"adress"`
 	SenderID      int       `json:"senderID"`
 	SenderName    string    `json:"senderName"`
+	SenderAdress  *Adress   `json:"senderAdress,omitempty"`
 	Seed          Seed      `json:"seed"`
 }
 
@@ -97,8 +821,8 @@ type OrderStore interface {
 	GetOrdersById(ID int) (*Order, error)
 	GetIncomingOrders(reciverUserID int) ([]Order, error)
 	GetOrdersToBeSent(senderUserID int) ([]Order, error)
-	MakeOrder(reUserID, reciverUserID, seedId, quantity int) error
-	ModifyOrder(order *Order) error
+	MakeOrder(reUserID, reciverUserID, seedId, quantity, adressID int) error
+	ModifyOrder(order *Order, userID int) error
 	DeleteOrder(ID int) error
 }
 
@@ -111,19 +835,338 @@ type UserStore interface {
 	GetCompleteUserByEmail(email string) (*User, error)
 	GetCompleteUserByID(ID int) (*User, error)
 
-	CreateAdress(adress *Adress) error
-	ModifyAdress(adress *Adress) error
-
 	RegisterSeed(seed *Seed, userID int) error
 	ModifySeedQuantity(seed *Seed, userID int) error
 }
 
+type AccountStore interface {
+	CreateEmailVerification(v *EmailVerification) error
+	GetEmailVerificationsSince(userID int, since time.Time) ([]time.Time, error)
+	ConsumeEmailVerification(v *EmailVerification) error
+	MarkEmailVerified(userID int) error
+
+	UpdateProfile(userID int, name string) error
+	ChangePassword(userID int, hash string) (int, error)
+}
+
+type AddressStore interface {
+	GetAddresses(userID int) ([]Adress, error)
+	GetAddress(userID, adressID int) (*Adress, error)
+	CreateAddress(adress *Adress) error
+	UpdateAddress(adress *Adress) error
+	DeleteAddress(userID, adressID int) error
+	SetDefaultAddress(userID, adressID int) error
+}
+
+type AccountDataStore interface {
+	RequestDeletion(userID int) (time.Time, error)
+	CancelDeletion(userID int) error
+	GetDueDeletions(requestedBefore time.Time) ([]int, error)
+	AnonymizeUser(userID int) error
+	GetDataExport(userID int) (*DataExport, error)
+}
+
+type AuditStore interface {
+	LogAction(entry *AuditEntry) error
+}
+
+type AdminStore interface {
+	AuditStore
+	GetAuditLog(limit, offset int) ([]AuditEntry, int, error)
+
+	SearchUsers(filter AdminUserFilter, limit, offset int) ([]AdminUser, int, error)
+	GetOrder(orderID int) (*Order, error)
+	SetUserStatus(userID int, status string) error
+	AdjustCredits(userID, delta int) (int, error)
+	SetUserRole(userID int, role string) error
+	GetStats() (*SystemStats, error)
+}
+
+type ModerationStore interface {
+	BlockUser(userID, blockedID int) error
+	UnblockUser(userID, blockedID int) error
+	GetBlockedUsers(userID int) ([]BlockedUser, error)
+
+	// ReportTargetOwner restituisce l'utente segnalato o l'autore del contenuto segnalato
+	ReportTargetOwner(targetType string, targetID int) (int, error)
+	HasOpenReport(reporterID int, targetType string, targetID int) (bool, error)
+	CreateReport(report *AbuseReport) error
+	GetReports(status string, limit, offset int) ([]AbuseReport, int, error)
+	GetReport(id int) (*AbuseReport, error)
+	ResolveReport(report *AbuseReport) error
+}
+
 type SeedStore interface {
 	GetSeeds() ([]Seed, error)
 	GetSeedByID(id int) (*Seed, error)
+	GetSeedsByIDs(ids []int) (map[int]Seed, error)
 	GetSeedByVarieties(varieties string) (*Seed, error)
 	GetSeedsByVegetable(vegetable string) ([]Seed, error)
-	CreateSeed(*CreateSeedPayload) error
-	GetSeedOwnersByID(id int) (map[string]int, error)
+	GetVarietyCandidates(vegetable string) ([]Seed, error)
+	CreateSeed(seed *CreateSeedPayload, creatorID int) error
+	UpdateSeed(seed *Seed, editorID, revertedFrom int) error
+	GetSeedRevisions(seedID int) ([]SeedRevision, error)
+	GetSeedRevision(seedID, revisionID int) (*SeedRevision, error)
+	MergeSeeds(survivorID, duplicateID int) error
+	GetSeedOwners(seedID, excludeUserID int, member bool, limit, offset int) ([]SeedOwner, int, error)
+	GetOwnerSharing(userID int) (*OwnerSharing, error)
+	SetOwnerSharing(userID int, sharing *OwnerSharing) error
+	GetSeedHolders(seedID, viewerID int) ([]SeedHolder, error)
+	GetAvailableHolders(seedIDs []int, viewerID int) ([]SeedHolder, error)
+	GetUserArea(userID int) (postalCode string, province string, err error)
+	GetHoldings(seedIDs []int) ([]SeedHolder, error)
+	GetCuratedSeedTags(seedIDs []int) (map[int][]Tag, error)
+	// BrowseCatalog restituisce una pagina del catalogo filtrato, con il totale e i conteggi delle faccette
+	BrowseCatalog(filter CatalogFilter, limit, offset int) (*CatalogPage, error)
+	GetCatalogSeedIDs(filter CatalogFilter) ([]int, error)
 	UserSeedQuantity(id, seedId int) int
 }
+
+type SeedImageStore interface {
+	CreateSeedImage(image *SeedImage) error
+	GetSeedImages(seedID int) ([]SeedImage, error)
+	GetSeedImageByID(id int) (*SeedImage, error)
+	DeleteSeedImage(id int) error
+	ReorderSeedImages(seedID int, imageIDs []int) error
+	UserHasSeed(userID, seedID int) (bool, error)
+}
+
+type CalendarStore interface {
+	GetCalendarSeeds(userID int) ([]Seed, error)
+	GetUserZoneInfo(userID int) (province string, zoneOverride string, err error)
+	SetClimateZone(userID int, zone string) error
+	SetCalendarToken(userID int, token string) error
+	GetUserIDByCalendarToken(token string) (int, error)
+}
+
+type TagStore interface {
+	GetTags(status string) ([]Tag, error)
+	GetTagBySlug(slug string) (*Tag, error)
+	GetTagByID(id int) (*Tag, error)
+	CreateTag(tag *Tag) error
+	SetTagStatus(id int, status string) error
+	GetSeedTags(seedID int) ([]Tag, error)
+	TagSeed(seedID, tagID, userID int) error
+	UntagSeed(seedID, tagID int) error
+	GetTagAuthor(seedID, tagID int) (int, error)
+}
+
+// AvailabilityNotifier viene avvisato quando un utente rende disponibile un seme
+type AvailabilityNotifier interface {
+	SeedAvailable(seedID, ownerID int)
+}
+
+type WishlistStore interface {
+	GetWishlist(userID int) ([]WishlistItem, error)
+	AddToWishlist(userID, seedID int) error
+	RemoveFromWishlist(userID, seedID int) error
+	IsSeedShared(ownerID, seedID int) (bool, error)
+	GetWishlistUsers(seedID, ownerID int) ([]int, error)
+	GetSavedSearches(userID int) ([]SavedSearch, error)
+	GetCandidateSearches(vegetable string, regions []string, ownerID int) ([]SavedSearch, error)
+	CreateSavedSearch(search *SavedSearch) error
+	UpdateSavedSearch(search *SavedSearch) error
+	DeleteSavedSearch(userID, searchID int) error
+	QueueAlerts(alerts []Alert) ([]Alert, error)
+	GetAlerts(userID int) ([]Alert, error)
+	GetPendingAlerts() ([]Alert, error)
+	MarkAlertsSent(ids []int) error
+	GetAlertRecipients(userIDs []int) (map[int]AlertRecipient, error)
+	SetAlertDelivery(userID int, delivery string) error
+}
+
+type ExportStore interface {
+	// StreamCatalog chiama fn per ogni seme senza caricare l'intero catalogo in memoria
+	StreamCatalog(filter ExportFilter, fn func(ExportSeed) error) error
+}
+
+type ImportStore interface {
+	GetCatalog() ([]Seed, error)
+	// ApplyImportBatch crea i semi nuovi (impostando SeedID) e aggiunge le quantità all'inventario
+	ApplyImportBatch(rows []ImportRow, userID int) error
+}
+
+type CompanionStore interface {
+	GetRelations() ([]CropRelation, error)
+	GetRelationsForCrops(crops []string) ([]CropRelation, error)
+	GetRelationByID(id int) (*CropRelation, error)
+	CreateRelation(relation *CropRelation) error
+	UpdateRelation(relation *CropRelation) error
+	DeleteRelation(id int) error
+}
+
+type LotStore interface {
+	GetSeedLots(seedID, viewerID int, member bool) ([]SeedLot, error)
+	GetLotByID(id int) (*SeedLot, error)
+	CreateGrowOut(lot *SeedLot) error
+}
+
+// BlobStorage is the backend where uploaded files are kept
+type BlobStorage interface {
+	Put(key string, data io.Reader, contentType string) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+type ConservationStore interface {
+	SetConservationStatus(seedID int, status, source string, curatorID int) error
+	GetContacts(userIDs []int) (map[int]AlertRecipient, error)
+}
+
+type GrowReportStore interface {
+	CreateGrowReport(report *GrowReport) error
+	GetGrowReports(seedID, limit, offset int) ([]GrowReport, int, error)
+	GetGrowReportByID(id int) (*GrowReport, error)
+	GetGrowTallies(seedID int) ([]GrowTally, error)
+	DeleteGrowReport(id int) error
+	FlagGrowReport(reportID, userID int, reason string) (int, error)
+	SetGrowReportStatus(id int, status string) error
+	GetFlaggedGrowReports() ([]GrowReport, error)
+	CountUserSeedImages(userID, seedID int, imageIDs []int) (int, error)
+	OrderHasSeed(orderID, userID, seedID int) (bool, error)
+}
+
+type ProfileStore interface {
+	GetPublicProfile(userID, viewerID int) (*PublicProfile, error)
+	GetPublicInventory(userID int) ([]PublicSeed, error)
+	SetSeedPrivacy(userID, seedID int, private bool) error
+	GetPrivacySettings(userID int) (*PrivacySettings, error)
+	SetPrivacySettings(userID int, settings *PrivacySettings) error
+}
+
+// AuditEntry è un'azione di un curatore o di un amministratore, anche di sola lettura
+type AuditEntry struct {
+	ID        int             `json:"id"`
+	ActorID   int             `json:"actorId"`
+	ActorName string          `json:"actorName"`
+	ActorRole string          `json:"actorRole"`
+	Action    string          `json:"action"`
+	Path      string          `json:"path"`
+	Details   json.RawMessage `json:"details,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+type AuditPage struct {
+	Results []AuditEntry `json:"results"`
+	Total   int          `json:"total"`
+	Page    int          `json:"page"`
+	Limit   int          `json:"limit"`
+}
+
+// AdminUserFilter restringe la lista degli utenti; Query cerca in nome ed email
+type AdminUserFilter struct {
+	Query  string
+	Role   string
+	Status string
+}
+
+// AdminUser è un utente come lo vede un amministratore
+type AdminUser struct {
+	ID                  int        `json:"id"`
+	Name                string     `json:"name"`
+	Email               string     `json:"email"`
+	Role                string     `json:"role"`
+	Status              string     `json:"status"`
+	Credits             int        `json:"credits"`
+	EmailVerified       bool       `json:"emailVerified"`
+	CreatedAt           *time.Time `json:"createdAt"`
+	DeletionRequestedAt *time.Time `json:"deletionRequestedAt,omitempty"`
+}
+
+type AdminUserPage struct {
+	Results []AdminUser `json:"results"`
+	Total   int         `json:"total"`
+	Page    int         `json:"page"`
+	Limit   int         `json:"limit"`
+}
+
+// SystemStats sono i numeri dell'applicazione: utenti per stato e ruolo, ordini per stato
+type SystemStats struct {
+	Users            map[string]int `json:"users"`
+	Roles            map[string]int `json:"roles"`
+	Orders           map[string]int `json:"orders"`
+	OrdersLast30Days int            `json:"ordersLast30Days"`
+	Seeds            int            `json:"seeds"`
+	PendingDeletions int            `json:"pendingDeletions"`
+	FlaggedReports   int            `json:"flaggedReports"`
+	OpenAbuseReports int            `json:"openAbuseReports"`
+}
+
+type SuspendPayload struct {
+	Reason string `json:"reason" validate:"required,max=255"`
+}
+
+type CreditsPayload struct {
+	Delta  int    `json:"delta" validate:"required,min=-127,max=127"`
+	Reason string `json:"reason" validate:"required,max=255"`
+}
+
+type RolePayload struct {
+	Role string `json:"role" validate:"required,oneof=user curator admin"`
+}
+
+// BlockedUser è un utente bloccato da chi guarda la lista
+type BlockedUser struct {
+	UserID    int       `json:"userId"`
+	Name      string    `json:"name"`
+	BlockedAt time.Time `json:"blockedAt"`
+}
+
+const (
+	ReportTargetUser       = "user"
+	ReportTargetSeed       = "seed"
+	ReportTargetGrowReport = "grow_report"
+)
+
+const (
+	ReportOpen     = "open"
+	ReportResolved = "resolved"
+)
+
+// Le decisioni di un moderatore su una segnalazione
+const (
+	ModerationDismiss = "dismiss"
+	ModerationWarn    = "warn"
+	ModerationSuspend = "suspend"
+	ModerationRemove  = "remove"
+)
+
+// AbuseReport è la segnalazione di un utente, di un seme o di un resoconto di coltivazione.
+// TargetUserID è l'utente segnalato o l'autore del contenuto, zero se non esiste più
+type AbuseReport struct {
+	ID             int        `json:"id"`
+	ReporterID     int        `json:"reporterId"`
+	ReporterName   string     `json:"reporterName"`
+	TargetType     string     `json:"targetType"`
+	TargetID       int        `json:"targetId"`
+	TargetUserID   int        `json:"targetUserId"`
+	TargetUserName string     `json:"targetUserName"`
+	Category       string     `json:"category"`
+	Reason         string     `json:"reason"`
+	Status         string     `json:"status"`
+	Resolution     string     `json:"resolution,omitempty"`
+	ResolutionNote string     `json:"resolutionNote,omitempty"`
+	ResolvedBy     int        `json:"resolvedBy,omitempty"`
+	ResolvedAt     *time.Time `json:"resolvedAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+type AbuseReportPage struct {
+	Results []AbuseReport `json:"results"`
+	Total   int           `json:"total"`
+	Page    int           `json:"page"`
+	Limit   int           `json:"limit"`
+}
+
+type AbuseReportPayload struct {
+	TargetType string `json:"targetType" validate:"required,oneof=user seed grow_report"`
+	TargetID   int    `json:"targetId" validate:"required,min=1"`
+	Category   string `json:"category" validate:"required,oneof=spam scam harassment inappropriate other"`
+	Reason     string `json:"reason" validate:"required,max=1000"`
+}
+
+// ModerationPayload è la decisione su una segnalazione; con warn Note è il messaggio inviato all'utente
+type ModerationPayload struct {
+	Action string `json:"action" validate:"required,oneof=dismiss warn suspend remove"`
+	Note   string `json:"note" validate:"max=1000"`
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// ParsePage legge page (da 1) e limit dalla query string, con limit predefinito defaultLimit e
+// massimo maxLimit
+func ParsePage(r *http.Request, defaultLimit, maxLimit int) (int, int, error) {
+	page, limit := 1, defaultLimit
+	if v := r.URL.Query().Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, 0, fmt.Errorf("page must be a positive number")
+		}
+		page = n
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLimit {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+		limit = n
+	}
+	return page, limit, nil
+}
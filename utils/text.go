@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeName porta un nome di varietà o ortaggio in una forma confrontabile:
+// minuscolo, senza accenti, senza punteggiatura e con un solo spazio tra le parole
+func NormalizeName(name string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	stripped, _, err := transform.String(t, name)
+	if err != nil {
+		stripped = name
+	}
+
+	fields := strings.FieldsFunc(strings.ToLower(stripped), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	return strings.Join(fields, " ")
+}
+
+// Slug trasforma un'etichetta in un identificatore da URL: "Resistente alla siccità" diventa
+// "resistente-alla-siccita"
+func Slug(label string) string {
+	return strings.ReplaceAll(NormalizeName(label), " ", "-")
+}